@@ -3,16 +3,21 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	apphttp "github.com/amakane-hakari/kavos/internal/api/http"
 	ilog "github.com/amakane-hakari/kavos/internal/log"
 	"github.com/amakane-hakari/kavos/internal/metrics"
+	"github.com/amakane-hakari/kavos/internal/pubsub"
 	"github.com/amakane-hakari/kavos/internal/store"
 )
 
@@ -27,20 +32,124 @@ func main() {
 	} else {
 		mx = metrics.NewSimple()
 	}
-	st := store.New[string, string](
+	opts := []store.Option{
 		store.WithShards(16),
-		store.WithCleanupInterval(1*time.Second),
+		store.WithCleanupInterval(1 * time.Second),
 		store.WithLogger(logger),
 		store.WithMetrics(mx),
-	).WithEvictor(store.NewLRUEvictor[string, string](10000))
+		store.WithWatchHistory(getEnvInt("KAVOS_WATCH_HISTORY", 0)),
+	}
+	if path := os.Getenv("KAVOS_AOF_PATH"); path != "" {
+		policy, err := store.ParseFsyncPolicy(getEnv("KAVOS_AOF_FSYNC", string(store.FsyncEverySec)))
+		if err != nil {
+			log.Fatalf("server.config.error err=%v", err)
+		}
+		opts = append(opts, store.WithAOF(path, policy))
+	}
+	if v := os.Getenv("KAVOS_EXPIRE_STRATEGY"); v != "" {
+		strategy, err := store.ParseExpireStrategy(v)
+		if err != nil {
+			log.Fatalf("server.config.error err=%v", err)
+		}
+		opts = append(opts, store.WithExpireStrategy(strategy))
+	}
+	if v := os.Getenv("KAVOS_MAX_IDLE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("server.config.error err=%v", err)
+		}
+		opts = append(opts, store.WithMaxIdle(d))
+	}
+	if n := getEnvInt("KAVOS_MAX_BYTES", 0); n > 0 {
+		opts = append(opts, store.WithMaxBytes(int64(n)))
+	}
+	if v, _ := strconv.ParseBool(os.Getenv("KAVOS_ORDERED_INDEX")); v {
+		opts = append(opts, store.WithOrderedIndex())
+	}
+	if dir := os.Getenv("KAVOS_BACKEND_DIR"); dir != "" {
+		mode, err := store.ParseWriteMode(getEnv("KAVOS_WRITE_MODE", string(store.WriteThrough)))
+		if err != nil {
+			log.Fatalf("server.config.error err=%v", err)
+		}
+		b, err := store.NewFileBackend[string, string](dir)
+		if err != nil {
+			log.Fatalf("server.backend.error err=%v", err)
+		}
+		if mode == store.WriteBehind {
+			opts = append(opts, store.WithWriteBehind[string, string](b, 0, 0))
+		} else {
+			opts = append(opts, store.WithWriteThrough[string, string](b))
+		}
+	}
+	st, err := store.Open[string, string](opts...)
+	if err != nil {
+		log.Fatalf("server.store.open.error err=%v", err)
+	}
+	if v, _ := strconv.ParseBool(os.Getenv("KAVOS_EVICTION_SHARDED")); v {
+		st.WithShardedEvictor(10000, func(c int) store.Evictor[string, string] {
+			return store.NewLRUEvictor[string, string](c)
+		})
+	} else {
+		st.WithEvictor(store.NewLRUEvictor[string, string](10000))
+	}
 
-	router := apphttp.NewRouter(st, logger)
+	// スナップショット (AOF 有効時は AOF が正となるため起動時の読み込みはしない)
+	snapshotPath := os.Getenv("KAVOS_SNAPSHOT_PATH")
+	if snapshotPath != "" && os.Getenv("KAVOS_AOF_PATH") == "" {
+		if err := st.LoadSnapshot(snapshotPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("server.snapshot.load.error path=%s err=%v", snapshotPath, err)
+		}
+	}
+	snapshotStop := make(chan struct{})
+	snapshotDone := make(chan struct{})
+	go func() {
+		defer close(snapshotDone)
+		if snapshotPath == "" {
+			return
+		}
+		interval := 5 * time.Minute
+		if v := os.Getenv("KAVOS_SNAPSHOT_INTERVAL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				interval = d
+			}
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := st.SaveSnapshot(snapshotPath); err != nil {
+					log.Printf("server.snapshot.save.error err=%v", err)
+				}
+			case <-snapshotStop:
+				return
+			}
+		}
+	}()
 
+	policy, err := pubsub.ParseOverflowPolicy(os.Getenv("KAVOS_PUBSUB_POLICY"))
+	if err != nil {
+		log.Fatalf("server.config.error err=%v", err)
+	}
+	broker := pubsub.New(
+		pubsub.WithBufferSize(getEnvInt("KAVOS_PUBSUB_BUFFER", 64)),
+		pubsub.WithOverflowPolicy(policy),
+		pubsub.WithMetrics(mx),
+	)
+
+	router := apphttp.NewRouter(st, logger, apphttp.WithBroker(broker))
+
+	// Shutdown はストリーミング中のリクエスト (/watch) の終了を待つため、
+	// シャットダウン開始時にリクエストのコンテキストをキャンセルして切断させる
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	srv := &http.Server{
 		Addr:              addr,
 		Handler:           router,
 		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelBase)
 
 	log.Printf("server.start addr=%s pid=%d", addr, os.Getpid())
 
@@ -81,6 +190,18 @@ func main() {
 		_ = srv.Close()
 	}
 
+	broker.Close()
+
+	close(snapshotStop)
+	<-snapshotDone
+	if snapshotPath != "" {
+		if err := st.SaveSnapshot(snapshotPath); err != nil {
+			log.Printf("server.snapshot.save.error err=%v", err)
+		} else {
+			log.Printf("server.snapshot.saved path=%s", snapshotPath)
+		}
+	}
+
 	st.Close()
 
 	remaining := "n/a"
@@ -100,3 +221,12 @@ func getEnv(k, def string) string {
 	}
 	return def
 }
+
+func getEnvInt(k string, def int) int {
+	if v := os.Getenv(k); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
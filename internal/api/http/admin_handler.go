@@ -0,0 +1,34 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type adminHandler struct {
+	st *store.Store[string, string]
+}
+
+func (h *adminHandler) mount(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/aof/rewrite", wrap(h.rewriteAOF))
+	})
+}
+
+func (h *adminHandler) rewriteAOF(w http.ResponseWriter, _ *http.Request) error {
+	if err := h.st.StartAOFRewrite(); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAOFDisabled):
+			return NewAppError(http.StatusConflict, CodeConflict, "aof is disabled", nil)
+		case errors.Is(err, store.ErrAOFRewriteInProgress):
+			return NewAppError(http.StatusConflict, CodeConflict, "aof rewrite already in progress", nil)
+		default:
+			return err
+		}
+	}
+	writeSuccess(w, http.StatusAccepted, map[string]string{"status": "started"})
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+
+	"github.com/amakane-hakari/kavos/internal/store"
 )
 
 // AppError はアプリケーション固有のエラーを表します。
@@ -36,6 +38,20 @@ const (
 	CodeConflict = "CONFLICT"
 	// CodeTooManyRequests は 429 Too Many Requests エラーを表します。
 	CodeTooManyRequests = "TOO_MANY_REQUESTS"
+	// CodePreconditionFailed は 412 Precondition Failed エラーを表します。
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	// CodeNotNumeric は 数値として扱えない値への加算による 409 Conflict エラーを表します。
+	CodeNotNumeric = "NOT_NUMERIC"
+	// CodeNotImplemented は 機能が無効な場合の 501 Not Implemented エラーを表します。
+	CodeNotImplemented = "NOT_IMPLEMENTED"
+	// CodeGone は 410 Gone エラーを表します。
+	CodeGone = "GONE"
+	// CodeValueTooLarge は 値がストアのバイト予算を超える場合の 413 Content Too Large エラーを表します。
+	CodeValueTooLarge = "VALUE_TOO_LARGE"
+	// CodeBackendError は write-through で Backend への書き込みに失敗した場合の 502 Bad Gateway エラーを表します。
+	CodeBackendError = "BACKEND_ERROR"
+	// CodePersistenceError は FsyncAlways の AOF への追記に失敗した場合の 500 Internal Server Error を表します。
+	CodePersistenceError = "PERSISTENCE_ERROR"
 )
 
 func (e *AppError) Error() string { return e.Code + ": " + e.Message }
@@ -70,6 +86,16 @@ func InvalidJSON(msg string) *AppError {
 	return NewAppError(http.StatusBadRequest, CodeInvalidJSON, msg, nil)
 }
 
+// PreconditionFailed は 412 Precondition Failed エラーを表す AppError を作成します。
+func PreconditionFailed(msg string) *AppError {
+	return NewAppError(http.StatusPreconditionFailed, CodePreconditionFailed, msg, nil)
+}
+
+// NotImplemented は 501 Not Implemented エラーを表す AppError を作成します。
+func NotImplemented(msg string) *AppError {
+	return NewAppError(http.StatusNotImplemented, CodeNotImplemented, msg, nil)
+}
+
 // FromStdError は標準の error を AppError に変換します。
 func FromStdError(err error) *AppError {
 	if err == nil {
@@ -85,6 +111,16 @@ func FromStdError(err error) *AppError {
 		return NewAppError(http.StatusRequestTimeout, CodeCanceled, "request canceled", nil)
 	case errors.Is(err, context.DeadlineExceeded):
 		return NewAppError(http.StatusRequestTimeout, CodeTimeout, "request timeout", nil)
+	case errors.Is(err, store.ErrNotFound):
+		return NotFound("key not found")
+	case errors.Is(err, store.ErrVersionMismatch):
+		return NewAppError(http.StatusConflict, CodeConflict, "version mismatch", nil)
+	case errors.Is(err, store.ErrValueTooLarge):
+		return NewAppError(http.StatusRequestEntityTooLarge, CodeValueTooLarge, "value exceeds store max bytes", nil)
+	case errors.Is(err, store.ErrBackend):
+		return NewAppError(http.StatusBadGateway, CodeBackendError, "backend write failed", nil)
+	case errors.Is(err, store.ErrAOF):
+		return NewAppError(http.StatusInternalServerError, CodePersistenceError, "aof write failed", nil)
 	default:
 		return Internal("unexpected error")
 	}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+)
+
+// maxBatchKeys は 1 回のバッチ操作で扱えるキーの最大数です。
+const maxBatchKeys = 1000
+
+type batchKeysRequest struct {
+	Keys []string `json:"keys"`
+}
+
+type batchSetItemRequest struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	TTL     int64  `json:"ttl,omitempty"`     // 秒。0 / 未指定で無期限
+	Sliding bool   `json:"sliding,omitempty"` // アクセスのたびに ttl 延長する
+}
+
+type batchSetRequest struct {
+	Items []batchSetItemRequest `json:"items"`
+}
+
+type batchResultDTO struct {
+	valueDTO
+	Found   bool      `json:"found"`
+	Version uint64    `json:"version,omitempty"`
+	Error   *AppError `json:"error,omitempty"` // 失敗したキーだけ。エラーエンベロープの error と同じ形式
+}
+
+type batchResponse struct {
+	Results []batchResultDTO `json:"results"`
+	Failed  int              `json:"failed"` // Error を持つ結果の数
+}
+
+// writeBatch はキーごとの結果を返します。失敗したキーがあれば 207 Multi-Status にします。
+func writeBatch(w http.ResponseWriter, dtos []batchResultDTO) {
+	failed := 0
+	for _, d := range dtos {
+		if d.Error != nil {
+			failed++
+		}
+	}
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeSuccess(w, status, batchResponse{Results: dtos, Failed: failed})
+}
+
+// validateBatchKeys はキーの数と空のキーを検証します。
+func validateBatchKeys(n int, keyAt func(i int) string) error {
+	if n == 0 {
+		return BadRequest("empty keys")
+	}
+	if n > maxBatchKeys {
+		return BadRequest("too many keys")
+	}
+	for i := range n {
+		if keyAt(i) == "" {
+			return BadRequest("empty key")
+		}
+	}
+	return nil
+}
+
+// mget は複数のキーをまとめて取得します（POST /kvs/_mget, JSON: {"keys":[...]}）。
+func (h *kvHandler) mget(w http.ResponseWriter, r *http.Request) error {
+	var req batchKeysRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := validateBatchKeys(len(req.Keys), func(i int) string { return req.Keys[i] }); err != nil {
+		return err
+	}
+	results := h.st.MGet(req.Keys)
+	now := h.st.Clock().Now()
+	dtos := make([]batchResultDTO, len(results))
+	for i, res := range results {
+		dtos[i] = batchResultDTO{valueDTO: valueDTO{Key: res.Key}, Found: res.Found}
+		if res.Found {
+			dtos[i].valueDTO = valueDTO{Key: res.Key, Value: res.Value}.withExpiry(res.ExpireAt, now)
+			dtos[i].Version = res.Version
+		}
+	}
+	writeBatch(w, dtos)
+	return nil
+}
+
+// mset は複数のキーをまとめてセットします（POST /kvs/_mset, JSON: {"items":[{"key","value","ttl","sliding"}]}）。
+// アトミックではなく、失敗したキーがあっても他のキーはセットします。
+func (h *kvHandler) mset(w http.ResponseWriter, r *http.Request) error {
+	var req batchSetRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := validateBatchKeys(len(req.Items), func(i int) string { return req.Items[i].Key }); err != nil {
+		return err
+	}
+	items := make([]store.BatchItem[string, string], len(req.Items))
+	for i, it := range req.Items {
+		if it.Sliding && it.TTL <= 0 {
+			return BadRequest("sliding requires ttl")
+		}
+		items[i] = store.BatchItem[string, string]{Key: it.Key, Value: it.Value, Sliding: it.Sliding}
+		if it.TTL > 0 {
+			items[i].TTL = time.Duration(it.TTL) * time.Second
+		}
+	}
+	results := h.st.MSet(items)
+	now := h.st.Clock().Now()
+	dtos := make([]batchResultDTO, len(results))
+	for i, res := range results {
+		dtos[i] = batchResultDTO{valueDTO: valueDTO{Key: res.Key}}
+		if res.Err != nil {
+			dtos[i].Error = FromStdError(res.Err)
+			continue
+		}
+		dtos[i].valueDTO = dtos[i].withExpiry(res.ExpireAt, now)
+		dtos[i].Found = res.Found
+		dtos[i].Version = res.Version
+	}
+	writeBatch(w, dtos)
+	return nil
+}
+
+// mdelete は複数のキーをまとめて削除します（POST /kvs/_mdelete, JSON: {"keys":[...]}）。
+func (h *kvHandler) mdelete(w http.ResponseWriter, r *http.Request) error {
+	var req batchKeysRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if err := validateBatchKeys(len(req.Keys), func(i int) string { return req.Keys[i] }); err != nil {
+		return err
+	}
+	results := h.st.MDelete(req.Keys)
+	dtos := make([]batchResultDTO, len(results))
+	for i, res := range results {
+		dtos[i] = batchResultDTO{valueDTO: valueDTO{Key: res.Key}, Found: res.Found}
+		if res.Err != nil {
+			dtos[i].Error = FromStdError(res.Err)
+		}
+	}
+	writeBatch(w, dtos)
+	return nil
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/clock/fake"
+	"github.com/amakane-hakari/kavos/internal/store"
+)
+
+type batchData struct {
+	Results []struct {
+		kvData
+		Found   bool   `json:"found"`
+		Version uint64 `json:"version"`
+		Error   *struct {
+			Status int    `json:"status"`
+			Code   string `json:"code"`
+		} `json:"error"`
+	} `json:"results"`
+	Failed int `json:"failed"`
+}
+
+func decodeBatch(t *testing.T, res *http.Response, status int) batchData {
+	t.Helper()
+	if res.StatusCode != status {
+		t.Fatalf("want %d got %d", status, res.StatusCode)
+	}
+	var sw successWrap[batchData]
+	if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return sw.Data
+}
+
+func TestKVS_Batch(t *testing.T) {
+	clk := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	st := store.New[string, string](store.WithClock(clk))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	d := decodeBatch(t, doReq(t, http.MethodPost, ts.URL+"/kvs/_mset", `{"items":[
+		{"key":"a","value":"1"},
+		{"key":"b","value":"2","ttl":60},
+		{"key":"s","value":"3","ttl":10,"sliding":true}
+	]}`, nil), http.StatusOK)
+	if len(d.Results) != 3 || d.Failed != 0 || d.Results[0].Version == 0 || d.Results[1].TTLMs == nil || *d.Results[1].TTLMs != 60000 {
+		t.Fatalf("unexpected mset response %+v", d)
+	}
+
+	clk.Advance(8 * time.Second)
+	d = decodeBatch(t, doReq(t, http.MethodPost, ts.URL+"/kvs/_mget", `{"keys":["a","missing","b","s"]}`, nil), http.StatusOK)
+	r := d.Results
+	if !r[0].Found || r[0].Value != "1" || r[0].Version == 0 || r[1].Found || r[1].Key != "missing" || r[2].Value != "2" {
+		t.Fatalf("unexpected mget response %+v", d)
+	}
+	if r[3].TTLMs == nil || *r[3].TTLMs != 10000 {
+		t.Fatalf("mget should extend sliding ttl: %+v", r[3])
+	}
+
+	d = decodeBatch(t, doReq(t, http.MethodPost, ts.URL+"/kvs/_mdelete", `{"keys":["a","missing"]}`, nil), http.StatusOK)
+	if !d.Results[0].Found || d.Results[1].Found {
+		t.Fatalf("unexpected mdelete response %+v", d)
+	}
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/a", "", nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("deleted key status=%d", res.StatusCode)
+	}
+
+	for _, tc := range []struct{ url, body string }{
+		{"/kvs/_mget", `{"keys":[]}`},
+		{"/kvs/_mget", `{"keys":[""]}`},
+		{"/kvs/_mdelete", `{}`},
+		{"/kvs/_mset", `{"items":[{"key":"k","value":"v","sliding":true}]}`},
+		{"/kvs/_mset", `{"items":[{"key":"k","value":"v","unknown":1}]}`},
+		{"/kvs/_mget", `{"keys":["` + strings.Repeat(`k","`, maxBatchKeys) + `k"]}`},
+	} {
+		if res := doReq(t, http.MethodPost, ts.URL+tc.url, tc.body, nil); res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s %s: want 400 got %d", tc.url, tc.body, res.StatusCode)
+		}
+	}
+}
+
+func TestKVS_BatchPartialFailure(t *testing.T) {
+	st := store.New[string, string](store.WithMaxBytes(200))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	d := decodeBatch(t, doReq(t, http.MethodPost, ts.URL+"/kvs/_mset", `{"items":[
+		{"key":"ok","value":"v"},
+		{"key":"big","value":"`+strings.Repeat("x", 200)+`"}
+	]}`, nil), http.StatusMultiStatus)
+	if d.Failed != 1 || d.Results[0].Error != nil || d.Results[1].Error == nil {
+		t.Fatalf("unexpected response %+v", d)
+	}
+	if e := d.Results[1].Error; e.Status != http.StatusRequestEntityTooLarge || e.Code != CodeValueTooLarge {
+		t.Fatalf("unexpected item error %+v", e)
+	}
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/ok", "", nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("successful item should be set, status=%d", res.StatusCode)
+	}
+}
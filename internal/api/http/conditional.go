@@ -0,0 +1,84 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatETag はエントリのバージョンから強い ETag を生成します。
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// etagMatcher は If-Match / If-None-Match ヘッダの内容を表します。
+type etagMatcher struct {
+	present bool
+	any     bool // "*"
+	tags    []string
+}
+
+func parseETagHeader(r *http.Request, name string) etagMatcher {
+	raw := r.Header.Values(name)
+	if len(raw) == 0 {
+		return etagMatcher{}
+	}
+	m := etagMatcher{present: true}
+	for _, line := range raw {
+		for _, t := range strings.Split(line, ",") {
+			t = strings.TrimSpace(t)
+			switch {
+			case t == "":
+			case t == "*":
+				m.any = true
+			default:
+				m.tags = append(m.tags, t)
+			}
+		}
+	}
+	return m
+}
+
+// matchStrong は If-Match 用の強い比較を行います（W/ 付きは一致しない）。
+func (m etagMatcher) matchStrong(etag string) bool {
+	if m.any {
+		return true
+	}
+	for _, t := range m.tags {
+		if t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeak は If-None-Match 用の弱い比較を行います。
+func (m etagMatcher) matchWeak(etag string) bool {
+	if m.any {
+		return true
+	}
+	for _, t := range m.tags {
+		if strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheHeaders は残り TTL から Cache-Control / Expires を設定します。
+// 無期限のキーはいつでも更新され得るため、キャッシュには再検証 (no-cache) を要求します。
+// now はストアの Clock の現在時刻です（期限はその時計で計算されているため）。
+func setCacheHeaders(w http.ResponseWriter, expireAt, now time.Time) {
+	if expireAt.IsZero() {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	maxAge := int64(math.Floor(expireAt.Sub(now).Seconds()))
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	w.Header().Set("Cache-Control", "max-age="+strconv.FormatInt(maxAge, 10))
+	w.Header().Set("Expires", expireAt.UTC().Format(http.TimeFormat))
+}
@@ -2,10 +2,14 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/amakane-hakari/kavos/internal/glob"
 	"github.com/amakane-hakari/kavos/internal/store"
 	"github.com/go-chi/chi/v5"
 )
@@ -16,10 +20,21 @@ type kvHandler struct {
 
 func (h *kvHandler) mount(r chi.Router) {
 	r.Route("/kvs", func(r chi.Router) {
+		r.Get("/", wrap(h.list))
+		r.Post("/_mget", wrap(h.mget))
+		r.Post("/_mset", wrap(h.mset))
+		r.Post("/_mdelete", wrap(h.mdelete))
 		r.Put("/{key}", wrap(h.put))
 		r.Get("/{key}", wrap(h.get))
 		r.Delete("/{key}", wrap(h.del))
+		r.Post("/{key}/incr", wrap(h.incr))
+		r.Post("/{key}/decr", wrap(h.decr))
+		r.Post("/{key}/incrbyfloat", wrap(h.incrByFloat))
+		r.Get("/{key}/ttl", wrap(h.getTTL))
+		r.Put("/{key}/ttl", wrap(h.putTTL))
+		r.Delete("/{key}/ttl", wrap(h.deleteTTL))
 	})
+	r.Get("/kvs-range", wrap(h.rangeKeys))
 }
 
 type valueRequest struct {
@@ -27,8 +42,32 @@ type valueRequest struct {
 }
 
 type valueDTO struct {
-	Key   string `json:"key"`
-	Value string `json:"value,omitempty"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 無期限なら省略
+	TTLMs     *int64     `json:"ttl_ms,omitempty"`     // 残り有効期間 (ミリ秒)。無期限なら省略
+}
+
+// withExpiry は期限がある場合に expires_at と ttl_ms を設定します。
+func (d valueDTO) withExpiry(expireAt, now time.Time) valueDTO {
+	if expireAt.IsZero() {
+		return d
+	}
+	at := expireAt.UTC()
+	ms := max(expireAt.Sub(now).Milliseconds(), 0)
+	d.ExpiresAt, d.TTLMs = &at, &ms
+	return d
+}
+
+// pageDefaultLimit / pageMaxLimit は GET /kvs, /kvs-range の limit の既定値と上限です。
+const (
+	pageDefaultLimit = 100
+	pageMaxLimit     = 1000
+)
+
+type scanDTO struct {
+	Keys   []string `json:"keys"`
+	Cursor string   `json:"cursor"` // "0" で走査完了
 }
 
 type handlerFunc func(w http.ResponseWriter, r *http.Request) error
@@ -36,7 +75,7 @@ type handlerFunc func(w http.ResponseWriter, r *http.Request) error
 func wrap(h handlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := h(w, r); err != nil {
-			writeError(w, err)
+			writeError(w, FromStdError(err))
 		}
 	}
 }
@@ -51,34 +90,162 @@ func (h *kvHandler) put(w http.ResponseWriter, r *http.Request) error {
 		return BadRequest("invalid json")
 	}
 
-	var ttlDur time.Duration
-	if raw := r.URL.Query().Get("ttl"); raw != "" {
-		sec, err := strconv.ParseInt(raw, 10, 64)
-		if err == nil && sec > 0 {
-			ttlDur = time.Duration(sec) * time.Second
+	ttl := ttlFromQuery(r)
+	sliding, err := slidingFromQuery(r, ttl)
+	if err != nil {
+		return err
+	}
+	ver, err := h.conditionalPut(r, key, req.Value, ttl, sliding)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", formatETag(ver))
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: req.Value})
+	return nil
+}
+
+// list はキーをカーソル単位で列挙します（GET /kvs?prefix=&match=&cursor=&limit=）。
+func (h *kvHandler) list(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	var cursor uint64
+	if c := q.Get("cursor"); c != "" {
+		v, err := strconv.ParseUint(c, 10, 64)
+		if err != nil {
+			return BadRequest("invalid cursor")
 		}
+		cursor = v
+	}
+	limit, err := limitFromQuery(r)
+	if err != nil {
+		return err
 	}
 
-	if ttlDur > 0 {
-		h.st.SetWithTTL(key, req.Value, ttlDur)
-	} else {
-		h.st.Set(key, req.Value)
+	prefix, match := q.Get("prefix"), q.Get("match")
+	if match == "" && prefix != "" {
+		// prefix だけならパターンに変換してストア側で絞り込む
+		match = glob.QuoteMeta(prefix) + "*"
+		prefix = ""
+	}
+	keys, next := h.st.Scan(cursor, match, limit)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if strings.HasPrefix(k, prefix) {
+			out = append(out, k)
+		}
 	}
+	writeSuccess(w, http.StatusOK, scanDTO{Keys: out, Cursor: strconv.FormatUint(next, 10)})
+	return nil
+}
 
-	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: req.Value})
+type rangeDTO struct {
+	Items []valueDTO `json:"items"`
+}
+
+// rangeKeys はキー順の範囲読み出しを行います（GET /kvs-range?start=&end=&limit=&reverse=）。
+// start 以上 end 未満で、空の場合は下限・上限なしです。
+func (h *kvHandler) rangeKeys(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	limit, err := limitFromQuery(r)
+	if err != nil {
+		return err
+	}
+	var reverse bool
+	if v := q.Get("reverse"); v != "" {
+		if reverse, err = strconv.ParseBool(v); err != nil {
+			return BadRequest("invalid reverse")
+		}
+	}
+	items, err := h.st.Range(q.Get("start"), q.Get("end"), limit, reverse)
+	if errors.Is(err, store.ErrOrderedIndexDisabled) {
+		return NotImplemented("ordered index is disabled")
+	}
+	if err != nil {
+		return err
+	}
+	now := h.st.Clock().Now()
+	out := make([]valueDTO, len(items))
+	for i, it := range items {
+		out[i] = valueDTO{Key: it.Key, Value: it.Value}.withExpiry(it.ExpireAt, now)
+	}
+	writeSuccess(w, http.StatusOK, rangeDTO{Items: out})
 	return nil
 }
 
+// limitFromQuery は ?limit= を解釈します（未指定なら既定値、上限で切り詰め）。
+func limitFromQuery(r *http.Request) (int, error) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return pageDefaultLimit, nil
+	}
+	v, err := strconv.Atoi(l)
+	if err != nil || v <= 0 {
+		return 0, BadRequest("invalid limit")
+	}
+	return min(v, pageMaxLimit), nil
+}
+
+// conditionalPut は If-Match / If-None-Match に従ってセットします。sliding ならスライディング期限にします。
+func (h *kvHandler) conditionalPut(r *http.Request, key, value string, ttl time.Duration, sliding bool) (uint64, error) {
+	ifMatch := parseETagHeader(r, "If-Match")
+	ifNoneMatch := parseETagHeader(r, "If-None-Match")
+	if !ifMatch.present && !ifNoneMatch.present {
+		if sliding {
+			return h.st.SetSlidingVersioned(key, value, ttl)
+		}
+		return h.st.SetVersioned(key, value, ttl)
+	}
+
+	var expected uint64 // 0 = 作成専用
+	_, cur, exists := h.st.GetVersioned(key)
+	if ifMatch.present {
+		if !exists || !ifMatch.matchStrong(formatETag(cur)) {
+			return 0, PreconditionFailed("If-Match precondition failed")
+		}
+		expected = cur
+	}
+	if ifNoneMatch.present {
+		if exists && ifNoneMatch.matchWeak(formatETag(cur)) {
+			return 0, PreconditionFailed("If-None-Match precondition failed")
+		}
+		if exists {
+			expected = cur
+		}
+	}
+	cas := h.st.CompareAndSwap
+	if sliding {
+		cas = h.st.CompareAndSwapSliding
+	}
+	ver, err := cas(key, expected, value, ttl)
+	if errors.Is(err, store.ErrVersionMismatch) || errors.Is(err, store.ErrNotFound) {
+		// 判定後に他クライアントが更新した
+		return 0, PreconditionFailed("precondition failed")
+	}
+	return ver, err
+}
+
 func (h *kvHandler) get(w http.ResponseWriter, r *http.Request) error {
 	key := chi.URLParam(r, "key")
 	if key == "" {
 		return BadRequest("empty key")
 	}
-	v, ok := h.st.Get(key)
-	if !ok {
+	// WithLoader（KAVOS_BACKEND_DIR の read-through など）があればミス時に読み込む
+	it, err := h.st.GetItemOrLoad(r.Context(), key)
+	if errors.Is(err, store.ErrNotFound) {
 		return NotFound("key not found")
 	}
-	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: v})
+	if err != nil {
+		return err
+	}
+	etag := formatETag(it.Version)
+	now := h.st.Clock().Now()
+	w.Header().Set("ETag", etag)
+	setCacheHeaders(w, it.ExpireAt, now)
+	if m := parseETagHeader(r, "If-None-Match"); m.present && m.matchWeak(etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: it.Value}.withExpiry(it.ExpireAt, now))
 	return nil
 }
 
@@ -87,7 +254,126 @@ func (h *kvHandler) del(w http.ResponseWriter, r *http.Request) error {
 	if key == "" {
 		return BadRequest("empty key")
 	}
-	h.st.Delete(key)
+	if m := parseETagHeader(r, "If-Match"); m.present {
+		_, cur, exists := h.st.GetVersioned(key)
+		if !exists || !m.matchStrong(formatETag(cur)) {
+			return PreconditionFailed("If-Match precondition failed")
+		}
+		if err := h.st.CompareAndDelete(key, cur); err != nil {
+			if errors.Is(err, store.ErrVersionMismatch) || errors.Is(err, store.ErrNotFound) {
+				return PreconditionFailed("precondition failed")
+			}
+			return err
+		}
+	} else if err := h.st.Delete(key); err != nil {
+		return err
+	}
 	writeSuccess(w, http.StatusOK, valueDTO{Key: key})
 	return nil
 }
+
+type incrRequest struct {
+	Delta *int64 `json:"delta"`
+}
+
+type incrFloatRequest struct {
+	Delta *float64 `json:"delta"`
+}
+
+func (h *kvHandler) incr(w http.ResponseWriter, r *http.Request) error {
+	return h.incrBy(w, r, 1)
+}
+
+func (h *kvHandler) decr(w http.ResponseWriter, r *http.Request) error {
+	return h.incrBy(w, r, -1)
+}
+
+// incrBy は body の delta (省略時 1) に sign を掛けて加算します。
+func (h *kvHandler) incrBy(w http.ResponseWriter, r *http.Request, sign int64) error {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		return BadRequest("empty key")
+	}
+	delta := int64(1)
+	if r.ContentLength != 0 {
+		var req incrRequest
+		if err := DecodeJSON(r, &req); err != nil {
+			return err
+		}
+		if req.Delta != nil {
+			delta = *req.Delta
+		}
+	}
+	if sign < 0 {
+		if delta == math.MinInt64 {
+			return BadRequest("delta out of range")
+		}
+		delta = -delta
+	}
+	n, err := h.st.Incr(key, delta, ttlFromQuery(r))
+	if err != nil {
+		return incrError(err)
+	}
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: strconv.FormatInt(n, 10)})
+	return nil
+}
+
+func (h *kvHandler) incrByFloat(w http.ResponseWriter, r *http.Request) error {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		return BadRequest("empty key")
+	}
+	var req incrFloatRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Delta == nil {
+		return BadRequest("delta is required")
+	}
+	f, err := h.st.IncrByFloat(key, *req.Delta, ttlFromQuery(r))
+	if err != nil {
+		return incrError(err)
+	}
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key, Value: strconv.FormatFloat(f, 'f', -1, 64)})
+	return nil
+}
+
+func incrError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNotInteger):
+		return NewAppError(http.StatusConflict, CodeNotNumeric, "value is not an integer", nil)
+	case errors.Is(err, store.ErrNotFloat):
+		return NewAppError(http.StatusConflict, CodeNotNumeric, "value is not a valid float", nil)
+	case errors.Is(err, store.ErrOverflow):
+		return NewAppError(http.StatusConflict, CodeConflict, "increment would overflow", nil)
+	default:
+		return err
+	}
+}
+
+// slidingFromQuery は ?sliding= を解釈します。スライディング期限には ?ttl= の指定が必要です。
+func slidingFromQuery(r *http.Request, ttl time.Duration) (bool, error) {
+	raw := r.URL.Query().Get("sliding")
+	if raw == "" {
+		return false, nil
+	}
+	sliding, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, BadRequest("invalid sliding")
+	}
+	if sliding && ttl <= 0 {
+		return false, BadRequest("sliding requires ttl")
+	}
+	return sliding, nil
+}
+
+// ttlFromQuery は ?ttl=秒 を解析します（不正値・0 以下は無期限）。
+func ttlFromQuery(r *http.Request) time.Duration {
+	if raw := r.URL.Query().Get("ttl"); raw != "" {
+		sec, err := strconv.ParseInt(raw, 10, 64)
+		if err == nil && sec > 0 {
+			return time.Duration(sec) * time.Second
+		}
+	}
+	return 0
+}
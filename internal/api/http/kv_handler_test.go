@@ -0,0 +1,524 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/clock/fake"
+	"github.com/amakane-hakari/kavos/internal/store"
+)
+
+func doReq(t *testing.T, method, url, body string, hdr map[string]string) *http.Response {
+	t.Helper()
+	var rd *bytes.Buffer
+	if body != "" {
+		rd = bytes.NewBufferString(body)
+	} else {
+		rd = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, url, rd)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	t.Cleanup(func() { _ = res.Body.Close() })
+	return res
+}
+
+func TestKVS_ETag(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	put := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"bar"}`, nil)
+	etag := put.Header.Get("ETag")
+	if put.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("put status=%d etag=%q", put.StatusCode, etag)
+	}
+
+	get := doReq(t, http.MethodGet, ts.URL+"/kvs/foo", "", nil)
+	if get.Header.Get("ETag") != etag {
+		t.Fatalf("get etag want %s got %s", etag, get.Header.Get("ETag"))
+	}
+	if get.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("persistent key should require revalidation, got %q", get.Header.Get("Cache-Control"))
+	}
+
+	notModified := doReq(t, http.MethodGet, ts.URL+"/kvs/foo", "", map[string]string{"If-None-Match": "W/" + etag})
+	if notModified.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 got %d", notModified.StatusCode)
+	}
+
+	modified := doReq(t, http.MethodGet, ts.URL+"/kvs/foo", "", map[string]string{"If-None-Match": `"0"`})
+	if modified.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", modified.StatusCode)
+	}
+}
+
+func TestKVS_IfMatch(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	etag := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v1"}`, nil).Header.Get("ETag")
+
+	ok := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v2"}`, map[string]string{"If-Match": etag})
+	if ok.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", ok.StatusCode)
+	}
+	newTag := ok.Header.Get("ETag")
+	if newTag == etag {
+		t.Fatalf("etag should change on update")
+	}
+
+	stale := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v3"}`, map[string]string{"If-Match": etag})
+	if stale.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", stale.StatusCode)
+	}
+	var errResp errorWrap
+	if err := json.NewDecoder(stale.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Error.Code != CodePreconditionFailed {
+		t.Fatalf("expected %s got %s", CodePreconditionFailed, errResp.Error.Code)
+	}
+
+	delStale := doReq(t, http.MethodDelete, ts.URL+"/kvs/foo", "", map[string]string{"If-Match": etag})
+	if delStale.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", delStale.StatusCode)
+	}
+	del := doReq(t, http.MethodDelete, ts.URL+"/kvs/foo", "", map[string]string{"If-Match": newTag})
+	if del.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", del.StatusCode)
+	}
+
+	missing := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v4"}`, map[string]string{"If-Match": "*"})
+	if missing.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("If-Match: * on missing key should fail, got %d", missing.StatusCode)
+	}
+}
+
+func TestKVS_IfNoneMatchCreateOnly(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	first := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v1"}`, map[string]string{"If-None-Match": "*"})
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", first.StatusCode)
+	}
+	second := doReq(t, http.MethodPut, ts.URL+"/kvs/foo", `{"value":"v2"}`, map[string]string{"If-None-Match": "*"})
+	if second.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", second.StatusCode)
+	}
+}
+
+func TestKVS_CacheHeadersFromTTL(t *testing.T) {
+	clk := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ts := httptest.NewServer(NewRouter(store.New[string, string](store.WithClock(clk)), nil))
+	defer ts.Close()
+
+	doReq(t, http.MethodPut, ts.URL+"/kvs/tmp?ttl=60", `{"value":"x"}`, nil)
+	clk.Advance(15 * time.Second)
+	res := doReq(t, http.MethodGet, ts.URL+"/kvs/tmp", "", nil)
+
+	cc := res.Header.Get("Cache-Control")
+	if !strings.HasPrefix(cc, "max-age=") {
+		t.Fatalf("unexpected Cache-Control %q", cc)
+	}
+	age, err := strconv.Atoi(strings.TrimPrefix(cc, "max-age="))
+	if err != nil || age != 45 {
+		t.Fatalf("max-age should reflect remaining ttl, got %q", cc)
+	}
+	if exp, err := http.ParseTime(res.Header.Get("Expires")); err != nil || !exp.Equal(clk.Now().Add(45*time.Second)) {
+		t.Fatalf("invalid Expires %q: %v", res.Header.Get("Expires"), err)
+	}
+}
+
+func TestKVS_TTLEndpoints(t *testing.T) {
+	clk := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	st := store.New[string, string](store.WithClock(clk))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	decode := func(res *http.Response) kvData {
+		t.Helper()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status=%d", res.StatusCode)
+		}
+		var sw successWrap[kvData]
+		if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return sw.Data
+	}
+
+	doReq(t, http.MethodPut, ts.URL+"/kvs/k?ttl=60", `{"value":"v"}`, nil)
+	clk.Advance(15 * time.Second)
+
+	// GET の応答に期限が含まれる
+	d := decode(doReq(t, http.MethodGet, ts.URL+"/kvs/k", "", nil))
+	if d.Value != "v" || d.TTLMs == nil || *d.TTLMs != 45000 || d.ExpiresAt == nil || !d.ExpiresAt.Equal(clk.Now().Add(45*time.Second)) {
+		t.Fatalf("unexpected expiry in GET: %+v", d)
+	}
+	d = decode(doReq(t, http.MethodGet, ts.URL+"/kvs/k/ttl", "", nil))
+	if d.Value != "" || d.TTLMs == nil || *d.TTLMs != 45000 {
+		t.Fatalf("unexpected ttl: %+v", d)
+	}
+
+	_, ver, _ := st.GetVersioned("k")
+	d = decode(doReq(t, http.MethodPut, ts.URL+"/kvs/k/ttl", `{"ttl_ms":120000}`, nil))
+	if d.TTLMs == nil || *d.TTLMs != 120000 {
+		t.Fatalf("ttl_ms should be updated: %+v", d)
+	}
+	if _, cur, _ := st.GetVersioned("k"); cur != ver {
+		t.Fatalf("changing ttl must not rewrite the value")
+	}
+	at := clk.Now().Add(time.Hour)
+	d = decode(doReq(t, http.MethodPut, ts.URL+"/kvs/k/ttl", `{"expires_at":"`+at.Format(time.RFC3339)+`"}`, nil))
+	if d.ExpiresAt == nil || !d.ExpiresAt.Equal(at) {
+		t.Fatalf("expires_at should be updated: %+v", d)
+	}
+
+	d = decode(doReq(t, http.MethodDelete, ts.URL+"/kvs/k/ttl", "", nil))
+	if d.TTLMs != nil || d.ExpiresAt != nil {
+		t.Fatalf("persisted key should omit expiry: %+v", d)
+	}
+	d = decode(doReq(t, http.MethodGet, ts.URL+"/kvs/k", "", nil))
+	if d.TTLMs != nil || d.ExpiresAt != nil {
+		t.Fatalf("persisted key should omit expiry in GET: %+v", d)
+	}
+
+	d = decode(doReq(t, http.MethodPut, ts.URL+"/kvs/k/ttl", `{"ttl_ms":0}`, nil))
+	if d.TTLMs == nil || *d.TTLMs != 0 {
+		t.Fatalf("ttl_ms=0 should expire immediately: %+v", d)
+	}
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/k", "", nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expired key status=%d", res.StatusCode)
+	}
+
+	for _, tc := range []struct {
+		method, url, body string
+		status            int
+	}{
+		{http.MethodGet, "/kvs/missing/ttl", "", http.StatusNotFound},
+		{http.MethodPut, "/kvs/missing/ttl", `{"ttl_ms":1000}`, http.StatusNotFound},
+		{http.MethodDelete, "/kvs/missing/ttl", "", http.StatusNotFound},
+		{http.MethodPut, "/kvs/k/ttl", `{}`, http.StatusBadRequest},
+		{http.MethodPut, "/kvs/k/ttl", `{"ttl_ms":1,"expires_at":"2024-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{http.MethodPut, "/kvs/k/ttl", `{"ttl_ms":9223372036854775807}`, http.StatusBadRequest},
+		{http.MethodPut, "/kvs/k/ttl", `{"ttl_ms":9223372036854}`, http.StatusBadRequest},
+	} {
+		if res := doReq(t, tc.method, ts.URL+tc.url, tc.body, nil); res.StatusCode != tc.status {
+			t.Fatalf("%s %s: want %d got %d", tc.method, tc.url, tc.status, res.StatusCode)
+		}
+	}
+}
+
+func TestKVS_SlidingTTL(t *testing.T) {
+	clk := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	st := store.New[string, string](store.WithClock(clk))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	if res := doReq(t, http.MethodPut, ts.URL+"/kvs/sess?ttl=10&sliding=true", `{"value":"v"}`, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("put status=%d", res.StatusCode)
+	}
+	for range 3 {
+		clk.Advance(8 * time.Second)
+		res := doReq(t, http.MethodGet, ts.URL+"/kvs/sess", "", nil)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("sliding key should be extended by GET, status=%d", res.StatusCode)
+		}
+		var sw successWrap[kvData]
+		if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if sw.Data.TTLMs == nil || *sw.Data.TTLMs != 10000 {
+			t.Fatalf("GET should report the extended ttl: %+v", sw.Data)
+		}
+	}
+	// GET /ttl は期限を参照するだけで延長しない
+	clk.Advance(8 * time.Second)
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/sess/ttl", "", nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("ttl status=%d", res.StatusCode)
+	}
+	clk.Advance(2 * time.Second)
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/sess", "", nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /ttl must not extend a sliding key, status=%d", res.StatusCode)
+	}
+
+	// 条件つき PUT でもスライディング期限にできる
+	res := doReq(t, http.MethodPut, ts.URL+"/kvs/cas?ttl=10&sliding=1", `{"value":"v"}`, map[string]string{"If-None-Match": "*"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("conditional put status=%d", res.StatusCode)
+	}
+	clk.Advance(8 * time.Second)
+	st.Get("cas")
+	clk.Advance(8 * time.Second)
+	if _, ok := st.Get("cas"); !ok {
+		t.Fatalf("conditional put should set sliding ttl")
+	}
+
+	for _, q := range []string{"?sliding=true", "?ttl=10&sliding=maybe"} {
+		if res := doReq(t, http.MethodPut, ts.URL+"/kvs/bad"+q, `{"value":"v"}`, nil); res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: want 400 got %d", q, res.StatusCode)
+		}
+	}
+}
+
+func TestKVS_IncrDecr(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	decode := func(res *http.Response) string {
+		t.Helper()
+		var sw successWrap[kvData]
+		if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return sw.Data.Value
+	}
+
+	if v := decode(doReq(t, http.MethodPost, ts.URL+"/kvs/c/incr", "", nil)); v != "1" {
+		t.Fatalf("incr want 1 got %s", v)
+	}
+	if v := decode(doReq(t, http.MethodPost, ts.URL+"/kvs/c/incr", `{"delta":10}`, nil)); v != "11" {
+		t.Fatalf("incr want 11 got %s", v)
+	}
+	if v := decode(doReq(t, http.MethodPost, ts.URL+"/kvs/c/decr", `{"delta":3}`, nil)); v != "8" {
+		t.Fatalf("decr want 8 got %s", v)
+	}
+	if v := decode(doReq(t, http.MethodPost, ts.URL+"/kvs/c/incrbyfloat", `{"delta":0.25}`, nil)); v != "8.25" {
+		t.Fatalf("incrbyfloat want 8.25 got %s", v)
+	}
+
+	res := doReq(t, http.MethodPost, ts.URL+"/kvs/c/incr", "", nil)
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 got %d", res.StatusCode)
+	}
+	var errResp errorWrap
+	if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Error.Code != CodeNotNumeric {
+		t.Fatalf("expected %s got %s", CodeNotNumeric, errResp.Error.Code)
+	}
+}
+
+func TestKVS_List(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	for _, k := range []string{"user:1", "user:2", "user:3", "order:1", "a*b"} {
+		doReq(t, http.MethodPut, ts.URL+"/kvs/"+url.PathEscape(k), `{"value":"v"}`, nil)
+	}
+
+	list := func(query string) []string {
+		t.Helper()
+		var keys []string
+		cursor := "0"
+		for {
+			res := doReq(t, http.MethodGet, ts.URL+"/kvs?"+query+"&limit=1&cursor="+cursor, "", nil)
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("list %q: status %d", query, res.StatusCode)
+			}
+			var sw successWrap[scanDTO]
+			if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			keys = append(keys, sw.Data.Keys...)
+			if sw.Data.Cursor == "0" {
+				sort.Strings(keys)
+				return keys
+			}
+			cursor = sw.Data.Cursor
+		}
+	}
+
+	if got := list(""); len(got) != 5 {
+		t.Fatalf("expected 5 keys, got %v", got)
+	}
+	if got := list("prefix=user:"); !slices.Equal(got, []string{"user:1", "user:2", "user:3"}) {
+		t.Fatalf("prefix: unexpected %v", got)
+	}
+	if got := list("match=*:1"); !slices.Equal(got, []string{"order:1", "user:1"}) {
+		t.Fatalf("match: unexpected %v", got)
+	}
+	if got := list("prefix=user:&match=*[23]"); !slices.Equal(got, []string{"user:2", "user:3"}) {
+		t.Fatalf("prefix+match: unexpected %v", got)
+	}
+	// prefix のメタ文字はリテラル扱い
+	if got := list("prefix=" + url.QueryEscape("a*")); !slices.Equal(got, []string{"a*b"}) {
+		t.Fatalf("literal prefix: unexpected %v", got)
+	}
+
+	for _, q := range []string{"cursor=x", "limit=0", "limit=abc"} {
+		if res := doReq(t, http.MethodGet, ts.URL+"/kvs?"+q, "", nil); res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", q, res.StatusCode)
+		}
+	}
+}
+
+func TestKVS_Range(t *testing.T) {
+	ts := httptest.NewServer(NewRouter(store.New[string, string](store.WithOrderedIndex()), nil))
+	defer ts.Close()
+
+	for _, k := range []string{"m:03", "m:01", "m:02", "n:01"} {
+		doReq(t, http.MethodPut, ts.URL+"/kvs/"+k, `{"value":"v-`+k+`"}`, nil)
+	}
+
+	get := func(query string) []kvData {
+		t.Helper()
+		res := doReq(t, http.MethodGet, ts.URL+"/kvs-range?"+query, "", nil)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", query, res.StatusCode)
+		}
+		var sw successWrap[struct {
+			Items []kvData `json:"items"`
+		}]
+		if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return sw.Data.Items
+	}
+
+	items := get("start=m:&end=m%3B")
+	if len(items) != 3 || items[0].Key != "m:01" || items[2].Key != "m:03" || items[0].Value != "v-m:01" {
+		t.Fatalf("forward: %+v", items)
+	}
+	items = get("start=m:&end=m%3B&reverse=true&limit=2")
+	if len(items) != 2 || items[0].Key != "m:03" || items[1].Key != "m:02" {
+		t.Fatalf("reverse: %+v", items)
+	}
+	if items = get(""); len(items) != 4 {
+		t.Fatalf("unbounded: %+v", items)
+	}
+
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs-range?reverse=maybe", "", nil); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+}
+
+func TestKVS_RangeDisabled(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	res := doReq(t, http.MethodGet, ts.URL+"/kvs-range", "", nil)
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 got %d", res.StatusCode)
+	}
+	var errResp errorWrap
+	if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Error.Code != CodeNotImplemented {
+		t.Fatalf("expected %s got %s", CodeNotImplemented, errResp.Error.Code)
+	}
+}
+
+func TestKVS_ValueTooLarge(t *testing.T) {
+	st := store.New[string, string](store.WithMaxBytes(256))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	big := strings.Repeat("x", 300)
+	res := doReq(t, http.MethodPut, ts.URL+"/kvs/big", `{"value":"`+big+`"}`, nil)
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 got %d", res.StatusCode)
+	}
+	var errResp errorWrap
+	if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Error.Code != CodeValueTooLarge {
+		t.Fatalf("expected %s got %s", CodeValueTooLarge, errResp.Error.Code)
+	}
+	if _, ok := st.Get("big"); ok {
+		t.Fatalf("rejected value must not be stored")
+	}
+
+	if res := doReq(t, http.MethodPut, ts.URL+"/kvs/small", `{"value":"ok"}`, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("small put status=%d", res.StatusCode)
+	}
+}
+
+func TestKVS_BackendError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backend")
+	b, err := store.NewFileBackend[string, string](dir)
+	if err != nil {
+		t.Fatalf("backend: %v", err)
+	}
+	st := store.New[string, string](store.WithWriteThrough[string, string](b))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	if res := doReq(t, http.MethodPut, ts.URL+"/kvs/a", `{"value":"1"}`, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("put status=%d", res.StatusCode)
+	}
+	// Backend のディレクトリを消して書き込みを失敗させる
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	res := doReq(t, http.MethodPut, ts.URL+"/kvs/a", `{"value":"2"}`, nil)
+	if res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 got %d", res.StatusCode)
+	}
+	var errResp errorWrap
+	if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Error.Code != CodeBackendError {
+		t.Fatalf("expected %s got %s", CodeBackendError, errResp.Error.Code)
+	}
+	if v, _ := st.Get("a"); v != "1" {
+		t.Fatalf("failed write must not change the store, got %q", v)
+	}
+}
+
+func TestKVS_GetReadThrough(t *testing.T) {
+	b, err := store.NewFileBackend[string, string](t.TempDir())
+	if err != nil {
+		t.Fatalf("backend: %v", err)
+	}
+	if err := b.Store(context.Background(), "cold", "from-backend"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	st := store.New[string, string](store.WithWriteThrough[string, string](b))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	defer ts.Close()
+
+	// キャッシュにないキーは Backend から読み込まれる
+	res := doReq(t, http.MethodGet, ts.URL+"/kvs/cold", "", nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("read-through status=%d", res.StatusCode)
+	}
+	var sw successWrap[kvData]
+	if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sw.Data.Value != "from-backend" || res.Header.Get("ETag") == "" {
+		t.Fatalf("unexpected read-through response: %+v etag=%q", sw.Data, res.Header.Get("ETag"))
+	}
+	if v, ok := st.GetItem("cold"); !ok || v.Value != "from-backend" {
+		t.Fatalf("loaded value should be cached")
+	}
+	if res := doReq(t, http.MethodGet, ts.URL+"/kvs/missing", "", nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing key status=%d", res.StatusCode)
+	}
+}
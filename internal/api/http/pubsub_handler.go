@@ -0,0 +1,111 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/pubsub"
+	"github.com/go-chi/chi/v5"
+)
+
+type pubsubHandler struct {
+	broker    *pubsub.Broker
+	heartbeat time.Duration
+}
+
+func (h *pubsubHandler) mount(r chi.Router) {
+	r.Route("/pubsub", func(r chi.Router) {
+		r.Post("/{channel}", wrap(h.publish))
+		r.Get("/{channel}", wrap(h.subscribe))
+	})
+}
+
+type publishRequest struct {
+	Message string `json:"message"`
+}
+
+type publishDTO struct {
+	Channel   string `json:"channel"`
+	Receivers int    `json:"receivers"`
+}
+
+type messageDTO struct {
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern,omitempty"`
+	Message string `json:"message"`
+}
+
+// publish はチャネルにメッセージを送ります（POST /pubsub/{channel}）。
+func (h *pubsubHandler) publish(w http.ResponseWriter, r *http.Request) error {
+	channel := chi.URLParam(r, "channel")
+	if channel == "" {
+		return BadRequest("empty channel")
+	}
+	var req publishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest("invalid json")
+	}
+	n := h.broker.Publish(channel, req.Message)
+	writeSuccess(w, http.StatusOK, publishDTO{Channel: channel, Receivers: n})
+	return nil
+}
+
+// subscribe はチャネルのメッセージを Server-Sent Events で配信します（GET /pubsub/{channel}）。
+// ?pattern=true でチャネル名をグロブパターンとして購読し、
+// ?policy=drop-newest|drop-oldest|disconnect でキューが溢れたときの扱いを指定します（省略時はブローカーの既定値）。
+func (h *pubsubHandler) subscribe(w http.ResponseWriter, r *http.Request) error {
+	channel := chi.URLParam(r, "channel")
+	if channel == "" {
+		return BadRequest("empty channel")
+	}
+	q := r.URL.Query()
+	var pattern bool
+	if v := q.Get("pattern"); v != "" {
+		var err error
+		if pattern, err = strconv.ParseBool(v); err != nil {
+			return BadRequest("invalid pattern")
+		}
+	}
+	cfg := pubsub.SubscribeConfig{Policy: h.broker.DefaultPolicy()}
+	if v := q.Get("policy"); v != "" {
+		policy, err := pubsub.ParseOverflowPolicy(v)
+		if errors.Is(err, pubsub.ErrInvalidPolicy) {
+			return BadRequest("invalid policy")
+		}
+		cfg.Policy = policy
+	}
+	if pattern {
+		cfg.Patterns = []string{channel}
+	} else {
+		cfg.Channels = []string{channel}
+	}
+	sub := h.broker.SubscribeWith(cfg)
+	defer sub.Close()
+
+	sse, err := startSSE(w)
+	if err != nil {
+		return nil
+	}
+	ticker := time.NewTicker(heartbeatInterval(h.heartbeat))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case m, ok := <-sub.C():
+			if !ok {
+				return nil
+			}
+			if err := sse.event("", "message", messageDTO{Channel: m.Channel, Pattern: m.Pattern, Message: m.Payload}); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			if err := sse.ping(); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/pubsub"
+	"github.com/amakane-hakari/kavos/internal/store"
+)
+
+func TestPubSub_PublishSubscribe(t *testing.T) {
+	b := pubsub.New()
+	ts := httptest.NewServer(NewRouter(store.New[string, string](), nil, WithBroker(b)))
+	t.Cleanup(ts.Close)
+
+	_, direct := openSSE(t, ts.URL+"/pubsub/news", nil)
+	_, pattern := openSSE(t, ts.URL+"/pubsub/news.*?pattern=true", nil)
+	// 購読の登録を待つ
+	deadline := time.Now().Add(2 * time.Second)
+	for b.NumSubscriptions() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	res := doReq(t, http.MethodPost, ts.URL+"/pubsub/news", `{"message":"hello"}`, nil)
+	var sw successWrap[publishDTO]
+	if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || sw.Data.Receivers != 1 {
+		t.Fatalf("publish: status=%d receivers=%d", res.StatusCode, sw.Data.Receivers)
+	}
+	doReq(t, http.MethodPost, ts.URL+"/pubsub/news.tech", `{"message":"go"}`, nil)
+
+	var m messageDTO
+	ev := nextSSE(t, direct)
+	if err := json.Unmarshal([]byte(ev.data), &m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev.event != "message" || m.Channel != "news" || m.Message != "hello" {
+		t.Fatalf("direct: unexpected %+v %+v", ev, m)
+	}
+	ev = nextSSE(t, pattern)
+	m = messageDTO{}
+	if err := json.Unmarshal([]byte(ev.data), &m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m.Channel != "news.tech" || m.Pattern != "news.*" || m.Message != "go" {
+		t.Fatalf("pattern: unexpected %+v", m)
+	}
+}
+
+func TestPubSub_BadRequest(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	t.Cleanup(ts.Close)
+
+	for _, u := range []string{"/pubsub/c?policy=never", "/pubsub/c?pattern=maybe"} {
+		if res := doReq(t, http.MethodGet, ts.URL+u, "", nil); res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", u, res.StatusCode)
+		}
+	}
+	if res := doReq(t, http.MethodPost, ts.URL+"/pubsub/c", `{`, nil); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+}
+
+func TestPubSub_DefaultPolicy(t *testing.T) {
+	// ?policy= を省略した購読にはブローカーの既定のポリシーを適用する
+	b := pubsub.New(pubsub.WithBufferSize(1), pubsub.WithOverflowPolicy(pubsub.Disconnect))
+	ts := httptest.NewServer(NewRouter(store.New[string, string](), nil, WithBroker(b)))
+	t.Cleanup(ts.Close)
+
+	openSSE(t, ts.URL+"/pubsub/c", nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for b.NumSubscriptions() < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 受信側を読まずに送り続ければいずれキューが溢れ、Disconnect なら購読が終了する
+	payload := strings.Repeat("x", 1024)
+	for i := 0; i < 100_000 && b.NumSubscriptions() > 0; i++ {
+		b.Publish("c", payload)
+	}
+	if n := b.NumSubscriptions(); n != 0 {
+		t.Fatalf("subscription should be disconnected by the broker default policy, subscriptions=%d", n)
+	}
+}
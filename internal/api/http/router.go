@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 
+	"github.com/amakane-hakari/kavos/internal/pubsub"
 	"github.com/amakane-hakari/kavos/internal/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -10,8 +11,29 @@ import (
 	ilog "github.com/amakane-hakari/kavos/internal/log"
 )
 
+type routerConfig struct {
+	broker *pubsub.Broker
+}
+
+// RouterOption は NewRouter のオプションを設定する関数です。
+type RouterOption func(*routerConfig)
+
+// WithBroker は /pubsub で使用するブローカーを設定するオプションです。
+// 指定しない場合は既定設定のブローカーを作成します。
+func WithBroker(b *pubsub.Broker) RouterOption {
+	return func(c *routerConfig) { c.broker = b }
+}
+
 // NewRouter は KVSのHTTPルーターを作成します。
-func NewRouter(st *store.Store[string, string], logger ilog.Logger) http.Handler {
+func NewRouter(st *store.Store[string, string], logger ilog.Logger, opts ...RouterOption) http.Handler {
+	var cfg routerConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.broker == nil {
+		cfg.broker = pubsub.New()
+	}
+
 	r := chi.NewRouter()
 	r.Use(RequestIDMiddleware(), RecoverMiddleware())
 	r.Use(AccessLog(logger))
@@ -27,5 +49,17 @@ func NewRouter(st *store.Store[string, string], logger ilog.Logger) http.Handler
 	kv := &kvHandler{st: st}
 	kv.mount(r)
 
+	txn := &txnHandler{st: st}
+	txn.mount(r)
+
+	watch := &watchHandler{st: st}
+	watch.mount(r)
+
+	ps := &pubsubHandler{broker: cfg.broker}
+	ps.mount(r)
+
+	admin := &adminHandler{st: st}
+	admin.mount(r)
+
 	return r
 }
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/amakane-hakari/kavos/internal/clock/fake"
 	"github.com/amakane-hakari/kavos/internal/store"
 )
 
@@ -21,8 +23,10 @@ type successWrap[T any] struct {
 }
 
 type kvData struct {
-	Key   string `json:"key"`
-	Value string `json:"value,omitempty"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTLMs     *int64     `json:"ttl_ms,omitempty"`
 }
 
 type errorWrap struct {
@@ -126,7 +130,8 @@ func TestKVS_CRUD(t *testing.T) {
 }
 
 func TestKVS_TTL(t *testing.T) {
-	ts := httptest.NewServer(NewRouter(store.New[string, string](), nil))
+	clk := fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ts := httptest.NewServer(NewRouter(store.New[string, string](store.WithClock(clk)), nil))
 	defer ts.Close()
 
 	// PUT with ttl=1(1秒)
@@ -142,7 +147,7 @@ func TestKVS_TTL(t *testing.T) {
 		t.Fatalf("get before expiry failed: %v code=%v", err, resp.StatusCode)
 	}
 
-	time.Sleep(1100 * time.Millisecond)
+	clk.Advance(1100 * time.Millisecond)
 
 	// After expiry
 	resp, err := http.Get(ts.URL + "/kvs/tmp")
@@ -155,3 +160,33 @@ func TestKVS_TTL(t *testing.T) {
 		t.Fatalf("expected 404 got %d body=%v", resp.StatusCode, dbg)
 	}
 }
+
+func TestAdmin_AOFRewrite(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	// AOF 無効
+	res, err := http.Post(ts.URL+"/admin/aof/rewrite", "application/json", nil)
+	if err != nil {
+		t.Fatalf("post error: %v", err)
+	}
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", res.StatusCode)
+	}
+
+	st, err := store.Open[string, string](store.WithAOF(filepath.Join(t.TempDir(), "kavos.aof"), store.FsyncNo))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ts2 := httptest.NewServer(NewRouter(st, nil))
+	defer ts2.Close()
+
+	res2, err := http.Post(ts2.URL+"/admin/aof/rewrite", "application/json", nil)
+	if err != nil {
+		t.Fatalf("post error: %v", err)
+	}
+	if res2.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", res2.StatusCode)
+	}
+}
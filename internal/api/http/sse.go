@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseHeartbeat はアイドル時にプロキシの切断を防ぐためのコメント送信間隔です。
+const sseHeartbeat = 15 * time.Second
+
+// sseWriter は Server-Sent Events の書き込みを行います。
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// startSSE はストリーム用のヘッダを送信します。
+func startSSE(w http.ResponseWriter) (*sseWriter, error) {
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	s := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	return s, s.rc.Flush()
+}
+
+// event は 1 イベントを書き込みます。id が空なら id 行を省略します。
+func (s *sseWriter) event(id, name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// ping はコメント行を書き込みます。
+func (s *sseWriter) ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func heartbeatInterval(d time.Duration) time.Duration {
+	if d <= 0 {
+		return sseHeartbeat
+	}
+	return d
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// ttlRequest は PUT /kvs/{key}/ttl のボディです。ttl_ms と expires_at のどちらか一方を指定します。
+type ttlRequest struct {
+	TTLMs     *int64     `json:"ttl_ms"`     // 現在からのミリ秒。0 以下なら直ちに期限切れ
+	ExpiresAt *time.Time `json:"expires_at"` // 絶対時刻 (RFC 3339)
+}
+
+// getTTL はキーの期限を返します（GET /kvs/{key}/ttl）。無期限なら expires_at / ttl_ms を省略します。
+func (h *kvHandler) getTTL(w http.ResponseWriter, r *http.Request) error {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		return BadRequest("empty key")
+	}
+	now := h.st.Clock().Now()
+	expireAt, ok := h.expireAt(key, now)
+	if !ok {
+		return NotFound("key not found")
+	}
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key}.withExpiry(expireAt, now))
+	return nil
+}
+
+// putTTL は値を書き換えずにキーの期限を変更します（PUT /kvs/{key}/ttl）。
+func (h *kvHandler) putTTL(w http.ResponseWriter, r *http.Request) error {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		return BadRequest("empty key")
+	}
+	var req ttlRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	var err error
+	switch {
+	case req.TTLMs != nil && req.ExpiresAt != nil:
+		return BadRequest("specify either ttl_ms or expires_at")
+	case req.TTLMs != nil:
+		ttl, terr := ttlFromMillis(*req.TTLMs, h.st.Clock().Now())
+		if terr != nil {
+			return terr
+		}
+		err = h.st.Expire(key, ttl)
+	case req.ExpiresAt != nil:
+		err = h.st.ExpireAt(key, *req.ExpiresAt)
+	default:
+		return BadRequest("ttl_ms or expires_at is required")
+	}
+	if err != nil {
+		return ttlError(err)
+	}
+	return h.writeTTL(w, key)
+}
+
+// deleteTTL はキーの期限を取り除きます（DELETE /kvs/{key}/ttl）。
+func (h *kvHandler) deleteTTL(w http.ResponseWriter, r *http.Request) error {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		return BadRequest("empty key")
+	}
+	if err := h.st.Persist(key); err != nil {
+		return ttlError(err)
+	}
+	return h.writeTTL(w, key)
+}
+
+// writeTTL は変更後の期限を返します。直ちに期限切れにした場合は ttl_ms=0 を返します。
+func (h *kvHandler) writeTTL(w http.ResponseWriter, key string) error {
+	now := h.st.Clock().Now()
+	expireAt, ok := h.expireAt(key, now)
+	if !ok {
+		expireAt = now
+	}
+	writeSuccess(w, http.StatusOK, valueDTO{Key: key}.withExpiry(expireAt, now))
+	return nil
+}
+
+// expireAt はキーの期限を返します（無期限ならゼロ値）。
+// GetItem と違いヒット / ミスの計上や evictor への通知、スライディング期限の延長を行いません。
+func (h *kvHandler) expireAt(key string, now time.Time) (time.Time, bool) {
+	ttl, ok := h.st.TTL(key)
+	if !ok || ttl == 0 {
+		return time.Time{}, ok
+	}
+	return now.Add(ttl), true
+}
+
+// ttlFromMillis は ttl_ms を time.Duration に変換します。0 以下は 0 とし、
+// 期限が time.Duration / UnixNano の範囲を超える値は 400 Bad Request とします。
+func ttlFromMillis(ms int64, now time.Time) (time.Duration, error) {
+	if ms <= 0 {
+		return 0, nil
+	}
+	if ms > (math.MaxInt64-now.UnixNano())/int64(time.Millisecond) {
+		return 0, BadRequest("ttl_ms is too large")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func ttlError(err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return NotFound("key not found")
+	}
+	return err
+}
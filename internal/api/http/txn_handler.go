@@ -0,0 +1,127 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type txnHandler struct {
+	st *store.Store[string, string]
+}
+
+func (h *txnHandler) mount(r chi.Router) {
+	r.Post("/txn", wrap(h.txn))
+}
+
+type txnOpRequest struct {
+	Op      string `json:"op"` // get / set / delete / check
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	TTL     int64  `json:"ttl,omitempty"`     // set: 秒
+	Version uint64 `json:"version,omitempty"` // check: 期待するバージョン (0 = 存在しないこと)
+}
+
+type txnRequest struct {
+	Ops []txnOpRequest `json:"ops"`
+}
+
+type txnOpResultDTO struct {
+	Op      string    `json:"op"`
+	Key     string    `json:"key"`
+	Found   bool      `json:"found"`
+	Value   string    `json:"value,omitempty"`
+	Version uint64    `json:"version,omitempty"`
+	Error   *AppError `json:"error,omitempty"` // 失敗した check だけ。エラーエンベロープの error と同じ形式
+}
+
+type txnResponse struct {
+	Committed bool             `json:"committed"`
+	Results   []txnOpResultDTO `json:"results"`
+}
+
+const maxTxnOps = 1000
+
+var txnOpNames = map[store.TxOpType]string{
+	store.TxGet:    "get",
+	store.TxSet:    "set",
+	store.TxDelete: "delete",
+	store.TxCheck:  "check",
+}
+
+func (h *txnHandler) txn(w http.ResponseWriter, r *http.Request) error {
+	var req txnRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		return err
+	}
+	if len(req.Ops) == 0 {
+		return BadRequest("empty ops")
+	}
+	if len(req.Ops) > maxTxnOps {
+		return BadRequest("too many ops")
+	}
+	for _, op := range req.Ops {
+		if op.Key == "" {
+			return BadRequest("empty key")
+		}
+		if _, ok := txnOpType(op.Op); !ok {
+			return BadRequest("unknown op: " + op.Op)
+		}
+	}
+
+	results, err := h.st.Txn(func(tx *store.Tx[string, string]) error {
+		for _, op := range req.Ops {
+			typ, _ := txnOpType(op.Op)
+			switch typ {
+			case store.TxGet:
+				tx.Get(op.Key)
+			case store.TxSet:
+				var ttl time.Duration
+				if op.TTL > 0 {
+					ttl = time.Duration(op.TTL) * time.Second
+				}
+				tx.Set(op.Key, op.Value, ttl)
+			case store.TxDelete:
+				tx.Delete(op.Key)
+			case store.TxCheck:
+				tx.Check(op.Key, op.Version)
+			}
+		}
+		return nil
+	})
+
+	dtos := make([]txnOpResultDTO, len(results))
+	for i, res := range results {
+		dtos[i] = txnOpResultDTO{
+			Op:      txnOpNames[res.Op],
+			Key:     res.Key,
+			Found:   res.Found,
+			Value:   res.Value,
+			Version: res.Version,
+		}
+		if res.Err != nil {
+			dtos[i].Error = FromStdError(res.Err)
+		}
+	}
+	if errors.Is(err, store.ErrTxnAborted) {
+		return NewAppError(http.StatusConflict, CodeConflict, "transaction aborted",
+			txnResponse{Committed: false, Results: dtos})
+	}
+	if err != nil {
+		return err
+	}
+	writeSuccess(w, http.StatusOK, txnResponse{Committed: true, Results: dtos})
+	return nil
+}
+
+func txnOpType(op string) (store.TxOpType, bool) {
+	for typ, name := range txnOpNames {
+		if name == op {
+			return typ, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type txnResultData struct {
+	Committed bool `json:"committed"`
+	Results   []struct {
+		Op      string `json:"op"`
+		Key     string `json:"key"`
+		Found   bool   `json:"found"`
+		Value   string `json:"value"`
+		Version uint64 `json:"version"`
+		Error   *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"results"`
+}
+
+func TestTxn_Commit(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	doReq(t, http.MethodPut, ts.URL+"/kvs/a", `{"value":"1"}`, nil)
+	res := doReq(t, http.MethodPost, ts.URL+"/txn", `{"ops":[
+		{"op":"get","key":"a"},
+		{"op":"set","key":"b","value":"2"},
+		{"op":"delete","key":"a"},
+		{"op":"get","key":"a"}
+	]}`, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", res.StatusCode)
+	}
+	var sw successWrap[txnResultData]
+	if err := json.NewDecoder(res.Body).Decode(&sw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	d := sw.Data
+	if !d.Committed || len(d.Results) != 4 {
+		t.Fatalf("unexpected response %+v", d)
+	}
+	if !d.Results[0].Found || d.Results[0].Value != "1" {
+		t.Fatalf("get result %+v", d.Results[0])
+	}
+	if d.Results[1].Version == 0 {
+		t.Fatalf("set should return new version")
+	}
+	if d.Results[3].Found {
+		t.Fatalf("get after delete should not find key")
+	}
+	if got := doReq(t, http.MethodGet, ts.URL+"/kvs/b", "", nil); got.StatusCode != http.StatusOK {
+		t.Fatalf("b should exist, got %d", got.StatusCode)
+	}
+}
+
+func TestTxn_Abort(t *testing.T) {
+	ts := httptest.NewServer(newTestServer())
+	defer ts.Close()
+
+	doReq(t, http.MethodPut, ts.URL+"/kvs/a", `{"value":"1"}`, nil)
+	res := doReq(t, http.MethodPost, ts.URL+"/txn", `{"ops":[
+		{"op":"check","key":"a","version":999},
+		{"op":"set","key":"a","value":"2"}
+	]}`, nil)
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 got %d", res.StatusCode)
+	}
+	var body struct {
+		Error struct {
+			Code string        `json:"code"`
+			Meta txnResultData `json:"meta"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Error.Code != CodeConflict || body.Error.Meta.Committed {
+		t.Fatalf("unexpected abort body %+v", body.Error)
+	}
+	if e := body.Error.Meta.Results[0].Error; e == nil || e.Code != CodeConflict || e.Message == "" {
+		t.Fatalf("failed check should carry an AppError, got %+v", e)
+	}
+	if e := body.Error.Meta.Results[1].Error; e != nil {
+		t.Fatalf("only the failed op should carry an error, got %+v", e)
+	}
+
+	res = doReq(t, http.MethodPost, ts.URL+"/txn", `{"ops":[{"op":"check","key":"missing","version":1}]}`, nil)
+	body.Error.Meta = txnResultData{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if e := body.Error.Meta.Results[0].Error; res.StatusCode != http.StatusConflict || e == nil || e.Code != CodeNotFound {
+		t.Fatalf("check on a missing key should report NOT_FOUND, status=%d err=%+v", res.StatusCode, e)
+	}
+
+	bad := doReq(t, http.MethodPost, ts.URL+"/txn", `{"ops":[{"op":"incr","key":"a"}]}`, nil)
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", bad.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type watchHandler struct {
+	st        *store.Store[string, string]
+	heartbeat time.Duration
+}
+
+func (h *watchHandler) mount(r chi.Router) {
+	r.Get("/watch", wrap(h.watch))
+}
+
+type watchEventDTO struct {
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	Version uint64 `json:"version"`
+}
+
+// watch はキーの変更を Server-Sent Events で配信します（GET /watch?prefix=）。
+// Last-Event-ID ヘッダ（または ?last_event_id=）があればその次のイベントから再開し、
+// 履歴から再開できない場合は 410 を返します。
+// 配信が追いつかず切断された場合はストリームを終了するため、クライアントは再接続して再開します。
+func (h *watchHandler) watch(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	prefix := r.URL.Query().Get("prefix")
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var ch <-chan store.Event[string, string]
+	if lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			return BadRequest("invalid Last-Event-ID")
+		}
+		ch, err = h.st.WatchFrom(ctx, prefix, id)
+		if errors.Is(err, store.ErrWatchResumeUnavailable) {
+			return NewAppError(http.StatusGone, CodeGone, "cannot resume from the given event id", nil)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		ch = h.st.Watch(ctx, prefix)
+	}
+
+	sse, err := startSSE(w)
+	if err != nil {
+		return nil
+	}
+	ticker := time.NewTicker(heartbeatInterval(h.heartbeat))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			data := watchEventDTO{Key: ev.Key, Value: ev.Value, Version: ev.Version}
+			if err := sse.event(strconv.FormatUint(ev.ID, 10), ev.Type.String(), data); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			if err := sse.ping(); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/store"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// openSSE は SSE エンドポイントに接続し、受信したイベントを流すチャネルを返します。
+func openSSE(t *testing.T, url string, hdr map[string]string) (*http.Response, <-chan sseEvent) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	t.Cleanup(func() { _ = res.Body.Close() })
+
+	ch := make(chan sseEvent, 16)
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(res.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev.event != "" {
+					ch <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return res, ch
+}
+
+func nextSSE(t *testing.T, ch <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatalf("stream closed")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for sse event")
+	}
+	return sseEvent{}
+}
+
+func TestWatch_SSE(t *testing.T) {
+	st := store.New[string, string](store.WithWatchHistory(100))
+	ts := httptest.NewServer(NewRouter(st, nil))
+	// ストリームの切断 (openSSE の Cleanup) 後にサーバーを閉じる
+	t.Cleanup(ts.Close)
+
+	res, ch := openSSE(t, ts.URL+"/watch?prefix=user:", nil)
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	doReq(t, http.MethodPut, ts.URL+"/kvs/other", `{"value":"x"}`, nil)
+	doReq(t, http.MethodPut, ts.URL+"/kvs/user:1", `{"value":"a"}`, nil)
+	doReq(t, http.MethodDelete, ts.URL+"/kvs/user:1", "", nil)
+
+	ev := nextSSE(t, ch)
+	var d watchEventDTO
+	if err := json.Unmarshal([]byte(ev.data), &d); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev.event != "set" || d.Key != "user:1" || d.Value != "a" || d.Version == 0 || ev.id == "" {
+		t.Fatalf("unexpected event %+v %+v", ev, d)
+	}
+	firstID := ev.id
+	if ev = nextSSE(t, ch); ev.event != "delete" {
+		t.Fatalf("expected delete, got %+v", ev)
+	}
+
+	// Last-Event-ID で再開
+	_, resumed := openSSE(t, ts.URL+"/watch?prefix=user:", map[string]string{"Last-Event-ID": firstID})
+	if ev = nextSSE(t, resumed); ev.event != "delete" {
+		t.Fatalf("resume: expected delete, got %+v", ev)
+	}
+
+	res, _ = openSSE(t, ts.URL+"/watch", map[string]string{"Last-Event-ID": "999999"})
+	if res.StatusCode != http.StatusGone {
+		t.Fatalf("expected 410 got %d", res.StatusCode)
+	}
+	res, _ = openSSE(t, ts.URL+"/watch", map[string]string{"Last-Event-ID": "abc"})
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+}
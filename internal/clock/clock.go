@@ -0,0 +1,30 @@
+package clock
+
+import "time"
+
+// Clock は現在時刻とティッカーの取得元です。
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker は time.Ticker の抽象です。
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real は time パッケージをそのまま使う Clock です。
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+
+func (r realTicker) Stop() { r.t.Stop() }
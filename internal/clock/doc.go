@@ -0,0 +1,3 @@
+// Package clock は現在時刻とティッカーを差し替え可能にする抽象を提供します。
+// テストでは clock/fake の Clock を使うと、time.Sleep を使わずに時間を進められます。
+package clock
@@ -0,0 +1,92 @@
+// Package fake はテスト用に手動で時間を進める clock.Clock を提供します。
+package fake
+
+import (
+	"sync"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/clock"
+)
+
+// Clock は Advance を呼ぶまで時間が進まない clock.Clock です。
+// Advance で時刻を進めると、その間に周期を迎えたティッカーも発火します。
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*ticker
+}
+
+var _ clock.Clock = (*Clock)(nil)
+
+// New は start を現在時刻とする Clock を作成します。
+func New(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now は現在時刻を返します。
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker は Advance で発火するティッカーを作成します。
+// time.Ticker と同様にチャネルのバッファは 1 で、受信が追いつかない分の発火は捨てられます。
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("fake: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &ticker{c: c, ch: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance は時刻を d だけ進め、周期を迎えたティッカーを発火させます。
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		// 進めた間に複数回周期を迎えても発火は 1 回（time.Ticker と同じ）
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Tickers は停止していないティッカーの数を返します。
+// ティッカーを作るゴルーチンの起動を待つときに使います。
+func (c *Clock) Tickers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
+
+type ticker struct {
+	c      *Clock
+	ch     chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *ticker) C() <-chan time.Time { return t.ch }
+
+func (t *ticker) Stop() {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	for i, x := range t.c.tickers {
+		if x == t {
+			t.c.tickers = append(t.c.tickers[:i], t.c.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package fake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock_AdvanceFiresTickers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(start)
+	tk := c.NewTicker(time.Second)
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatalf("ticker fired early")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	select {
+	case got := <-tk.C():
+		if !got.Equal(start.Add(time.Second)) {
+			t.Fatalf("unexpected tick time %v", got)
+		}
+	default:
+		t.Fatalf("ticker should fire")
+	}
+
+	// 複数周期を一度に進めても発火は 1 回で、次の周期は揃ったまま
+	c.Advance(3500 * time.Millisecond)
+	<-tk.C()
+	select {
+	case <-tk.C():
+		t.Fatalf("ticker should coalesce missed ticks")
+	default:
+	}
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-tk.C():
+	default:
+		t.Fatalf("ticker should fire at the next period")
+	}
+	if !c.Now().Equal(start.Add(5 * time.Second)) {
+		t.Fatalf("unexpected now %v", c.Now())
+	}
+
+	tk.Stop()
+	if c.Tickers() != 0 {
+		t.Fatalf("stopped ticker should be removed")
+	}
+	c.Advance(time.Hour)
+	select {
+	case <-tk.C():
+		t.Fatalf("stopped ticker fired")
+	default:
+	}
+}
@@ -0,0 +1,2 @@
+// Package glob は Redis 互換のグロブパターンマッチを提供します。
+package glob
@@ -0,0 +1,114 @@
+package glob
+
+// Match は s が pattern に一致するかを返します。
+// パターンは Redis の KEYS/SCAN/PSUBSCRIBE と同じく以下をサポートします。
+//   - *      任意の文字列（'/' を含む）
+//   - ?      任意の 1 文字
+//   - [abc]  文字クラス（[^a] / [a-z] も可）
+//   - \x     x をリテラルとして扱う
+//
+// 不正なパターン（閉じていない '[' など）は一致しないものとして扱います。
+func Match(pattern, s string) bool {
+	p := []rune(pattern)
+	str := []rune(s)
+	// バックトラック位置（直近の '*'）
+	starP, starS := -1, 0
+	pi, si := 0, 0
+	for si < len(str) {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				starP, starS = pi, si
+				pi++
+				continue
+			case '?':
+				pi++
+				si++
+				continue
+			case '[':
+				ok, next, valid := matchClass(p, pi, str[si])
+				if !valid {
+					return false
+				}
+				if ok {
+					pi = next
+					si++
+					continue
+				}
+			case '\\':
+				if pi+1 < len(p) && p[pi+1] == str[si] {
+					pi += 2
+					si++
+					continue
+				}
+			default:
+				if p[pi] == str[si] {
+					pi++
+					si++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starS++
+		pi, si = starP+1, starS
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// matchClass は p[i] == '[' から始まる文字クラスを評価します。
+func matchClass(p []rune, i int, c rune) (matched bool, next int, valid bool) {
+	i++
+	negate := false
+	if i < len(p) && p[i] == '^' {
+		negate = true
+		i++
+	}
+	first := true
+	for i < len(p) && (first || p[i] != ']') {
+		first = false
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			if hi == '\\' && i+3 < len(p) {
+				i++
+				hi = p[i+2]
+			}
+			i += 2
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return false, 0, false
+	}
+	return matched != negate, i + 1, true
+}
+
+// QuoteMeta は s 中のメタ文字をエスケープし、s そのものに一致するパターンを返します。
+func QuoteMeta(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,49 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "users/1", true},
+		{"user:*", "user:42", true},
+		{"user:*", "users:42", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"*:*:end", "a:b:c:end", true},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXbY", false},
+		{"[abc", "a", false},
+		{"日本*", "日本語", true},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.s); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
+
+func TestQuoteMeta(t *testing.T) {
+	for _, s := range []string{"plain", "a*b", "[x]?", `back\slash`} {
+		if !Match(QuoteMeta(s), s) {
+			t.Errorf("QuoteMeta(%q) should match itself", s)
+		}
+		if Match(QuoteMeta(s)+"x", s) {
+			t.Errorf("QuoteMeta(%q)+x should not match", s)
+		}
+	}
+	if Match(QuoteMeta("a*")+"*", "ab") {
+		t.Errorf("escaped star must be literal")
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 // Interface はメトリクス更新用抽象
@@ -13,6 +14,23 @@ type Interface interface {
 	AddEvicted(n int)
 	AddTTLExpired(n int)
 	SetLRUSize(n int)
+	SetStoreBytes(n int64)
+	AddActiveExpireSampled(n int)
+	AddActiveExpireHits(n int)
+	IncActiveExpireOverrun()
+	IncLoad()
+	IncLoadError()
+	ObserveLoadDuration(d time.Duration)
+	SetWriteBehindPending(n int)
+	IncWriteBehindFlushError()
+	SetAOFRewriteInProgress(inProgress bool)
+	ObserveAOFRewriteDuration(d time.Duration)
+	IncAOFRewriteFailed()
+	IncAOFWriteError()
+	IncPubSubPublished()
+	AddPubSubDelivered(n int)
+	AddPubSubDropped(n int)
+	SetPubSubSubscribers(n int)
 }
 
 // Noop は何もしないメトリクス実装
@@ -39,6 +57,57 @@ func (Noop) AddTTLExpired(_ int) {}
 // SetLRUSize は何もしないメトリクス実装
 func (Noop) SetLRUSize(_ int) {}
 
+// SetStoreBytes は何もしないメトリクス実装
+func (Noop) SetStoreBytes(_ int64) {}
+
+// AddActiveExpireSampled は何もしないメトリクス実装
+func (Noop) AddActiveExpireSampled(_ int) {}
+
+// AddActiveExpireHits は何もしないメトリクス実装
+func (Noop) AddActiveExpireHits(_ int) {}
+
+// IncActiveExpireOverrun は何もしないメトリクス実装
+func (Noop) IncActiveExpireOverrun() {}
+
+// IncLoad は何もしないメトリクス実装
+func (Noop) IncLoad() {}
+
+// IncLoadError は何もしないメトリクス実装
+func (Noop) IncLoadError() {}
+
+// ObserveLoadDuration は何もしないメトリクス実装
+func (Noop) ObserveLoadDuration(_ time.Duration) {}
+
+// SetWriteBehindPending は何もしないメトリクス実装
+func (Noop) SetWriteBehindPending(_ int) {}
+
+// IncWriteBehindFlushError は何もしないメトリクス実装
+func (Noop) IncWriteBehindFlushError() {}
+
+// SetAOFRewriteInProgress は何もしないメトリクス実装
+func (Noop) SetAOFRewriteInProgress(_ bool) {}
+
+// ObserveAOFRewriteDuration は何もしないメトリクス実装
+func (Noop) ObserveAOFRewriteDuration(_ time.Duration) {}
+
+// IncAOFRewriteFailed は何もしないメトリクス実装
+func (Noop) IncAOFRewriteFailed() {}
+
+// IncAOFWriteError は何もしないメトリクス実装
+func (Noop) IncAOFWriteError() {}
+
+// IncPubSubPublished は何もしないメトリクス実装
+func (Noop) IncPubSubPublished() {}
+
+// AddPubSubDelivered は何もしないメトリクス実装
+func (Noop) AddPubSubDelivered(_ int) {}
+
+// AddPubSubDropped は何もしないメトリクス実装
+func (Noop) AddPubSubDropped(_ int) {}
+
+// SetPubSubSubscribers は何もしないメトリクス実装
+func (Noop) SetPubSubSubscribers(_ int) {}
+
 // Simple はシンプルなメトリクス実装です。
 type Simple struct {
 	SetNew     atomic.Uint64
@@ -48,6 +117,29 @@ type Simple struct {
 	Evicted    atomic.Uint64
 	TTLExpired atomic.Uint64
 	LRUSize    atomic.Uint64
+	StoreBytes atomic.Int64
+
+	ActiveExpireSampled  atomic.Uint64
+	ActiveExpireHits     atomic.Uint64
+	ActiveExpireOverruns atomic.Uint64
+
+	Loads      atomic.Uint64
+	LoadErrors atomic.Uint64
+	LoadNanos  atomic.Int64 // ローダー呼び出しの所要時間の合計
+
+	WriteBehindPending     atomic.Int64
+	WriteBehindFlushErrors atomic.Uint64
+
+	AOFRewriteInProgress atomic.Bool
+	AOFRewrites          atomic.Uint64
+	AOFRewriteFailed     atomic.Uint64
+	AOFRewriteLastNanos  atomic.Int64
+	AOFWriteErrors       atomic.Uint64
+
+	PubSubPublished   atomic.Uint64
+	PubSubDelivered   atomic.Uint64
+	PubSubDropped     atomic.Uint64
+	PubSubSubscribers atomic.Uint64
 }
 
 // NewSimple は新しい Simple メトリクスを作成します。
@@ -85,3 +177,77 @@ func (m *Simple) SetLRUSize(n int) {
 		m.LRUSize.Store(uint64(n))
 	}
 }
+
+// SetStoreBytes はエントリのコスト合計 (バイト) を設定します。
+func (m *Simple) SetStoreBytes(n int64) { m.StoreBytes.Store(n) }
+
+// AddActiveExpireSampled はアクティブ期限切れ処理でサンプルしたキー数を加算します。
+func (m *Simple) AddActiveExpireSampled(n int) {
+	if n > 0 {
+		m.ActiveExpireSampled.Add(uint64(n))
+	}
+}
+
+// AddActiveExpireHits はサンプルのうち期限切れで削除したキー数を加算します。
+func (m *Simple) AddActiveExpireHits(n int) {
+	if n > 0 {
+		m.ActiveExpireHits.Add(uint64(n))
+	}
+}
+
+// IncActiveExpireOverrun は時間予算を使い切って打ち切ったサイクルをカウントします。
+func (m *Simple) IncActiveExpireOverrun() { m.ActiveExpireOverruns.Add(1) }
+
+// IncLoad はローダーの呼び出しをカウントします。
+func (m *Simple) IncLoad() { m.Loads.Add(1) }
+
+// IncLoadError はエラーを返したローダーの呼び出しをカウントします。
+func (m *Simple) IncLoadError() { m.LoadErrors.Add(1) }
+
+// ObserveLoadDuration はローダーの所要時間を加算します。
+func (m *Simple) ObserveLoadDuration(d time.Duration) { m.LoadNanos.Add(int64(d)) }
+
+// SetWriteBehindPending は Backend へ未反映の書き込み数を設定します。
+func (m *Simple) SetWriteBehindPending(n int) { m.WriteBehindPending.Store(int64(n)) }
+
+// IncWriteBehindFlushError は失敗した write-behind の反映をカウントします。
+func (m *Simple) IncWriteBehindFlushError() { m.WriteBehindFlushErrors.Add(1) }
+
+// SetAOFRewriteInProgress は AOF rewrite の実行中フラグを設定します。
+func (m *Simple) SetAOFRewriteInProgress(inProgress bool) { m.AOFRewriteInProgress.Store(inProgress) }
+
+// ObserveAOFRewriteDuration は完了した AOF rewrite の所要時間を記録します。
+func (m *Simple) ObserveAOFRewriteDuration(d time.Duration) {
+	m.AOFRewrites.Add(1)
+	m.AOFRewriteLastNanos.Store(int64(d))
+}
+
+// IncAOFRewriteFailed は失敗した AOF rewrite をカウントします。
+func (m *Simple) IncAOFRewriteFailed() { m.AOFRewriteFailed.Add(1) }
+
+// IncAOFWriteError は失敗した AOF への追記・fsync をカウントします。
+func (m *Simple) IncAOFWriteError() { m.AOFWriteErrors.Add(1) }
+
+// IncPubSubPublished は Publish されたメッセージをカウントします。
+func (m *Simple) IncPubSubPublished() { m.PubSubPublished.Add(1) }
+
+// AddPubSubDelivered は購読者のキューに入ったメッセージ数を加算します。
+func (m *Simple) AddPubSubDelivered(n int) {
+	if n > 0 {
+		m.PubSubDelivered.Add(uint64(n))
+	}
+}
+
+// AddPubSubDropped はキューが溢れて破棄されたメッセージ数を加算します。
+func (m *Simple) AddPubSubDropped(n int) {
+	if n > 0 {
+		m.PubSubDropped.Add(uint64(n))
+	}
+}
+
+// SetPubSubSubscribers は現在の購読者数を設定します。
+func (m *Simple) SetPubSubSubscribers(n int) {
+	if n >= 0 {
+		m.PubSubSubscribers.Store(uint64(n))
+	}
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -13,6 +15,28 @@ type Prom struct {
 	evicted    prometheus.Counter
 	ttlExpired prometheus.Counter
 	lruSize    prometheus.Gauge
+	storeBytes prometheus.Gauge
+
+	activeExpireSampled  prometheus.Counter
+	activeExpireHits     prometheus.Counter
+	activeExpireOverruns prometheus.Counter
+
+	loads        prometheus.Counter
+	loadErrors   prometheus.Counter
+	loadDuration prometheus.Histogram
+
+	writeBehindPending     prometheus.Gauge
+	writeBehindFlushErrors prometheus.Counter
+
+	aofRewriteInProgress prometheus.Gauge
+	aofRewriteDuration   prometheus.Histogram
+	aofRewriteFailed     prometheus.Counter
+	aofWriteErrors       prometheus.Counter
+
+	pubsubPublished   prometheus.Counter
+	pubsubDelivered   prometheus.Counter
+	pubsubDropped     prometheus.Counter
+	pubsubSubscribers prometheus.Gauge
 }
 
 // NewProm は Prometheus を使ったメトリクス実装を初期化します。
@@ -40,11 +64,48 @@ func NewProm(namespace string) *Prom {
 		evicted:    makeC("evicted_total", "Number of evicted items"),
 		ttlExpired: makeC("ttl_expired_total", "Number of TTL expired items"),
 		lruSize:    makeG("lru_current_size", "Current number of keys tracked by LRU"),
+		storeBytes: makeG("store_bytes", "Estimated bytes of stored entries (sum of entry costs)"),
+
+		activeExpireSampled:  makeC("active_expire_sampled_total", "Number of keys sampled by the active expire cycle"),
+		activeExpireHits:     makeC("active_expire_hits_total", "Number of sampled keys that were expired and removed"),
+		activeExpireOverruns: makeC("active_expire_budget_overruns_total", "Number of active expire cycles stopped by the time budget"),
+
+		loads:      makeC("loads_total", "Number of loader calls for cache misses"),
+		loadErrors: makeC("load_errors_total", "Number of loader calls that returned an error"),
+		loadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "load_duration_seconds",
+			Help:      "Duration of loader calls",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+
+		writeBehindPending:     makeG("write_behind_pending", "Number of keys waiting to be flushed to the backend"),
+		writeBehindFlushErrors: makeC("write_behind_flush_errors_total", "Number of failed write-behind flush attempts"),
+
+		aofRewriteInProgress: makeG("aof_rewrite_in_progress", "1 while an AOF rewrite is running"),
+		aofRewriteDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "aof_rewrite_duration_seconds",
+			Help:      "Duration of completed AOF rewrites",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+		}),
+		aofRewriteFailed: makeC("aof_rewrite_failed_total", "Number of failed AOF rewrites"),
+		aofWriteErrors:   makeC("aof_write_errors_total", "Number of failed AOF appends and fsyncs"),
+
+		pubsubPublished:   makeC("pubsub_published_total", "Number of messages published"),
+		pubsubDelivered:   makeC("pubsub_delivered_total", "Number of messages queued to subscribers"),
+		pubsubDropped:     makeC("pubsub_dropped_total", "Number of messages dropped due to full subscriber queues"),
+		pubsubSubscribers: makeG("pubsub_subscribers", "Current number of pub/sub subscriptions"),
 	}
 
 	// Register (重複登録は無視したいので MustRegister で panic するなら再利用側で 1 回だけ呼ぶ設計)
 	prometheus.MustRegister(
-		p.setNew, p.setUpdate, p.getHit, p.getMiss, p.evicted, p.ttlExpired, p.lruSize,
+		p.setNew, p.setUpdate, p.getHit, p.getMiss, p.evicted, p.ttlExpired, p.lruSize, p.storeBytes,
+		p.activeExpireSampled, p.activeExpireHits, p.activeExpireOverruns,
+		p.loads, p.loadErrors, p.loadDuration,
+		p.writeBehindPending, p.writeBehindFlushErrors,
+		p.aofRewriteInProgress, p.aofRewriteDuration, p.aofRewriteFailed, p.aofWriteErrors,
+		p.pubsubPublished, p.pubsubDelivered, p.pubsubDropped, p.pubsubSubscribers,
 	)
 	return p
 }
@@ -81,3 +142,80 @@ func (p *Prom) SetLRUSize(n int) {
 		p.lruSize.Set(float64(n))
 	}
 }
+
+// SetStoreBytes はエントリのコスト合計 (バイト) を設定します。
+func (p *Prom) SetStoreBytes(n int64) { p.storeBytes.Set(float64(n)) }
+
+// AddActiveExpireSampled はアクティブ期限切れ処理でサンプルしたキー数を加算します。
+func (p *Prom) AddActiveExpireSampled(n int) {
+	if n > 0 {
+		p.activeExpireSampled.Add(float64(n))
+	}
+}
+
+// AddActiveExpireHits はサンプルのうち期限切れで削除したキー数を加算します。
+func (p *Prom) AddActiveExpireHits(n int) {
+	if n > 0 {
+		p.activeExpireHits.Add(float64(n))
+	}
+}
+
+// IncActiveExpireOverrun は時間予算を使い切って打ち切ったサイクルをカウントします。
+func (p *Prom) IncActiveExpireOverrun() { p.activeExpireOverruns.Inc() }
+
+// IncLoad はローダーの呼び出しをカウントします。
+func (p *Prom) IncLoad() { p.loads.Inc() }
+
+// IncLoadError はエラーを返したローダーの呼び出しをカウントします。
+func (p *Prom) IncLoadError() { p.loadErrors.Inc() }
+
+// ObserveLoadDuration はローダーの所要時間を記録します。
+func (p *Prom) ObserveLoadDuration(d time.Duration) { p.loadDuration.Observe(d.Seconds()) }
+
+// SetWriteBehindPending は Backend へ未反映の書き込み数を設定します。
+func (p *Prom) SetWriteBehindPending(n int) { p.writeBehindPending.Set(float64(n)) }
+
+// IncWriteBehindFlushError は失敗した write-behind の反映をカウントします。
+func (p *Prom) IncWriteBehindFlushError() { p.writeBehindFlushErrors.Inc() }
+
+// SetAOFRewriteInProgress は AOF rewrite の実行中フラグを設定します。
+func (p *Prom) SetAOFRewriteInProgress(inProgress bool) {
+	if inProgress {
+		p.aofRewriteInProgress.Set(1)
+	} else {
+		p.aofRewriteInProgress.Set(0)
+	}
+}
+
+// ObserveAOFRewriteDuration は完了した AOF rewrite の所要時間を記録します。
+func (p *Prom) ObserveAOFRewriteDuration(d time.Duration) { p.aofRewriteDuration.Observe(d.Seconds()) }
+
+// IncAOFRewriteFailed は失敗した AOF rewrite をカウントします。
+func (p *Prom) IncAOFRewriteFailed() { p.aofRewriteFailed.Inc() }
+
+// IncAOFWriteError は失敗した AOF への追記・fsync をカウントします。
+func (p *Prom) IncAOFWriteError() { p.aofWriteErrors.Inc() }
+
+// IncPubSubPublished は Publish されたメッセージをカウントします。
+func (p *Prom) IncPubSubPublished() { p.pubsubPublished.Inc() }
+
+// AddPubSubDelivered は購読者のキューに入ったメッセージ数を加算します。
+func (p *Prom) AddPubSubDelivered(n int) {
+	if n > 0 {
+		p.pubsubDelivered.Add(float64(n))
+	}
+}
+
+// AddPubSubDropped はキューが溢れて破棄されたメッセージ数を加算します。
+func (p *Prom) AddPubSubDropped(n int) {
+	if n > 0 {
+		p.pubsubDropped.Add(float64(n))
+	}
+}
+
+// SetPubSubSubscribers は現在の購読者数を設定します。
+func (p *Prom) SetPubSubSubscribers(n int) {
+	if n >= 0 {
+		p.pubsubSubscribers.Set(float64(n))
+	}
+}
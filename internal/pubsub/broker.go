@@ -0,0 +1,297 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/amakane-hakari/kavos/internal/glob"
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+var (
+	// ErrSlowConsumer は Disconnect ポリシーでキューが溢れて購読が終了したことを表します。
+	ErrSlowConsumer = errors.New("pubsub: slow consumer disconnected")
+	// ErrClosed はブローカーのクローズにより購読が終了したことを表します。
+	ErrClosed = errors.New("pubsub: broker closed")
+	// ErrInvalidPolicy は不明なオーバーフローポリシー名です。
+	ErrInvalidPolicy = errors.New("pubsub: invalid overflow policy")
+)
+
+// Message は購読者に届くメッセージです。
+type Message struct {
+	Channel string // Publish されたチャネル
+	Pattern string // パターン購読で一致した場合のパターン（通常購読では空）
+	Payload string
+}
+
+// Broker はチャネルへのメッセージ配信を行います。ゼロ値ではなく New で作成してください。
+type Broker struct {
+	cfg Config
+
+	mu       sync.RWMutex
+	channels map[string]map[*Subscription]struct{}
+	patterns map[*Subscription]struct{}
+	count    int // 購読数
+	closed   bool
+}
+
+// New は新しい Broker を作成します。
+func New(opts ...Option) *Broker {
+	cfg := Config{BufferSize: defaultBufferSize, Metrics: &metrics.Noop{}}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = &metrics.Noop{}
+	}
+	return &Broker{
+		cfg:      cfg,
+		channels: make(map[string]map[*Subscription]struct{}),
+		patterns: make(map[*Subscription]struct{}),
+	}
+}
+
+// SubscribeConfig は購読ごとの設定です。
+type SubscribeConfig struct {
+	Channels   []string       // 完全一致で購読するチャネル
+	Patterns   []string       // グロブパターン（glob.Match）で購読するチャネル
+	BufferSize int            // 0 ならブローカーの既定値
+	Policy     OverflowPolicy // キューが一杯のときの扱い
+}
+
+// Subscription は 1 つの購読を表します。
+type Subscription struct {
+	b        *Broker
+	ch       chan Message
+	channels []string
+	patterns []string
+	policy   OverflowPolicy
+
+	mu      sync.Mutex // DropOldest の取り出し・投入を直列化
+	dropped atomic.Uint64
+	err     atomic.Pointer[error]
+}
+
+// Subscribe は指定チャネルを既定のポリシーで購読します。
+func (b *Broker) Subscribe(channels ...string) *Subscription {
+	return b.SubscribeWith(SubscribeConfig{Channels: channels, Policy: b.cfg.Policy})
+}
+
+// PSubscribe はパターンに一致するチャネルを既定のポリシーで購読します。
+func (b *Broker) PSubscribe(patterns ...string) *Subscription {
+	return b.SubscribeWith(SubscribeConfig{Patterns: patterns, Policy: b.cfg.Policy})
+}
+
+// SubscribeWith は設定を指定して購読します。ブローカーがクローズ済みの場合、
+// 返される Subscription のチャネルは閉じられています。
+func (b *Broker) SubscribeWith(c SubscribeConfig) *Subscription {
+	size := c.BufferSize
+	if size <= 0 {
+		size = b.cfg.BufferSize
+	}
+	sub := &Subscription{
+		b:        b,
+		ch:       make(chan Message, size),
+		channels: append([]string(nil), c.Channels...),
+		patterns: append([]string(nil), c.Patterns...),
+		policy:   c.Policy,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.finish(ErrClosed)
+		return sub
+	}
+	for _, name := range sub.channels {
+		m := b.channels[name]
+		if m == nil {
+			m = make(map[*Subscription]struct{})
+			b.channels[name] = m
+		}
+		m[sub] = struct{}{}
+	}
+	if len(sub.patterns) > 0 {
+		b.patterns[sub] = struct{}{}
+	}
+	b.count++
+	b.cfg.Metrics.SetPubSubSubscribers(b.count)
+	return sub
+}
+
+// Publish はチャネルにメッセージを送り、キューに入った購読数を返します。
+// 購読者を待つことはなく、キューが一杯の購読者には各自のポリシーを適用します。
+func (b *Broker) Publish(channel, payload string) int {
+	var (
+		delivered, dropped int
+		slow               []*Subscription
+	)
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return 0
+	}
+	for sub := range b.channels[channel] {
+		if sub.offer(Message{Channel: channel, Payload: payload}) {
+			delivered++
+		} else if sub.policy == Disconnect {
+			slow = append(slow, sub)
+		} else {
+			dropped++
+		}
+	}
+	for sub := range b.patterns {
+		for _, p := range sub.patterns {
+			if !glob.Match(p, channel) {
+				continue
+			}
+			if sub.offer(Message{Channel: channel, Pattern: p, Payload: payload}) {
+				delivered++
+			} else if sub.policy == Disconnect {
+				slow = append(slow, sub)
+			} else {
+				dropped++
+			}
+			break // 1 購読につき 1 メッセージ
+		}
+	}
+	b.mu.RUnlock()
+
+	// close は送信と排他にする必要があるため、読み取りロックを外してから切断する
+	for _, sub := range slow {
+		b.remove(sub, ErrSlowConsumer)
+	}
+	b.cfg.Metrics.IncPubSubPublished()
+	b.cfg.Metrics.AddPubSubDelivered(delivered)
+	b.cfg.Metrics.AddPubSubDropped(dropped + len(slow))
+	return delivered
+}
+
+// offer はキューに入れられたら true を返します。DropOldest では古いメッセージを捨てて入れます。
+func (s *Subscription) offer(msg Message) bool {
+	select {
+	case s.ch <- msg:
+		return true
+	default:
+	}
+	switch s.policy {
+	case DropOldest:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-s.ch:
+			s.dropped.Add(1)
+		default:
+		}
+		select {
+		case s.ch <- msg:
+			return true
+		default:
+			s.dropped.Add(1)
+			return false
+		}
+	default:
+		s.dropped.Add(1)
+		return false
+	}
+}
+
+// remove は購読を解除してチャネルを閉じます。
+func (b *Broker) remove(sub *Subscription, reason error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.unlinkLocked(sub) {
+		return
+	}
+	sub.finish(reason)
+	b.cfg.Metrics.SetPubSubSubscribers(b.count)
+}
+
+// unlinkLocked は購読を索引から外します。既に外れていれば false を返します。
+func (b *Broker) unlinkLocked(sub *Subscription) bool {
+	linked := false
+	for _, name := range sub.channels {
+		if m, ok := b.channels[name]; ok {
+			if _, ok := m[sub]; ok {
+				linked = true
+				delete(m, sub)
+				if len(m) == 0 {
+					delete(b.channels, name)
+				}
+			}
+		}
+	}
+	if _, ok := b.patterns[sub]; ok {
+		linked = true
+		delete(b.patterns, sub)
+	}
+	if linked {
+		b.count--
+	}
+	return linked
+}
+
+// DefaultPolicy は購読時に指定しない場合の既定のオーバーフローポリシー (WithOverflowPolicy) を返します。
+func (b *Broker) DefaultPolicy() OverflowPolicy {
+	return b.cfg.Policy
+}
+
+// NumSubscriptions は現在の購読数を返します。
+func (b *Broker) NumSubscriptions() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.count
+}
+
+// Close は全購読を終了させ、以降の Publish を無視します。
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	seen := make(map[*Subscription]struct{})
+	for _, m := range b.channels {
+		for sub := range m {
+			seen[sub] = struct{}{}
+		}
+	}
+	for sub := range b.patterns {
+		seen[sub] = struct{}{}
+	}
+	for sub := range seen {
+		b.unlinkLocked(sub)
+		sub.finish(ErrClosed)
+	}
+	b.cfg.Metrics.SetPubSubSubscribers(0)
+}
+
+// C はメッセージを受け取るチャネルを返します。購読の終了時に閉じられます。
+func (s *Subscription) C() <-chan Message { return s.ch }
+
+// Close は購読を解除します。チャネルは閉じられ、Err は nil のままです。
+func (s *Subscription) Close() { s.b.remove(s, nil) }
+
+// Err は購読が終了した理由を返します（Close による終了や購読中は nil）。
+func (s *Subscription) Err() error {
+	if p := s.err.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Dropped はキューが溢れて届かなかったメッセージ数を返します。
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// finish はブローカーの書き込みロック下で呼び出します。
+func (s *Subscription) finish(reason error) {
+	if reason != nil {
+		s.err.Store(&reason)
+	}
+	close(s.ch)
+}
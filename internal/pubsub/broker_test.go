@@ -0,0 +1,180 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func recv(t *testing.T, sub *Subscription) Message {
+	t.Helper()
+	select {
+	case m, ok := <-sub.C():
+		if !ok {
+			t.Fatalf("subscription closed: %v", sub.Err())
+		}
+		return m
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for message")
+	}
+	return Message{}
+}
+
+func drain(sub *Subscription) []string {
+	var out []string
+	for {
+		select {
+		case m, ok := <-sub.C():
+			if !ok {
+				return out
+			}
+			out = append(out, m.Payload)
+		default:
+			return out
+		}
+	}
+}
+
+func TestBroker_PublishSubscribe(t *testing.T) {
+	mx := metrics.NewSimple()
+	b := New(WithMetrics(mx))
+	defer b.Close()
+
+	news := b.Subscribe("news")
+	both := b.Subscribe("news", "sports")
+	pat := b.PSubscribe("news.*", "s*")
+	if n := b.NumSubscriptions(); n != 3 || mx.PubSubSubscribers.Load() != 3 {
+		t.Fatalf("expected 3 subscriptions, got %d", n)
+	}
+
+	if n := b.Publish("news", "hello"); n != 2 {
+		t.Fatalf("news: expected 2 receivers, got %d", n)
+	}
+	if n := b.Publish("sports", "goal"); n != 2 {
+		t.Fatalf("sports: expected 2 receivers, got %d", n)
+	}
+	if n := b.Publish("news.tech", "go"); n != 1 {
+		t.Fatalf("news.tech: expected 1 receiver, got %d", n)
+	}
+	if n := b.Publish("nobody", "x"); n != 0 {
+		t.Fatalf("expected 0 receivers, got %d", n)
+	}
+
+	if m := recv(t, news); m.Channel != "news" || m.Payload != "hello" || m.Pattern != "" {
+		t.Fatalf("unexpected %+v", m)
+	}
+	if got := fmt.Sprint(drain(both)); got != "[hello goal]" {
+		t.Fatalf("both: %s", got)
+	}
+	if m := recv(t, pat); m.Channel != "sports" || m.Pattern != "s*" {
+		t.Fatalf("pattern: unexpected %+v", m)
+	}
+	if m := recv(t, pat); m.Channel != "news.tech" || m.Pattern != "news.*" {
+		t.Fatalf("pattern: unexpected %+v", m)
+	}
+
+	if mx.PubSubPublished.Load() != 4 || mx.PubSubDelivered.Load() != 5 {
+		t.Fatalf("metrics: published=%d delivered=%d", mx.PubSubPublished.Load(), mx.PubSubDelivered.Load())
+	}
+
+	news.Close()
+	if _, ok := <-news.C(); ok {
+		t.Fatalf("expected closed channel")
+	}
+	if news.Err() != nil {
+		t.Fatalf("Close should not set Err, got %v", news.Err())
+	}
+	if n := b.Publish("news", "again"); n != 1 {
+		t.Fatalf("after unsubscribe expected 1 receiver, got %d", n)
+	}
+}
+
+func TestBroker_OverflowPolicies(t *testing.T) {
+	mx := metrics.NewSimple()
+	b := New(WithBufferSize(2), WithMetrics(mx))
+	defer b.Close()
+
+	newest := b.SubscribeWith(SubscribeConfig{Channels: []string{"c"}, Policy: DropNewest})
+	oldest := b.SubscribeWith(SubscribeConfig{Channels: []string{"c"}, Policy: DropOldest})
+	disc := b.SubscribeWith(SubscribeConfig{Channels: []string{"c"}, Policy: Disconnect})
+	big := b.SubscribeWith(SubscribeConfig{Channels: []string{"c"}, BufferSize: 10})
+
+	for i := 1; i <= 4; i++ {
+		b.Publish("c", fmt.Sprint(i))
+	}
+
+	if got := fmt.Sprint(drain(newest)); got != "[1 2]" || newest.Dropped() != 2 {
+		t.Fatalf("drop-newest: %s dropped=%d", got, newest.Dropped())
+	}
+	if got := fmt.Sprint(drain(oldest)); got != "[3 4]" || oldest.Dropped() != 2 {
+		t.Fatalf("drop-oldest: %s dropped=%d", got, oldest.Dropped())
+	}
+	if got := fmt.Sprint(drain(disc)); got != "[1 2]" {
+		t.Fatalf("disconnect: %s", got)
+	}
+	if !errors.Is(disc.Err(), ErrSlowConsumer) {
+		t.Fatalf("expected ErrSlowConsumer, got %v", disc.Err())
+	}
+	if got := fmt.Sprint(drain(big)); got != "[1 2 3 4]" {
+		t.Fatalf("per-subscription buffer: %s", got)
+	}
+	if n := b.NumSubscriptions(); n != 3 {
+		t.Fatalf("slow consumer should be removed, got %d subscriptions", n)
+	}
+	if d := mx.PubSubDropped.Load(); d != 3 {
+		// drop-newest 2 件 + disconnect 1 件（drop-oldest は新しいメッセージは届いている）
+		t.Fatalf("expected 3 dropped, got %d", d)
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := New()
+	sub := b.PSubscribe("*")
+	b.Close()
+	if _, ok := <-sub.C(); ok {
+		t.Fatalf("expected closed channel")
+	}
+	if !errors.Is(sub.Err(), ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", sub.Err())
+	}
+	if n := b.Publish("x", "y"); n != 0 {
+		t.Fatalf("publish after close should deliver nothing, got %d", n)
+	}
+	late := b.Subscribe("x")
+	if _, ok := <-late.C(); ok {
+		t.Fatalf("subscribe after close should return closed subscription")
+	}
+	sub.Close() // 二重解除しても問題ない
+}
+
+func TestBroker_Concurrent(t *testing.T) {
+	b := New(WithBufferSize(8), WithOverflowPolicy(Disconnect))
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				b.Publish(fmt.Sprintf("ch%d", j%3), "m")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sub := b.PSubscribe("ch*")
+				drain(sub)
+				sub.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := b.NumSubscriptions(); n != 0 {
+		t.Fatalf("expected no subscriptions left, got %d", n)
+	}
+}
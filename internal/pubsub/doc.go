@@ -0,0 +1,2 @@
+// Package pubsub はキーと独立したチャネルへの Publish / Subscribe（パターン購読を含む）を提供します。
+package pubsub
@@ -0,0 +1,57 @@
+package pubsub
+
+import "github.com/amakane-hakari/kavos/internal/metrics"
+
+// OverflowPolicy は購読者のキューが一杯のときの扱いです。
+type OverflowPolicy int
+
+const (
+	// DropNewest は新しいメッセージを破棄します（既定）。
+	DropNewest OverflowPolicy = iota
+	// DropOldest はキューの最も古いメッセージを捨てて新しいメッセージを入れます。
+	DropOldest
+	// Disconnect は購読を終了しチャネルを閉じます。Err は ErrSlowConsumer を返します。
+	Disconnect
+)
+
+// ParseOverflowPolicy は文字列 ("drop-newest" / "drop-oldest" / "disconnect") を OverflowPolicy に変換します。
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "drop-newest", "":
+		return DropNewest, nil
+	case "drop-oldest":
+		return DropOldest, nil
+	case "disconnect":
+		return Disconnect, nil
+	default:
+		return 0, ErrInvalidPolicy
+	}
+}
+
+// defaultBufferSize は購読者ごとのキューの既定サイズです。
+const defaultBufferSize = 64
+
+// Config はブローカーの設定を表します。
+type Config struct {
+	BufferSize int               // 購読者ごとのキューサイズ。0 なら 64
+	Policy     OverflowPolicy    // 購読時に指定しない場合の既定ポリシー
+	Metrics    metrics.Interface // nil なら Noop
+}
+
+// Option はブローカーのオプションを設定する関数です。
+type Option func(*Config)
+
+// WithBufferSize は購読者ごとのキューサイズを設定するオプションです。
+func WithBufferSize(n int) Option {
+	return func(c *Config) { c.BufferSize = n }
+}
+
+// WithOverflowPolicy は既定のオーバーフローポリシーを設定するオプションです。
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(c *Config) { c.Policy = p }
+}
+
+// WithMetrics はブローカーのメトリクスを設定するオプションです。
+func WithMetrics(m metrics.Interface) Option {
+	return func(c *Config) { c.Metrics = m }
+}
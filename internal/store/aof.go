@@ -0,0 +1,543 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy は AOF の fsync ポリシーを表します。
+type FsyncPolicy string
+
+const (
+	// FsyncAlways は書き込みごとに fsync します（最も安全・最も遅い）。
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec は 1 秒ごとに fsync します。
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo は fsync を OS に任せます。
+	FsyncNo FsyncPolicy = "no"
+)
+
+// ErrAOF は AOF への追記または fsync に失敗した場合のエラーです。元のエラーも errors.Is / errors.As で参照できます。
+// 書き込み系の操作がこのエラーを返すのは FsyncAlways の場合だけです。ストアの変更は取り消さないため、
+// メモリ上には反映済みですが再起動後には失われることがあります。
+var ErrAOF = errors.New("store: aof write failed")
+
+// ParseFsyncPolicy は文字列から FsyncPolicy を解析します。
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch p := FsyncPolicy(s); p {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+		return p, nil
+	default:
+		return "", fmt.Errorf("store: unknown fsync policy %q", s)
+	}
+}
+
+/*
+AOF ファイルフォーマット
+
+	header : magic "KAVOSAOF" (8) | format version (1)
+	record : payload 長 (uint32 BE) | CRC32-IEEE(payload) (uint32 BE) | payload
+	payload: op (1) | expireAt (int64 BE, UnixNano, 0=無期限) | version (uint64 BE) | key 長 (uvarint) | key | value 長 (uvarint) | value
+
+op=multi (トランザクション) は value に複数の record を連結して格納し、1 つの CRC で全体を保護します。
+op=expire (Expire / Persist / Touch) は既存のキーの expireAt だけを変更します (value は空)。
+
+expireAt は絶対時刻で記録するため、再起動を跨いでも期限が維持されます。
+*/
+const (
+	aofMagic            = "KAVOSAOF"
+	aofFormatVersion    = 2
+	aofHeaderSize       = len(aofMagic) + 1
+	aofRecordHeaderSize = 8
+	aofMaxRecordSize    = 64 << 20
+)
+
+type aofOp byte
+
+const (
+	aofOpSet    aofOp = 1
+	aofOpDel    aofOp = 2
+	aofOpMulti  aofOp = 3
+	aofOpExpire aofOp = 4
+)
+
+type aofRecord struct {
+	op       aofOp
+	expireAt int64
+	version  uint64
+	key      []byte
+	val      []byte
+}
+
+var errAOFCorrupt = errors.New("store: corrupt aof record")
+
+func appendAOFRecord(dst []byte, rec aofRecord) []byte {
+	start := len(dst)
+	dst = append(dst, make([]byte, aofRecordHeaderSize)...)
+	dst = append(dst, byte(rec.op))
+	dst = binary.BigEndian.AppendUint64(dst, uint64(rec.expireAt))
+	dst = binary.BigEndian.AppendUint64(dst, rec.version)
+	dst = binary.AppendUvarint(dst, uint64(len(rec.key)))
+	dst = append(dst, rec.key...)
+	dst = binary.AppendUvarint(dst, uint64(len(rec.val)))
+	dst = append(dst, rec.val...)
+	payload := dst[start+aofRecordHeaderSize:]
+	binary.BigEndian.PutUint32(dst[start:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(dst[start+4:], crc32.ChecksumIEEE(payload))
+	return dst
+}
+
+func decodeAOFPayload(p []byte) (aofRecord, error) {
+	var rec aofRecord
+	if len(p) < 17 {
+		return rec, errAOFCorrupt
+	}
+	rec.op = aofOp(p[0])
+	if rec.op != aofOpSet && rec.op != aofOpDel && rec.op != aofOpMulti && rec.op != aofOpExpire {
+		return rec, errAOFCorrupt
+	}
+	rec.expireAt = int64(binary.BigEndian.Uint64(p[1:9]))
+	rec.version = binary.BigEndian.Uint64(p[9:17])
+	p = p[17:]
+	var ok bool
+	if rec.key, p, ok = readUvarintBytes(p); !ok {
+		return rec, errAOFCorrupt
+	}
+	if rec.val, p, ok = readUvarintBytes(p); !ok || len(p) != 0 {
+		return rec, errAOFCorrupt
+	}
+	return rec, nil
+}
+
+func readUvarintBytes(p []byte) (b, rest []byte, ok bool) {
+	n, sz := binary.Uvarint(p)
+	if sz <= 0 || n > uint64(len(p)-sz) {
+		return nil, nil, false
+	}
+	end := sz + int(n)
+	return p[sz:end], p[end:], true
+}
+
+// readAOFRecord は 1 レコードを読み込みます。
+// 正常終端では io.EOF、途中切れ/破損では errAOFCorrupt を返します。
+func readAOFRecord(r io.Reader, hdr []byte) (aofRecord, int64, error) {
+	if _, err := io.ReadFull(r, hdr[:aofRecordHeaderSize]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return aofRecord{}, 0, io.EOF
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return aofRecord{}, 0, errAOFCorrupt
+		}
+		return aofRecord{}, 0, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	sum := binary.BigEndian.Uint32(hdr[4:8])
+	if n > aofMaxRecordSize {
+		return aofRecord{}, 0, errAOFCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return aofRecord{}, 0, errAOFCorrupt
+		}
+		return aofRecord{}, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return aofRecord{}, 0, errAOFCorrupt
+	}
+	rec, err := decodeAOFPayload(payload)
+	if err != nil {
+		return aofRecord{}, 0, err
+	}
+	return rec, int64(aofRecordHeaderSize) + int64(n), nil
+}
+
+// replayAOF はファイル先頭からレコードを読み apply に渡します。
+// 戻り値 good は最後に正しく読めたレコードの終端オフセットです。
+// 末尾が途中切れ/破損していた場合は tailErr にその理由を返します（致命的ではない）。
+func replayAOF(r io.Reader, apply func(aofRecord) error) (good int64, tailErr, err error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, aofHeaderSize)
+	n, err := io.ReadFull(br, hdr)
+	switch {
+	case errors.Is(err, io.EOF):
+		return 0, nil, nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return 0, errAOFCorrupt, nil
+	case err != nil:
+		return 0, nil, err
+	}
+	if string(hdr[:len(aofMagic)]) != aofMagic {
+		return 0, nil, errors.New("store: not an aof file")
+	}
+	if v := hdr[len(aofMagic)]; v != aofFormatVersion {
+		return 0, nil, fmt.Errorf("store: unsupported aof version %d", v)
+	}
+	good = int64(n)
+	recHdr := make([]byte, aofRecordHeaderSize)
+	for {
+		rec, sz, err := readAOFRecord(br, recHdr)
+		if errors.Is(err, io.EOF) {
+			return good, nil, nil
+		}
+		if errors.Is(err, errAOFCorrupt) {
+			return good, err, nil
+		}
+		if err != nil {
+			return good, nil, err
+		}
+		if err := apply(rec); err != nil {
+			return good, nil, err
+		}
+		good += sz
+	}
+}
+
+// aofLog は追記専用ログファイルを表します。
+// aofFile は aofLog が追記先として使うファイルの操作です（*os.File が満たします）。
+type aofFile interface {
+	io.WriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+type aofLog struct {
+	mu     sync.Mutex
+	path   string
+	f      aofFile
+	policy FsyncPolicy
+	buf    []byte
+	size   int64
+	dirty  bool  // fsync されていない書き込みがある
+	broken error // 途中まで書かれたレコードを取り除けなかった場合のエラー（以降の追記はすべて失敗させる）
+
+	// rewrite
+	rewriting     bool
+	rewriteBuf    []byte // rewrite 中に追記されたレコード（完了時に新ファイルへ追記）
+	baseSize      int64  // 起動時 / 直近の rewrite 完了時のサイズ
+	rewritePct    int    // baseSize からの増加率 (%) で自動 rewrite。0 で無効
+	rewriteMinLen int64  // 自動 rewrite を行う最小サイズ
+}
+
+// append はレコードを追記します。needRewrite は自動 rewrite の閾値を超えたことを表します。
+func (a *aofLog) append(rec aofRecord) (needRewrite bool, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.broken != nil {
+		return false, a.broken
+	}
+	a.buf = appendAOFRecord(a.buf[:0], rec)
+	if n, err := a.f.Write(a.buf); err != nil {
+		if n > 0 {
+			a.discardPartial()
+		}
+		return false, err
+	}
+	a.size += int64(len(a.buf))
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, a.buf...)
+	}
+	if a.policy == FsyncAlways {
+		err = a.f.Sync()
+	} else {
+		a.dirty = true
+	}
+	return a.shouldRewrite(), err
+}
+
+// discardPartial は書き込みに失敗したレコードの断片を a.size まで切り詰めて取り除きます。
+// 断片が残ると再生時にそこで打ち切られ、後続のレコードがすべて失われるためです。
+// 取り除けなかった場合は以降の追記を失敗させます。
+func (a *aofLog) discardPartial() {
+	if err := a.f.Truncate(a.size); err != nil {
+		a.broken = fmt.Errorf("store: aof has a torn record at offset %d: %w", a.size, err)
+		return
+	}
+	if _, err := a.f.Seek(a.size, io.SeekStart); err != nil {
+		a.broken = fmt.Errorf("store: aof has a torn record at offset %d: %w", a.size, err)
+	}
+}
+
+func (a *aofLog) shouldRewrite() bool {
+	if a.rewriting || a.rewritePct <= 0 || a.size < a.rewriteMinLen {
+		return false
+	}
+	return a.size >= a.baseSize+a.baseSize*int64(a.rewritePct)/100
+}
+
+func (a *aofLog) sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.dirty {
+		return nil
+	}
+	a.dirty = false
+	return a.f.Sync()
+}
+
+func (a *aofLog) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.f.Sync()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// openAOF は AOF ファイルを開いて内容をストアへ再生し、追記可能な状態にします。
+func (s *Store[K, V]) openAOF(path string, policy FsyncPolicy) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	now := s.now().UnixNano()
+	good, tailErr, err := replayAOF(f, func(rec aofRecord) error {
+		return s.applyAOFRecord(rec)
+	})
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("store: replay aof %s: %w", path, err)
+	}
+	s.dropReplayedExpired(now)
+	if tailErr != nil {
+		// 途中切れ/破損した末尾は切り捨てて起動を継続する
+		if err := f.Truncate(good); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if s.cfg.Logger != nil {
+			s.cfg.Logger.Error("store.aof.truncated", "path", path, "offset", good, "err", tailErr)
+		}
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if good == 0 {
+		hdr := append([]byte(aofMagic), aofFormatVersion)
+		if _, err := f.Write(hdr); err != nil {
+			_ = f.Close()
+			return err
+		}
+		good = int64(len(hdr))
+	}
+	if policy == "" {
+		policy = FsyncEverySec
+	}
+	s.aof = &aofLog{
+		path:          path,
+		f:             f,
+		policy:        policy,
+		size:          good,
+		baseSize:      good,
+		rewritePct:    s.cfg.AOFRewritePercent,
+		rewriteMinLen: s.cfg.AOFRewriteMinSize,
+	}
+	return nil
+}
+
+// applyAOFRecord はレコードを 1 件ストアに反映します。
+// 期限切れのキーも後続の expire レコードで期限が延びることがあるため、ここでは残して再生後に dropReplayedExpired で取り除きます。
+func (s *Store[K, V]) applyAOFRecord(rec aofRecord) error {
+	if rec.op == aofOpMulti {
+		r := bytes.NewReader(rec.val)
+		hdr := make([]byte, aofRecordHeaderSize)
+		for {
+			inner, _, err := readAOFRecord(r, hdr)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.applyAOFRecord(inner); err != nil {
+				return err
+			}
+		}
+	}
+	key, err := unmarshalValue[K](rec.key)
+	if err != nil {
+		return err
+	}
+	_, mp := s.getShard(key)
+	cur, existed := mp[key]
+	if rec.op == aofOpExpire && !existed {
+		return nil
+	}
+	if rec.op == aofOpDel {
+		delete(mp, key)
+		s.indexRemove(key)
+		s.addBytes(-cur.cost)
+		return nil
+	}
+	if rec.op == aofOpExpire {
+		cur.deadline, cur.ttl, cur.sliding = rec.expireAt, 0, false
+		cur.expireAt = s.accessExpireAt(cur, s.now().UnixNano())
+		mp[key] = cur
+		s.expireAdd(key, cur.expireAt)
+		return nil
+	}
+	val, err := unmarshalValue[V](rec.val)
+	if err != nil {
+		return err
+	}
+	// 記録した期限は絶対期限として復元する。WithMaxIdle のアイドル時間は復元した時点から数える
+	e := entry[V]{val: val, deadline: rec.expireAt, ver: rec.version, cost: s.costOf(key, val)}
+	e.expireAt = s.accessExpireAt(e, s.now().UnixNano())
+	mp[key] = e
+	s.addBytes(e.cost - cur.cost)
+	s.expireAdd(key, e.expireAt)
+	s.indexAdd(key)
+	s.observeVersion(rec.version)
+	return nil
+}
+
+func (s *Store[K, V]) aofSyncLoop() {
+	defer s.wg.Done()
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// 定期 fsync の失敗は呼び出し元がいないため、aofError による記録（AOFErr / メトリクス / ログ）のみとする
+			_ = s.aofError(s.aof.sync())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// dropReplayedExpired は再生後に期限切れのキーを取り除きます（削除レコードは書かず、OnRemove も呼びません）。
+func (s *Store[K, V]) dropReplayedExpired(now int64) {
+	for i := 0; i < s.shardCount(); i++ {
+		sh := s.shardAt(i)
+		for k, e := range sh.m {
+			if e.expired(now) {
+				delete(sh.m, k)
+				s.indexRemove(k)
+				s.addBytes(-e.cost)
+			}
+		}
+	}
+}
+
+// aofSet は set レコードを追記します。
+// レコード順序をシャードの状態と一致させるため、シャードロック保持中に呼び出します。
+// FsyncAlways で失敗した場合だけ ErrAOF を返します (aofAppend を参照)。
+func (s *Store[K, V]) aofSet(key K, value V, expireAt int64, version uint64) error {
+	if s.aof == nil {
+		return nil
+	}
+	rec, err := s.aofSetRecord(key, value, expireAt, version)
+	if err != nil {
+		return s.aofError(err)
+	}
+	return s.aofAppend(rec)
+}
+
+// aofDelete は delete レコードを追記します（明示削除・エビクション・TTL 失効共通）。
+func (s *Store[K, V]) aofDelete(key K) error {
+	if s.aof == nil {
+		return nil
+	}
+	rec, err := s.aofDeleteRecord(key)
+	if err != nil {
+		return s.aofError(err)
+	}
+	return s.aofAppend(rec)
+}
+
+// aofExpire は expire レコードを追記します（Expire / Persist / Touch による期限の変更）。
+func (s *Store[K, V]) aofExpire(key K, expireAt int64) error {
+	if s.aof == nil {
+		return nil
+	}
+	kb, err := marshalValue(key)
+	if err != nil {
+		return s.aofError(err)
+	}
+	return s.aofAppend(aofRecord{op: aofOpExpire, expireAt: expireAt, key: kb})
+}
+
+// aofMulti は複数レコードを 1 レコードとして追記します（途中までの反映を防ぐ）。
+func (s *Store[K, V]) aofMulti(recs []aofRecord) error {
+	if s.aof == nil || len(recs) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, rec := range recs {
+		buf = appendAOFRecord(buf, rec)
+	}
+	return s.aofAppend(aofRecord{op: aofOpMulti, val: buf})
+}
+
+// aofSetRecord は set レコードを作ります。エラーは aofError を通して扱ってください。
+func (s *Store[K, V]) aofSetRecord(key K, value V, expireAt int64, version uint64) (aofRecord, error) {
+	kb, err := marshalValue(key)
+	if err != nil {
+		return aofRecord{}, err
+	}
+	vb, err := marshalValue(value)
+	if err != nil {
+		return aofRecord{}, err
+	}
+	return aofRecord{op: aofOpSet, expireAt: expireAt, version: version, key: kb, val: vb}, nil
+}
+
+// aofDeleteRecord は delete レコードを作ります。エラーは aofError を通して扱ってください。
+func (s *Store[K, V]) aofDeleteRecord(key K) (aofRecord, error) {
+	kb, err := marshalValue(key)
+	if err != nil {
+		return aofRecord{}, err
+	}
+	return aofRecord{op: aofOpDel, key: kb}, nil
+}
+
+// aofAppend はレコードを追記し、失敗を aofError に渡します。
+func (s *Store[K, V]) aofAppend(rec aofRecord) error {
+	needRewrite, err := s.aof.append(rec)
+	if needRewrite {
+		s.triggerAOFRewrite()
+	}
+	return s.aofError(err)
+}
+
+// aofError は AOF への追記・fsync の失敗をログとメトリクスに記録し、AOFErr で参照できるよう保持します。
+// FsyncAlways の場合だけ ErrAOF でラップして返し、呼び出し側は書き込み系の操作の戻り値にします
+// （TTL 失効や Evict など、呼び出し元に返せない書き込みでは無視します）。
+// それ以外のポリシーでは永続化はベストエフォートで、nil を返します。
+func (s *Store[K, V]) aofError(err error) error {
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%w: %w", ErrAOF, err)
+	s.aofErr.Store(&err)
+	s.cfg.Metrics.IncAOFWriteError()
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Error("store.aof.write", "err", err)
+	}
+	if s.aof.policy != FsyncAlways {
+		return nil
+	}
+	return err
+}
+
+// AOFErr は最後に失敗した AOF への追記・fsync のエラー (ErrAOF) を返します。失敗していなければ nil です。
+// 一度失敗すると、その後の書き込みが成功しても nil には戻りません。FsyncEverySec / FsyncNo では
+// 書き込み系の操作はこのエラーを返さないので、永続化の失敗を検知するにはこれかメトリクスを確認してください。
+func (s *Store[K, V]) AOFErr() error {
+	if p := s.aofErr.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
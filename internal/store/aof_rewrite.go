@@ -0,0 +1,187 @@
+package store
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrAOFDisabled は AOF が無効な場合のエラーです。
+	ErrAOFDisabled = errors.New("store: aof is disabled")
+	// ErrAOFRewriteInProgress は rewrite が既に実行中の場合のエラーです。
+	ErrAOFRewriteInProgress = errors.New("store: aof rewrite already in progress")
+)
+
+// StartAOFRewrite はバックグラウンドで AOF の rewrite を開始します。
+func (s *Store[K, V]) StartAOFRewrite() error {
+	if s.aof == nil {
+		return ErrAOFDisabled
+	}
+	s.aof.mu.Lock()
+	running := s.aof.rewriting
+	s.aof.mu.Unlock()
+	if running {
+		return ErrAOFRewriteInProgress
+	}
+	select {
+	case s.aofRewriteCh <- struct{}{}:
+		return nil
+	default:
+		return ErrAOFRewriteInProgress
+	}
+}
+
+func (s *Store[K, V]) triggerAOFRewrite() {
+	select {
+	case s.aofRewriteCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Store[K, V]) aofRewriteLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.aofRewriteCh:
+			if err := s.RewriteAOF(); err != nil && !errors.Is(err, ErrAOFRewriteInProgress) && s.cfg.Logger != nil {
+				s.cfg.Logger.Error("store.aof.rewrite", "err", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// RewriteAOF は現在のストア内容から最小の AOF（生存キー 1 件につき 1 レコード）を作成し、
+// 既存ファイルとアトミックに置き換えます。完了するまでブロックします。
+// rewrite 中の書き込みは通常通り既存ファイルに追記されつつバッファにも蓄積され、
+// 置き換え直前に新ファイルへ追記されます。
+func (s *Store[K, V]) RewriteAOF() error {
+	if s.aof == nil {
+		return ErrAOFDisabled
+	}
+	a := s.aof
+	a.mu.Lock()
+	if a.rewriting {
+		a.mu.Unlock()
+		return ErrAOFRewriteInProgress
+	}
+	a.rewriting = true
+	a.rewriteBuf = nil
+	a.mu.Unlock()
+
+	start := time.Now()
+	s.cfg.Metrics.SetAOFRewriteInProgress(true)
+	defer s.cfg.Metrics.SetAOFRewriteInProgress(false)
+
+	n, err := s.rewriteAOF(a)
+	if err != nil {
+		a.mu.Lock()
+		a.rewriting = false
+		a.rewriteBuf = nil
+		a.mu.Unlock()
+		s.cfg.Metrics.IncAOFRewriteFailed()
+		return err
+	}
+	dur := time.Since(start)
+	s.cfg.Metrics.ObserveAOFRewriteDuration(dur)
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Info("store.aof.rewrite.done", "keys", n, "duration_ms", dur.Milliseconds())
+	}
+	return nil
+}
+
+func (s *Store[K, V]) rewriteAOF(a *aofLog) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".rewrite-*")
+	if err != nil {
+		return 0, err
+	}
+	swapped := false
+	defer func() {
+		if !swapped {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	// CreateTemp は 0600 で作成するため、差し替え後も元の AOF の権限を保つ
+	if fi, err := os.Stat(a.path); err == nil {
+		if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+			return 0, err
+		}
+	}
+
+	bw := bufio.NewWriter(tmp)
+	if _, err := bw.Write(append([]byte(aofMagic), aofFormatVersion)); err != nil {
+		return 0, err
+	}
+	total := 0
+	var buf []byte
+	for i := 0; i < s.shardCount(); i++ {
+		sh := s.shardAt(i)
+		now := s.now().UnixNano()
+		buf = buf[:0]
+		var encErr error
+		sh.mu.RLock()
+		for k, e := range sh.m {
+			if e.expireAt > 0 && e.expireAt <= now {
+				continue
+			}
+			var kb, vb []byte
+			if kb, encErr = marshalValue(k); encErr != nil {
+				break
+			}
+			if vb, encErr = marshalValue(e.val); encErr != nil {
+				break
+			}
+			buf = appendAOFRecord(buf, aofRecord{op: aofOpSet, expireAt: e.persistedExpireAt(), version: e.ver, key: kb, val: vb})
+			total++
+		}
+		sh.mu.RUnlock()
+		if encErr != nil {
+			return 0, encErr
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return 0, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, err
+	}
+
+	// rewrite 中に溜まった差分を追記し、ファイルを差し替える
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := tmp.Write(a.rewriteBuf); err != nil {
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, err
+	}
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return 0, err
+	}
+	swapped = true
+	if err := syncDir(filepath.Dir(a.path)); err != nil && s.cfg.Logger != nil {
+		s.cfg.Logger.Error("store.aof.rewrite.syncdir", "err", err)
+	}
+	_ = a.f.Close()
+	a.f = tmp
+	a.size = size
+	a.baseSize = size
+	a.dirty = false
+	a.broken = nil
+	a.rewriting = false
+	a.rewriteBuf = nil
+	return total, nil
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrBackend は write-through で Backend への書き込みに失敗した場合のエラーです。
+// 元のエラーも errors.Is / errors.As で参照できます。
+var ErrBackend = errors.New("store: backend write failed")
+
+// Backend は Store の背後にある永続ストア (system of record) です。
+// WithWriteThrough / WithWriteBehind で設定すると、Set や Delete などの書き込みが Backend に反映されます。
+// Evict や TTL による削除はキャッシュからの削除なので Backend には反映しません。
+type Backend[K comparable, V any] interface {
+	// Load はキーの値を読み込みます。存在しない場合は ErrNotFound を返します。
+	Load(ctx context.Context, key K) (V, error)
+	// Store はキーの値を書き込みます。
+	Store(ctx context.Context, key K, value V) error
+	// Delete はキーを削除します。存在しないキーの削除はエラーにしません。
+	Delete(ctx context.Context, key K) error
+	// BatchStore は複数の書き込み (削除を含む) をまとめて反映します。
+	BatchStore(ctx context.Context, writes []BackendWrite[K, V]) error
+}
+
+// BackendWrite は Backend への 1 件の書き込みです。Delete が true なら削除を表します。
+type BackendWrite[K comparable, V any] struct {
+	Key    K
+	Value  V
+	Delete bool
+}
+
+// WriteMode は Backend への書き込み方式を表します。
+type WriteMode string
+
+const (
+	// WriteThrough は書き込みのたびに同期的に Backend へ反映します。
+	// Backend が失敗した場合は書き込み自体を失敗させ (ErrBackend)、ストアも変更しません。
+	WriteThrough WriteMode = "write-through"
+	// WriteBehind は書き込みをキューに入れ、バックグラウンドでまとめて Backend へ反映します。
+	// 同じキーへの連続した書き込みは最後の 1 件にまとめられます。
+	WriteBehind WriteMode = "write-behind"
+)
+
+// ParseWriteMode は文字列から WriteMode を解析します。
+func ParseWriteMode(s string) (WriteMode, error) {
+	switch m := WriteMode(s); m {
+	case WriteThrough, WriteBehind:
+		return m, nil
+	default:
+		return "", fmt.Errorf("store: unknown write mode %q", s)
+	}
+}
+
+const (
+	defaultWriteBehindInterval = time.Second
+	defaultWriteBehindBatch    = 256
+	defaultWriteBehindRetries  = 3
+	defaultWriteBehindBackoff  = 100 * time.Millisecond
+)
+
+// backendFunc は Config.Backend を Store のキー・値の型に合わせて取り出します。
+func backendFunc[K comparable, V any](cfg Config) (Backend[K, V], error) {
+	if cfg.Backend == nil {
+		return nil, nil
+	}
+	b, ok := cfg.Backend.(Backend[K, V])
+	if !ok {
+		var k K
+		var v V
+		return nil, fmt.Errorf("store: backend %T does not match Store[%T, %T]", cfg.Backend, k, v)
+	}
+	return b, nil
+}
+
+// backendLoader は Backend の Load をローダーとして使います（TTL なしでセット）。
+func backendLoader[K comparable, V any](b Backend[K, V]) LoaderFunc[K, V] {
+	return func(ctx context.Context, key K) (V, time.Duration, error) {
+		v, err := b.Load(ctx, key)
+		return v, 0, err
+	}
+}
+
+// keyLockStripes は write-through で同じキーへの書き込みを直列化するストライプロックの数です。
+const keyLockStripes = 256
+
+// lockKeys は write-through のとき keys のストライプロックを取り、解放する関数を返します。
+// Backend への書き込み中はシャードロックを外すため、その間に同じキーへの別の書き込みが割り込まないようにします
+// （ロック順序: キー → シャード）。write-through でなければ何もしません。
+// OnRemove や Evictor を呼ぶ前に解放してください。
+func (s *Store[K, V]) lockKeys(keys ...K) (unlock func()) {
+	if s.keyLocks == nil {
+		return func() {}
+	}
+	if len(keys) == 1 {
+		m := &s.keyLocks[s.hashKey(keys[0])%keyLockStripes]
+		m.Lock()
+		return m.Unlock
+	}
+	idx := make([]uint32, 0, len(keys))
+	for _, k := range keys {
+		idx = append(idx, s.hashKey(k)%keyLockStripes)
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	for _, i := range idx {
+		s.keyLocks[i].Lock()
+	}
+	return func() {
+		for _, i := range idx {
+			s.keyLocks[i].Unlock()
+		}
+	}
+}
+
+// backendWrite は 1 件の変更を Backend に反映します。シャードロック mu の下で、ストアを変更する前に呼びます。
+// write-through では Backend への書き込み中だけ mu を外し（呼び出し側は lockKeys でキーのロックを持っておく）、
+// 戻る前に取り直します。その間にエントリが TTL や Evict で消えることがあるので、呼び出し側はマップを読み直してから変更を適用します。
+// Backend の失敗は ErrBackend として返し、呼び出し側は変更を適用しません。
+// write-behind では mu を持ったままキューに入れるだけでエラーを返しません。
+func (s *Store[K, V]) backendWrite(mu *sync.RWMutex, w BackendWrite[K, V]) error {
+	switch {
+	case s.backend == nil:
+		return nil
+	case s.writeBehind != nil:
+		s.cfg.Metrics.SetWriteBehindPending(s.writeBehind.enqueue(w))
+		return nil
+	}
+	mu.Unlock()
+	defer mu.Lock()
+	var err error
+	if w.Delete {
+		err = s.backend.Delete(context.Background(), w.Key)
+	} else {
+		err = s.backend.Store(context.Background(), w.Key, w.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBackend, err)
+	}
+	return nil
+}
+
+// backendWriteBatch は Txn や MSet / MDelete の変更をまとめて Backend に反映します。
+// backendWrite と同じく、write-through では書き込み中だけ mus のシャードロックを外して同じ順に取り直します。
+func (s *Store[K, V]) backendWriteBatch(mus []*sync.RWMutex, writes []BackendWrite[K, V]) error {
+	switch {
+	case s.backend == nil || len(writes) == 0:
+		return nil
+	case s.writeBehind != nil:
+		n := 0
+		for _, w := range writes {
+			n = s.writeBehind.enqueue(w)
+		}
+		s.cfg.Metrics.SetWriteBehindPending(n)
+		return nil
+	}
+	for _, mu := range mus {
+		mu.Unlock()
+	}
+	defer func() {
+		for _, mu := range mus {
+			mu.Lock()
+		}
+	}()
+	if err := s.backend.BatchStore(context.Background(), writes); err != nil {
+		return fmt.Errorf("%w: %w", ErrBackend, err)
+	}
+	return nil
+}
+
+// writeBehind は Backend へ未反映の書き込みのキューです。
+// キーごとに最新の書き込みだけを保持し（合体）、最初にキューに入った順に反映します。
+type writeBehind[K comparable, V any] struct {
+	mu      sync.Mutex
+	pending map[K]BackendWrite[K, V]
+	order   []K
+	kick    chan struct{} // バッチサイズに達したら flushLoop を起こす
+	batch   int
+
+	flushMu sync.Mutex // flush を直列化する（同じキーの新しい書き込みが古いものより先に反映されないように）
+}
+
+func newWriteBehind[K comparable, V any](batch int) *writeBehind[K, V] {
+	return &writeBehind[K, V]{
+		pending: make(map[K]BackendWrite[K, V]),
+		kick:    make(chan struct{}, 1),
+		batch:   batch,
+	}
+}
+
+// enqueue は書き込みをキューに入れ、キューの長さを返します。
+func (q *writeBehind[K, V]) enqueue(w BackendWrite[K, V]) int {
+	q.mu.Lock()
+	if _, ok := q.pending[w.Key]; !ok {
+		q.order = append(q.order, w.Key)
+	}
+	q.pending[w.Key] = w
+	n := len(q.pending)
+	q.mu.Unlock()
+	if n >= q.batch {
+		select {
+		case q.kick <- struct{}{}:
+		default:
+		}
+	}
+	return n
+}
+
+// take はキューの先頭から最大 n 件を取り出します。
+func (q *writeBehind[K, V]) take(n int) []BackendWrite[K, V] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n = min(n, len(q.order))
+	writes := make([]BackendWrite[K, V], 0, n)
+	for _, k := range q.order[:n] {
+		writes = append(writes, q.pending[k])
+		delete(q.pending, k)
+	}
+	q.order = q.order[n:]
+	return writes
+}
+
+// requeue は反映に失敗した書き込みをキューの先頭に戻します。
+// 失敗中に同じキーへ新しい書き込みがあった場合はそちらを残します。
+func (q *writeBehind[K, V]) requeue(writes []BackendWrite[K, V]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	back := make([]K, 0, len(writes)+len(q.order))
+	for _, w := range writes {
+		if _, ok := q.pending[w.Key]; ok {
+			continue
+		}
+		q.pending[w.Key] = w
+		back = append(back, w.Key)
+	}
+	q.order = append(back, q.order...)
+}
+
+func (q *writeBehind[K, V]) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// PendingWrites は write-behind で Backend へ未反映の書き込み数（キー数）を返します。
+func (s *Store[K, V]) PendingWrites() int {
+	if s.writeBehind == nil {
+		return 0
+	}
+	return s.writeBehind.len()
+}
+
+// Flush は write-behind のキューが空になるまで Backend へ反映します。
+// 再試行しても失敗したバッチはキューに戻し、そのエラーを返します。write-behind でなければ何もしません。
+func (s *Store[K, V]) Flush(ctx context.Context) error {
+	q := s.writeBehind
+	if q == nil {
+		return nil
+	}
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+	defer func() { s.cfg.Metrics.SetWriteBehindPending(q.len()) }()
+	for {
+		writes := q.take(q.batch)
+		if len(writes) == 0 {
+			return nil
+		}
+		if err := s.flushBatch(ctx, writes); err != nil {
+			q.requeue(writes)
+			return err
+		}
+	}
+}
+
+// flushBatch は 1 バッチを BatchStore で反映し、失敗したら指数バックオフで再試行します。
+// バックオフは実時間で待ちます（Clock の影響を受けません）。
+func (s *Store[K, V]) flushBatch(ctx context.Context, writes []BackendWrite[K, V]) error {
+	retries := s.cfg.WriteBehindRetries
+	if retries <= 0 {
+		retries = defaultWriteBehindRetries
+	}
+	backoff := s.cfg.WriteBehindBackoff
+	if backoff <= 0 {
+		backoff = defaultWriteBehindBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := s.backend.BatchStore(ctx, writes)
+		if err == nil {
+			return nil
+		}
+		s.cfg.Metrics.IncWriteBehindFlushError()
+		if s.cfg.Logger != nil {
+			s.cfg.Logger.Error("store.backend.flush", "writes", len(writes), "attempt", attempt+1, "err", err)
+		}
+		if attempt >= retries {
+			return fmt.Errorf("%w: %w", ErrBackend, err)
+		}
+		t := time.NewTimer(backoff << attempt)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// flushLoop は一定間隔、またはキューがバッチサイズに達するたびに Flush します。
+// Close 時は停止前に残りの書き込みを反映します。
+func (s *Store[K, V]) flushLoop(interval time.Duration) {
+	defer s.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-s.writeBehind.kick:
+		case <-s.stopCh:
+			if err := s.Flush(context.Background()); err != nil && s.cfg.Logger != nil {
+				s.cfg.Logger.Error("store.backend.close", "pending", s.PendingWrites(), "err", err)
+			}
+			return
+		}
+		_ = s.Flush(context.Background()) // 失敗はログとメトリクスに記録済み。次の周期で再試行する
+	}
+}
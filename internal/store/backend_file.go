@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileBackend はキーごとに 1 ファイルを書き出す Backend の参照実装です（テストや小規模な用途向け）。
+// ファイル名はキーを marshalValue した値の 16 進表現で、書き込みは一時ファイルからの rename で行います。
+// 16 進表現が fileBackendMaxName を超える長いキーは、ファイル名の長さ制限を避けるため "h" + SHA-256 の 16 進表現にします。
+type FileBackend[K comparable, V any] struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileBackend は dir 以下に値を保存する FileBackend を作成します。dir がなければ作成します。
+func NewFileBackend[K comparable, V any](dir string) (*FileBackend[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileBackend[K, V]{dir: dir}, nil
+}
+
+// fileBackendMaxName はキーの 16 進表現をそのままファイル名にする最大の長さです。
+// 一時ファイルの接尾辞 ".tmp" を付けても一般的な上限 (255 バイト) に収まるようにしています。
+const fileBackendMaxName = 200
+
+func (b *FileBackend[K, V]) path(key K) (string, error) {
+	kb, err := marshalValue(key)
+	if err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(kb)
+	if len(name) > fileBackendMaxName {
+		// "h" は 16 進数に現れないため、短いキーのファイル名とは衝突しない
+		sum := sha256.Sum256(kb)
+		name = "h" + hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(b.dir, name), nil
+}
+
+// Load はキーのファイルを読み込みます。ファイルがなければ ErrNotFound を返します。
+func (b *FileBackend[K, V]) Load(_ context.Context, key K) (V, error) {
+	var zero V
+	p, err := b.path(key)
+	if err != nil {
+		return zero, err
+	}
+	b.mu.RLock()
+	data, err := os.ReadFile(p)
+	b.mu.RUnlock()
+	if errors.Is(err, fs.ErrNotExist) {
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		return zero, err
+	}
+	return unmarshalValue[V](data)
+}
+
+// Store はキーのファイルを書き込みます。
+func (b *FileBackend[K, V]) Store(_ context.Context, key K, value V) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.store(key, value)
+}
+
+// Delete はキーのファイルを削除します。
+func (b *FileBackend[K, V]) Delete(_ context.Context, key K) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.delete(key)
+}
+
+// BatchStore は書き込みを順に反映します。途中で失敗した場合、それ以前の書き込みは反映されたままです。
+func (b *FileBackend[K, V]) BatchStore(ctx context.Context, writes []BackendWrite[K, V]) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, w := range writes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if w.Delete {
+			err = b.delete(w.Key)
+		} else {
+			err = b.store(w.Key, w.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FileBackend[K, V]) store(key K, value V) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	data, err := marshalValue(value)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (b *FileBackend[K, V]) delete(key K) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
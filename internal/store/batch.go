@@ -0,0 +1,297 @@
+package store
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchItem は MSet でセットする 1 件です。
+type BatchItem[K comparable, V any] struct {
+	Key     K
+	Value   V
+	TTL     time.Duration // 0 以下なら無期限
+	Sliding bool          // true ならスライディング期限 (SetWithSlidingTTL と同じ)
+}
+
+// BatchResult は MGet / MSet / MDelete の 1 件ごとの結果です。引数と同じ順に並びます。
+type BatchResult[K comparable, V any] struct {
+	Item[K, V]       // MGet: 値とメタデータ, MSet: セットした値と新しいバージョン・期限, MDelete: Key のみ
+	Found      bool  // MGet: キーが存在したか, MSet: 既存のキーを上書きしたか, MDelete: 削除したキーが存在したか
+	Err        error // MSet / MDelete: 失敗理由 (ErrValueTooLarge / ErrBackend / ErrAOF)。MGet では常に nil
+}
+
+// batchGroup は同じシャードに属するキーの、引数の中での位置です。
+type batchGroup struct {
+	shard int
+	idx   []int
+}
+
+// batchGroups は n 件のキーをシャードごとにまとめ、シャードのインデックス順に返します。
+// 各シャード内の位置は引数の順のままなので、同じキーが複数回あれば後のものが優先されます。
+func (s *Store[K, V]) batchGroups(n int, keyAt func(i int) K) []batchGroup {
+	// シャードごとの件数を数えてから位置を振り分ける（計数ソート）
+	shardOf := make([]int, n)
+	start := make([]int, s.shardCount()+1)
+	for i := range n {
+		si := s.shardIndex(keyAt(i))
+		shardOf[i] = si
+		start[si+1]++
+	}
+	for si := 1; si < len(start); si++ {
+		start[si] += start[si-1]
+	}
+	flat := make([]int, n)
+	next := slices.Clone(start[:len(start)-1])
+	for i, si := range shardOf {
+		flat[next[si]] = i
+		next[si]++
+	}
+	var groups []batchGroup
+	for si := range s.shardCount() {
+		if start[si] < start[si+1] {
+			groups = append(groups, batchGroup{shard: si, idx: flat[start[si]:start[si+1]]})
+		}
+	}
+	return groups
+}
+
+// batchKeys は idx の位置にあるキーを返します（lockKeys に渡すため）。
+func batchKeys[K comparable](idx []int, keyAt func(i int) K) []K {
+	keys := make([]K, len(idx))
+	for j, i := range idx {
+		keys[j] = keyAt(i)
+	}
+	return keys
+}
+
+// MGet は複数のキーの値をまとめて取得します。キーをシャードごとにまとめ、各シャードの読み取りロックを 1 回だけ取ります。
+// 期限切れのキーの削除とアクセスによる期限の延長 (スライディング期限・max-idle) が必要な場合だけ、そのシャードの書き込みロックを取り直します。
+// Get と異なりローダーは使いません。複数のキーをまたいだ一貫性は保証しません (Txn を使ってください)。
+func (s *Store[K, V]) MGet(keys []K) []BatchResult[K, V] {
+	results := make([]BatchResult[K, V], len(keys))
+	now := s.now().UnixNano()
+	var expired []removedEntry[K, V]
+	for _, g := range s.batchGroups(len(keys), func(i int) K { return keys[i] }) {
+		sh := s.shardAt(g.shard)
+		var touch []int // 期限切れ、またはアクセスで期限が延びるキー
+		sh.mu.RLock()
+		for _, i := range g.idx {
+			results[i].Key = keys[i]
+			e, ok := sh.m[keys[i]]
+			switch {
+			case !ok:
+			case e.expired(now):
+				touch = append(touch, i)
+			default:
+				if _, ok := s.accessExtension(e, now); ok {
+					touch = append(touch, i)
+				}
+				results[i].Item = newItem(keys[i], e)
+				results[i].Found = true
+			}
+		}
+		sh.mu.RUnlock()
+		if len(touch) > 0 {
+			expired = append(expired, s.touchBatch(sh, keys, touch, now, results)...)
+		}
+	}
+
+	s.removedAll(expired)
+	if len(expired) > 0 {
+		s.cfg.Metrics.AddTTLExpired(len(expired))
+		if s.evictor != nil {
+			for _, r := range expired {
+				s.evictor.OnDelete(r.key)
+			}
+		}
+	}
+	for _, r := range results {
+		if r.Found {
+			s.cfg.Metrics.IncGetHit()
+		} else {
+			s.cfg.Metrics.IncGetMiss()
+		}
+		if s.evictor != nil {
+			s.evictor.OnGet(r.Key, r.Found)
+		}
+	}
+	if s.evictor != nil {
+		if sp, ok := s.evictor.(interface{ Size() int }); ok {
+			s.cfg.Metrics.SetLRUSize(sp.Size())
+		}
+	}
+	return results
+}
+
+// touchBatch は sh の書き込みロック下で、MGet が読んだキーのうち期限切れのものを削除し (遅延削除)、
+// 読んだエントリがそのままなら期限を延ばします。削除したエントリを返します。
+func (s *Store[K, V]) touchBatch(sh *shardCompact[K, V], keys []K, idx []int, now int64, results []BatchResult[K, V]) (expired []removedEntry[K, V]) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	for _, i := range idx {
+		key := keys[i]
+		cur, ok := sh.m[key]
+		switch {
+		case !ok:
+		case results[i].Found:
+			if cur.ver != results[i].Version || cur.expired(now) {
+				continue
+			}
+			if exp, ok := s.accessExtension(cur, now); ok {
+				cur.expireAt = exp
+				sh.m[key] = cur
+				results[i].ExpireAt = time.Unix(0, exp)
+			}
+		case cur.expired(now):
+			delete(sh.m, key)
+			s.indexRemove(key)
+			s.addBytes(-cur.cost)
+			s.notifyRemoved(EventExpire, key, cur.ver)
+			// 失敗は aofError で記録済み。期限切れのキーは削除レコードが欠けても再生後に dropReplayedExpired で取り除かれる
+			_ = s.aofDelete(key)
+			expired = append(expired, removedEntry[K, V]{key, cur.val, Expired})
+		}
+	}
+	return expired
+}
+
+// MSet は複数のキーと値をまとめてセットします。キーをシャードごとにまとめ、各シャードのロックを 1 回だけ取ります。
+// Txn と異なりアトミックではなく、失敗した要素があっても他の要素はセットします。各要素の Err を確認してください。
+//   - 値だけで WithMaxBytes の予算を超える: その要素だけ ErrValueTooLarge
+//   - write-through で Backend への書き込みに失敗: 同じシャードの要素は BatchStore でまとめて反映するため、そのシャードの要素すべてが ErrBackend
+//   - FsyncAlways の AOF への追記に失敗: その要素が ErrAOF (ストアにはセット済み)
+func (s *Store[K, V]) MSet(items []BatchItem[K, V]) []BatchResult[K, V] {
+	results := make([]BatchResult[K, V], len(items))
+	costs := make([]int64, len(items))
+	var (
+		removed []removedEntry[K, V]
+		set     []int
+		existed []bool
+	)
+	for _, g := range s.batchGroups(len(items), func(i int) K { return items[i].Key }) {
+		valid := make([]int, 0, len(g.idx))
+		for _, i := range g.idx {
+			results[i].Key = items[i].Key
+			costs[i] = s.costOf(items[i].Key, items[i].Value)
+			if err := s.checkCost(costs[i]); err != nil {
+				results[i].Err = err
+				continue
+			}
+			valid = append(valid, i)
+		}
+		if len(valid) == 0 {
+			continue
+		}
+
+		sh := s.shardAt(g.shard)
+		unlockKeys := s.lockKeys(batchKeys(valid, func(i int) K { return items[i].Key })...)
+		sh.mu.Lock()
+		if s.backend != nil {
+			writes := make([]BackendWrite[K, V], 0, len(valid))
+			for _, i := range valid {
+				writes = append(writes, BackendWrite[K, V]{Key: items[i].Key, Value: items[i].Value})
+			}
+			if err := s.backendWriteBatch([]*sync.RWMutex{&sh.mu}, writes); err != nil {
+				sh.mu.Unlock()
+				unlockKeys()
+				for _, i := range valid {
+					results[i].Err = err
+				}
+				continue
+			}
+		}
+		now := s.now()
+		nowNano := now.UnixNano()
+		for _, i := range valid {
+			it := items[i]
+			cur, ok := sh.m[it.Key]
+			live := ok && !cur.expired(nowNano)
+			ver := s.nextVersion()
+			e := entry[V]{val: it.Value, ver: ver, cost: costs[i], ttl: max(it.TTL, 0), sliding: it.Sliding && it.TTL > 0}
+			if it.TTL > 0 && !e.sliding {
+				e.deadline = now.Add(it.TTL).UnixNano()
+			}
+			e.expireAt = s.accessExpireAt(e, nowNano)
+			sh.m[it.Key] = e
+			s.addBytes(costs[i] - cur.cost)
+			s.expireAdd(it.Key, e.expireAt)
+			s.indexAdd(it.Key)
+			s.notify(setEventType(live), it.Key, it.Value, ver)
+			results[i].Err = s.aofSet(it.Key, it.Value, e.persistedExpireAt(), ver)
+			results[i].Item = newItem(it.Key, e)
+			results[i].Found = live
+			if ok {
+				removed = append(removed, removedEntry[K, V]{it.Key, cur.val, removalReason(cur, nowNano, Replaced)})
+			}
+			set = append(set, i)
+			existed = append(existed, ok)
+		}
+		sh.mu.Unlock()
+		unlockKeys()
+	}
+
+	s.removedAll(removed)
+	for j, i := range set {
+		s.afterSet(items[i].Key, items[i].Value, existed[j])
+	}
+	return results
+}
+
+// MDelete は複数のキーをまとめて削除します。キーをシャードごとにまとめ、各シャードのロックを 1 回だけ取ります。
+// Delete と同様に、Backend を設定している場合はストアにないキーも Backend から削除します。
+// write-through で Backend の削除に失敗した場合は、そのシャードのキーすべてが ErrBackend になり、ストアからも削除しません。
+// FsyncAlways の AOF への追記に失敗したキーは ErrAOF になります (ストアからは削除済み)。
+func (s *Store[K, V]) MDelete(keys []K) []BatchResult[K, V] {
+	results := make([]BatchResult[K, V], len(keys))
+	var (
+		removed []removedEntry[K, V]
+		deleted []K
+	)
+	for _, g := range s.batchGroups(len(keys), func(i int) K { return keys[i] }) {
+		for _, i := range g.idx {
+			results[i].Key = keys[i]
+		}
+		sh := s.shardAt(g.shard)
+		unlockKeys := s.lockKeys(batchKeys(g.idx, func(i int) K { return keys[i] })...)
+		sh.mu.Lock()
+		if s.backend != nil {
+			writes := make([]BackendWrite[K, V], 0, len(g.idx))
+			for _, i := range g.idx {
+				writes = append(writes, BackendWrite[K, V]{Key: keys[i], Delete: true})
+			}
+			if err := s.backendWriteBatch([]*sync.RWMutex{&sh.mu}, writes); err != nil {
+				sh.mu.Unlock()
+				unlockKeys()
+				for _, i := range g.idx {
+					results[i].Err = err
+				}
+				continue
+			}
+		}
+		now := s.now().UnixNano()
+		for _, i := range g.idx {
+			key := keys[i]
+			cur, ok := sh.m[key]
+			if !ok {
+				continue
+			}
+			delete(sh.m, key)
+			s.indexRemove(key)
+			s.addBytes(-cur.cost)
+			s.notifyRemoved(EventDelete, key, cur.ver)
+			results[i].Err = s.aofDelete(key)
+			results[i].Found = !cur.expired(now)
+			removed = append(removed, removedEntry[K, V]{key, cur.val, removalReason(cur, now, Deleted)})
+			deleted = append(deleted, key)
+		}
+		sh.mu.Unlock()
+		unlockKeys()
+	}
+
+	s.removedAll(removed)
+	for _, key := range deleted {
+		s.afterDelete(key, false)
+	}
+	return results
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound はキーが存在しない（期限切れを含む）場合のエラーです。
+	ErrNotFound = errors.New("store: key not found")
+	// ErrVersionMismatch は期待したバージョンと現在のバージョンが一致しない場合のエラーです。
+	ErrVersionMismatch = errors.New("store: version mismatch")
+)
+
+// CompareAndSwap は現在のバージョンが expectedVersion と一致する場合のみ値をセットし、新しいバージョンを返します。
+// expectedVersion に 0 を指定するとキーが存在しない場合のみセットします（作成専用）。
+//   - キーが存在しない: ErrNotFound（expectedVersion=0 を除く）
+//   - バージョン不一致 / 作成専用で既に存在: ErrVersionMismatch
+//   - 値だけで WithMaxBytes の予算を超える: ErrValueTooLarge
+//   - write-through で Backend への書き込みに失敗: ErrBackend
+//   - FsyncAlways の AOF への追記に失敗: ErrAOF（セットは済んでおり、新しいバージョンも返します）
+func (s *Store[K, V]) CompareAndSwap(key K, expectedVersion uint64, value V, ttl time.Duration) (uint64, error) {
+	return s.compareAndSwap(key, expectedVersion, value, ttl, false, true)
+}
+
+// compareAndSwap は CompareAndSwap の本体です。sliding ならスライディング期限にします。
+// toBackend が false なら Backend に書き込みません（Backend から読み込んだ値をセットする場合）。
+func (s *Store[K, V]) compareAndSwap(key K, expectedVersion uint64, value V, ttl time.Duration, sliding, toBackend bool) (uint64, error) {
+	cost := s.costOf(key, value)
+	if err := s.checkCost(cost); err != nil {
+		return 0, err
+	}
+	now := s.now()
+	e := entry[V]{val: value, cost: cost, ttl: max(ttl, 0), sliding: sliding && ttl > 0}
+	if ttl > 0 && !e.sliding {
+		e.deadline = now.Add(ttl).UnixNano()
+	}
+	e.expireAt = s.accessExpireAt(e, now.UnixNano())
+	mu, mp := s.getShard(key)
+	// Backend に書き込まない場合（ローダー）も、書き込み中の同じキーの変更を追い越さないようにキーのロックを取る
+	unlockKey := s.lockKeys(key)
+	mu.Lock()
+	cur, existed := mp[key]
+	if err := checkVersion(cur, existed, expectedVersion, now.UnixNano()); err != nil {
+		mu.Unlock()
+		unlockKey()
+		return 0, err
+	}
+	if toBackend {
+		if err := s.backendWrite(mu, BackendWrite[K, V]{Key: key, Value: value}); err != nil {
+			mu.Unlock()
+			unlockKey()
+			return 0, err
+		}
+		cur, existed = mp[key] // Backend への書き込み中に TTL や Evict で消えていることがある
+	}
+	ver := s.nextVersion()
+	e.ver = ver
+	mp[key] = e
+	s.addBytes(cost - cur.cost)
+	s.expireAdd(key, e.expireAt)
+	s.indexAdd(key)
+	s.notify(setEventType(existed && !cur.expired(now.UnixNano())), key, value, ver)
+	aofErr := s.aofSet(key, value, e.persistedExpireAt(), ver)
+	mu.Unlock()
+	unlockKey()
+
+	if existed {
+		s.removed(key, cur.val, removalReason(cur, now.UnixNano(), Replaced))
+	}
+	s.afterSet(key, value, existed)
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Debug("store.cas", "key", key, "version", ver)
+	}
+	return ver, aofErr
+}
+
+// CompareAndDelete は現在のバージョンが expectedVersion と一致する場合のみキーを削除します。
+func (s *Store[K, V]) CompareAndDelete(key K, expectedVersion uint64) error {
+	if expectedVersion == 0 {
+		return ErrVersionMismatch
+	}
+	mu, mp := s.getShard(key)
+	unlockKey := s.lockKeys(key)
+	mu.Lock()
+	cur, existed := mp[key]
+	if err := checkVersion(cur, existed, expectedVersion, s.now().UnixNano()); err != nil {
+		mu.Unlock()
+		unlockKey()
+		return err
+	}
+	if err := s.backendWrite(mu, BackendWrite[K, V]{Key: key, Delete: true}); err != nil {
+		mu.Unlock()
+		unlockKey()
+		return err
+	}
+	cur, existed = mp[key]
+	var aofErr error
+	if existed {
+		delete(mp, key)
+		s.indexRemove(key)
+		s.addBytes(-cur.cost)
+		s.notifyRemoved(EventDelete, key, cur.ver)
+		aofErr = s.aofDelete(key)
+	}
+	mu.Unlock()
+	unlockKey()
+
+	if existed {
+		s.removed(key, cur.val, Deleted)
+		s.afterDelete(key, false)
+	}
+	return aofErr
+}
+
+// checkVersion は期限切れのエントリを存在しないものとして扱い、バージョンを比較します。
+func checkVersion[V any](cur entry[V], existed bool, expected uint64, now int64) error {
+	live := existed && !cur.expired(now)
+	switch {
+	case expected == 0 && live:
+		return ErrVersionMismatch
+	case expected == 0:
+		return nil
+	case !live:
+		return ErrNotFound
+	case cur.ver != expected:
+		return ErrVersionMismatch
+	}
+	return nil
+}
@@ -1,79 +1,61 @@
 package store
 
-import "time"
+import "github.com/amakane-hakari/kavos/internal/clock"
 
-func (s *Store[K, V]) cleanupLoop() {
+// expireBatch はシャードロックを 1 回取る間に期限ヒープから取り出す要素の最大数です。
+// 大量のキーが同時に期限を迎えても、他の操作を長く止めないようにします。
+const expireBatch = 1024
+
+// cleanupLoop は t の発火ごとに TTL クリーンアップを行います。
+// ティッカーは Open で作成して渡します（fake の Clock で Advance する前に確実に登録されるように）。
+func (s *Store[K, V]) cleanupLoop(t clock.Ticker) {
 	defer s.wg.Done()
-	t := time.NewTicker(s.cleanupInterval)
 	defer t.Stop()
 	for {
 		select {
-		case <-t.C:
-			s.scanExpired()
+		case <-t.C():
+			if s.cfg.ExpireStrategy == ExpireSampled {
+				s.activeExpireCycle()
+			} else {
+				s.scanExpired()
+			}
+			s.purgeNegative()
 		case <-s.stopCh:
 			return
 		}
 	}
 }
 
+// scanExpired は各シャードの期限ヒープから期限を迎えたキーだけを削除します。
+// 処理量は期限切れのキー数に比例し、ストア全体のキー数には依存しません。
 func (s *Store[K, V]) scanExpired() {
-	now := time.Now().UnixNano()
+	now := s.now().UnixNano()
 	totalExpired := 0
-	if s.cfg.EnableShardPadding {
-		for i := range s.shardsPadded {
-			sh := &s.shardsPadded[i]
-			var expiredKeys []K
-			sh.mu.Lock()
-			for k, e := range sh.m {
-				if e.expireAt > 0 && e.expireAt <= now {
-					delete(sh.m, k)
-					expiredKeys = append(expiredKeys, k)
-				}
-			}
-			sh.mu.Unlock()
-			if len(expiredKeys) > 0 {
-				totalExpired += len(expiredKeys)
+	for i := 0; i < s.shardCount(); i++ {
+		sh := s.shardAt(i)
+		removed := 0
+		for {
+			expired, more := s.expireShard(sh, now)
+			if len(expired) > 0 {
+				removed += len(expired)
+				s.removedAll(expired)
 				if s.evictor != nil {
-					for _, k := range expiredKeys {
-						s.evictor.OnDelete(k)
+					for _, r := range expired {
+						s.evictor.OnDelete(r.key)
 					}
 				}
-				if sp, ok := s.evictor.(interface{ Size() int }); ok {
-					s.cfg.Metrics.SetLRUSize(sp.Size())
-				}
-				if s.cfg.Logger != nil {
-					s.cfg.Logger.Info("store.ttl.cleanup", "shard", i, "removed", len(expiredKeys))
-				}
+			}
+			if !more {
+				break
 			}
 		}
-	} else {
-		for i := range s.shardsCompact {
-			sh := &s.shardsCompact[i]
-			var expiredKeys []K
-			sh.mu.Lock()
-			for k, e := range sh.m {
-				if e.expireAt > 0 && e.expireAt <= now {
-					delete(sh.m, k)
-					expiredKeys = append(expiredKeys, k)
-				}
+		if removed > 0 {
+			totalExpired += removed
+			if sp, ok := s.evictor.(interface{ Size() int }); ok {
+				s.cfg.Metrics.SetLRUSize(sp.Size())
 			}
-			if s.cfg.Logger != nil && len(expiredKeys) > 0 {
-				s.cfg.Logger.Info("store.ttl.cleanup", "shard", i, "removed", len(expiredKeys))
-			}
-			sh.mu.Unlock()
-			if len(expiredKeys) > 0 {
-				totalExpired += len(expiredKeys)
-				if s.evictor != nil {
-					for _, k := range expiredKeys {
-						s.evictor.OnDelete(k)
-					}
-				}
-				if sp, ok := s.evictor.(interface{ Size() int }); ok {
-					s.cfg.Metrics.SetLRUSize(sp.Size())
-				}
-				if s.cfg.Logger != nil {
-					s.cfg.Logger.Info("store.ttl.cleanup", "shard", i, "removed", len(expiredKeys))
-				}
+			if s.cfg.Logger != nil {
+				s.cfg.Logger.Info("store.ttl.cleanup", "shard", i, "removed", removed)
 			}
 		}
 	}
@@ -81,3 +63,50 @@ func (s *Store[K, V]) scanExpired() {
 		s.cfg.Metrics.AddTTLExpired(totalExpired)
 	}
 }
+
+// expireShard は sh の期限ヒープから now までに期限を迎えた要素を最大 expireBatch 件取り出し、
+// まだ有効なエントリ（期限が一致するもの）を削除します。続きがあれば more=true を返します。
+// アクセスで期限が延びたエントリ（スライディング期限・max-idle）は、延びた期限で登録し直します。
+func (s *Store[K, V]) expireShard(sh *shardCompact[K, V], now int64) (expired []removedEntry[K, V], more bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	for range expireBatch {
+		it, ok := sh.exp.peek()
+		if !ok || it.at > now {
+			s.compactExpiry(sh)
+			return expired, false
+		}
+		sh.exp.pop()
+		e, ok := sh.m[it.key]
+		if ok && e.expireAt > it.at && s.extendsOnAccess(e) {
+			sh.exp.push(it.key, e.expireAt)
+			continue
+		}
+		if !ok || e.expireAt != it.at {
+			// 削除済み、または更新で期限が変わった
+			continue
+		}
+		delete(sh.m, it.key)
+		s.indexRemove(it.key)
+		s.addBytes(-e.cost)
+		s.notifyRemoved(EventExpire, it.key, e.ver)
+		// 失敗は aofError で記録済み。期限切れのキーは削除レコードが欠けても再生後に dropReplayedExpired で取り除かれる
+		_ = s.aofDelete(it.key)
+		expired = append(expired, removedEntry[K, V]{it.key, e.val, Expired})
+	}
+	return expired, true
+}
+
+// compactExpiry は無効な要素が溜まった期限ヒープを作り直します。シャードロック下で呼びます。
+func (s *Store[K, V]) compactExpiry(sh *shardCompact[K, V]) {
+	if sh.exp.len() <= 2*len(sh.m)+expireBatch {
+		return
+	}
+	items := make([]expiryItem[K], 0, len(sh.m))
+	for k, e := range sh.m {
+		if e.expireAt > 0 {
+			items = append(items, expiryItem[K]{at: e.expireAt, key: k})
+		}
+	}
+	sh.exp.rebuild(items)
+}
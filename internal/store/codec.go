@@ -0,0 +1,32 @@
+package store
+
+import "encoding/json"
+
+// marshalValue はキー/値を永続化用のバイト列に変換します。
+// string / []byte はそのまま、それ以外は JSON でエンコードします。
+func marshalValue[T any](v T) ([]byte, error) {
+	switch x := any(v).(type) {
+	case string:
+		return []byte(x), nil
+	case []byte:
+		return x, nil
+	default:
+		return json.Marshal(x)
+	}
+}
+
+// unmarshalValue は marshalValue の逆変換です。
+func unmarshalValue[T any](b []byte) (T, error) {
+	var v T
+	switch p := any(&v).(type) {
+	case *string:
+		*p = string(b)
+	case *[]byte:
+		*p = append([]byte(nil), b...)
+	default:
+		if err := json.Unmarshal(b, &v); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
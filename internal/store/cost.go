@@ -0,0 +1,106 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrValueTooLarge は 1 件のコストだけで WithMaxBytes の予算を超える値をセットしようとした場合のエラーです。
+var ErrValueTooLarge = errors.New("store: value exceeds max bytes")
+
+// CostOverhead は組み込みのコスト関数がエントリごとに加算する固定のバイト数です。
+// map のバケットやエントリ構造体などキーと値以外に使うメモリの概算です。
+const CostOverhead = 64
+
+// StringCost は string のキーと値のコストを見積もります。
+//
+//	store.WithCost(store.StringCost)
+func StringCost(key, value string) int64 {
+	return int64(len(key)+len(value)) + CostOverhead
+}
+
+// BytesCost は string のキーと []byte の値のコストを見積もります（値は確保済みの容量で数えます）。
+func BytesCost(key string, value []byte) int64 {
+	return int64(len(key)+cap(value)) + CostOverhead
+}
+
+// CostEvictor はバイト予算 (WithMaxBytes) を守るために、キー数の容量とは別に追い出しを行える Evictor です。
+// 組み込みの Evictor はすべて実装しています。実装しない Evictor ではバイト予算による追い出しは行われません。
+type CostEvictor[K comparable] interface {
+	// Evict は次に追い出すキーを 1 件内部状態から除外して返します。追い出せるキーがなければ ok=false。
+	Evict() (key K, ok bool)
+}
+
+// costFunc は Config.Cost を Store の型に合わせて取り出します。
+// WithMaxBytes のみ指定された場合は string キーの Store に限り組み込みのコスト関数を使います。
+func costFunc[K comparable, V any](cfg Config) (func(K, V) int64, error) {
+	if cfg.Cost != nil {
+		fn, ok := cfg.Cost.(func(K, V) int64)
+		if !ok {
+			var k K
+			var v V
+			return nil, fmt.Errorf("store: cost function %T does not match Store[%T, %T]", cfg.Cost, k, v)
+		}
+		return fn, nil
+	}
+	if cfg.MaxBytes <= 0 {
+		return nil, nil
+	}
+	for _, fn := range []any{StringCost, BytesCost} {
+		if f, ok := fn.(func(K, V) int64); ok {
+			return f, nil
+		}
+	}
+	return nil, errors.New("store: WithMaxBytes requires WithCost for this key/value type")
+}
+
+func (s *Store[K, V]) costOf(key K, value V) int64 {
+	if s.cost == nil {
+		return 0
+	}
+	return s.cost(key, value)
+}
+
+// checkCost は 1 件で予算全体を超える値を拒否します。
+func (s *Store[K, V]) checkCost(cost int64) error {
+	if s.cfg.MaxBytes > 0 && cost > s.cfg.MaxBytes {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// addBytes はコスト合計を増減し、メトリクスに反映します。シャードロック下で呼びます。
+func (s *Store[K, V]) addBytes(delta int64) {
+	if s.cost == nil || delta == 0 {
+		return
+	}
+	s.cfg.Metrics.SetStoreBytes(s.bytes.Add(delta))
+}
+
+// Bytes は保持しているエントリのコスト合計を返します。WithCost/WithMaxBytes を指定していなければ常に 0 です。
+// 期限切れでまだ削除されていないエントリも含みます。
+func (s *Store[K, V]) Bytes() int64 {
+	return s.bytes.Load()
+}
+
+// shrinkToMaxBytes はコスト合計が予算以下になるまで Evictor に追い出させ、削除したキーを返します。
+func (s *Store[K, V]) shrinkToMaxBytes() []K {
+	if s.cfg.MaxBytes <= 0 {
+		return nil
+	}
+	ce, ok := s.evictor.(CostEvictor[K])
+	if !ok {
+		return nil
+	}
+	var victims []K
+	for s.bytes.Load() > s.cfg.MaxBytes {
+		k, ok := ce.Evict()
+		if !ok {
+			break
+		}
+		// 追い出しは AOF への記録に失敗しても取り消さない（失敗は aofError で記録済み。再起動後に残っても evictor が上限内に収める）
+		_ = s.deleteInternal(k, true)
+		victims = append(victims, k)
+	}
+	return victims
+}
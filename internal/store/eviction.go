@@ -65,6 +65,20 @@ func (l *LRUEvictor[K, V]) OnSet(key K, _ V, existed bool) (victims []K) {
 	return victims
 }
 
+// Evict は最も古いキーを 1 件取り除いて返します（CostEvictor）。
+func (l *LRUEvictor[K, V]) Evict() (key K, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	front := l.ll.Front()
+	if front == nil {
+		return key, false
+	}
+	it := front.Value.(*lruItem[K])
+	delete(l.idx, it.key)
+	l.ll.Remove(front)
+	return it.key, true
+}
+
 // OnGet はアイテムが取得されたときに呼び出されます。
 func (l *LRUEvictor[K, V]) OnGet(key K, hit bool) {
 	if !hit {
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ARCEvictor は ARC (Adaptive Replacement Cache) 方式のエビクタです。
+//
+// 常駐キーを一度だけ使われた T1 と二度以上使われた T2 に分けて LRU で管理し、
+// それぞれから追い出したキーをゴーストリスト B1 / B2 に記憶します。
+// 追い出したキーが再びセットされたとき、B1 にあれば T1（最近性）側、
+// B2 にあれば T2（頻度）側の目標サイズ p を広げ、ワークロードに合わせて配分を調整します。
+//
+// ゴーストはキーのみを保持し、Size() には含まれません（最大 capacity 件）。
+type ARCEvictor[K comparable, V any] struct {
+	cap int
+
+	mu     sync.Mutex
+	p      int // T1 の目標サイズ
+	t1, t2 *list.List
+	b1, b2 *list.List
+	idx    map[K]*list.Element // *arcItem[K]（常駐・ゴースト両方）
+}
+
+type arcList uint8
+
+const (
+	arcT1 arcList = iota
+	arcT2
+	arcB1
+	arcB2
+	arcNone // ゴーストに残さない
+)
+
+type arcItem[K comparable] struct {
+	key K
+	in  arcList
+}
+
+// NewARCEvictor は新しい ARCEvictor を作成します。
+func NewARCEvictor[K comparable, V any](capacity int) *ARCEvictor[K, V] {
+	if capacity <= 0 {
+		capacity = 1 // 最低でも1つは保持
+	}
+	return &ARCEvictor[K, V]{
+		cap: capacity,
+		t1:  list.New(),
+		t2:  list.New(),
+		b1:  list.New(),
+		b2:  list.New(),
+		idx: make(map[K]*list.Element),
+	}
+}
+
+// Size は現在のサイズ（常駐キー数）を返します。
+func (a *ARCEvictor[K, V]) Size() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.t1.Len() + a.t2.Len()
+}
+
+// OnSet はアイテムがセットされたときに呼び出されます。
+func (a *ARCEvictor[K, V]) OnSet(key K, _ V, _ bool) (victims []K) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	el, ok := a.idx[key]
+	if ok {
+		it := el.Value.(*arcItem[K])
+		switch it.in {
+		case arcT1, arcT2:
+			a.promote(el)
+			return nil
+		case arcB1:
+			// 最近性側で取りこぼした: T1 の目標を広げる
+			a.p = min(a.cap, a.p+max(a.b2.Len()/a.b1.Len(), 1))
+			victims = a.replace(false)
+			a.list(it.in).Remove(el)
+			a.push(arcT2, key)
+			return victims
+		case arcB2:
+			// 頻度側で取りこぼした: T2 の目標を広げる
+			a.p = max(0, a.p-max(a.b1.Len()/a.b2.Len(), 1))
+			victims = a.replace(true)
+			a.list(it.in).Remove(el)
+			a.push(arcT2, key)
+			return victims
+		}
+	}
+
+	// 完全なミス
+	l1 := a.t1.Len() + a.b1.Len()
+	total := l1 + a.t2.Len() + a.b2.Len()
+	switch {
+	case l1 >= a.cap:
+		if a.t1.Len() < a.cap {
+			a.dropGhost(a.b1)
+			victims = a.replace(false)
+		} else {
+			victims = append(victims, a.evictLRU(a.t1, arcNone))
+		}
+	case total >= a.cap:
+		if total >= 2*a.cap {
+			a.dropGhost(a.b2)
+		}
+		victims = a.replace(false)
+	}
+	a.push(arcT1, key)
+	return victims
+}
+
+// Evict は目標 p に従って T1 か T2 の LRU を 1 件ゴーストへ移し、そのキーを返します（CostEvictor）。
+// 容量に関係なく追い出すため、ゴーストが容量を超えないよう古いものから捨てます。
+func (a *ARCEvictor[K, V]) Evict() (key K, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case a.t1.Len() > 0 && (a.t1.Len() > a.p || a.t2.Len() == 0):
+		key = a.evictLRU(a.t1, arcB1)
+	case a.t2.Len() > 0:
+		key = a.evictLRU(a.t2, arcB2)
+	default:
+		return key, false
+	}
+	for a.b1.Len() > 0 && a.t1.Len()+a.b1.Len() > a.cap {
+		a.dropGhost(a.b1)
+	}
+	for a.b2.Len() > 0 && a.t1.Len()+a.t2.Len()+a.b1.Len()+a.b2.Len() > 2*a.cap {
+		a.dropGhost(a.b2)
+	}
+	return key, true
+}
+
+// OnGet はアイテムが取得されたときに呼び出されます。
+func (a *ARCEvictor[K, V]) OnGet(key K, hit bool) {
+	if !hit {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if el, ok := a.idx[key]; ok {
+		if in := el.Value.(*arcItem[K]).in; in == arcT1 || in == arcT2 {
+			a.promote(el)
+		}
+	}
+}
+
+// OnDelete はアイテムが削除されたときに呼び出されます。
+// 明示的に削除されたキーは再アクセスの指標にならないため、ゴーストにも残しません。
+func (a *ARCEvictor[K, V]) OnDelete(key K) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if el, ok := a.idx[key]; ok {
+		a.list(el.Value.(*arcItem[K]).in).Remove(el)
+		delete(a.idx, key)
+	}
+}
+
+// promote は常駐キーを T2 の MRU へ移します。
+func (a *ARCEvictor[K, V]) promote(el *list.Element) {
+	it := el.Value.(*arcItem[K])
+	if it.in == arcT2 {
+		a.t2.MoveToBack(el)
+		return
+	}
+	a.t1.Remove(el)
+	a.push(arcT2, it.key)
+}
+
+// replace は常駐数が容量に達していれば T1 か T2 の LRU をゴーストへ移し、そのキーを返します。
+// inB2 は今回のキーが B2 のゴーストだったかどうかです。
+func (a *ARCEvictor[K, V]) replace(inB2 bool) []K {
+	if a.t1.Len()+a.t2.Len() < a.cap {
+		return nil
+	}
+	if a.t1.Len() > 0 && (a.t1.Len() > a.p || (inB2 && a.t1.Len() == a.p)) {
+		return []K{a.evictLRU(a.t1, arcB1)}
+	}
+	if a.t2.Len() > 0 {
+		return []K{a.evictLRU(a.t2, arcB2)}
+	}
+	return []K{a.evictLRU(a.t1, arcB1)}
+}
+
+// evictLRU は l の LRU を取り除き、ghost が arcNone 以外ならそのゴーストリストへ移します。
+func (a *ARCEvictor[K, V]) evictLRU(l *list.List, ghost arcList) K {
+	el := l.Front()
+	it := el.Value.(*arcItem[K])
+	l.Remove(el)
+	delete(a.idx, it.key)
+	if ghost != arcNone {
+		a.push(ghost, it.key)
+	}
+	return it.key
+}
+
+func (a *ARCEvictor[K, V]) dropGhost(l *list.List) {
+	if el := l.Front(); el != nil {
+		l.Remove(el)
+		delete(a.idx, el.Value.(*arcItem[K]).key)
+	}
+}
+
+func (a *ARCEvictor[K, V]) push(in arcList, key K) {
+	a.idx[key] = a.list(in).PushBack(&arcItem[K]{key: key, in: in})
+}
+
+func (a *ARCEvictor[K, V]) list(in arcList) *list.List {
+	switch in {
+	case arcT1:
+		return a.t1
+	case arcT2:
+		return a.t2
+	case arcB1:
+		return a.b1
+	default:
+		return a.b2
+	}
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LFUEvictor はアクセス頻度の最も低いキーを追い出します（同頻度なら最も古いもの）。
+// 頻度ごとのバケットを連結リストで保持するため、OnSet / OnGet / OnDelete はいずれも O(1) です。
+//
+// 新規キーは頻度 1 から始まるため、一度しか読まれないキーを大量に流すスキャンでも
+// 繰り返し使われるキーは追い出されません。
+// WithLFUDecay を指定すると一定回数のアクセスごとに全キーの頻度を半減させ、
+// 過去に人気だったが使われなくなったキーもいずれ追い出されるようにします。
+type LFUEvictor[K comparable, V any] struct {
+	cap        int
+	decayEvery int // 0 で減衰なし
+
+	mu    sync.Mutex
+	idx   map[K]*lfuEntry[K]
+	freqs *list.List // *lfuBucket[K]、頻度の昇順
+	ops   int        // 前回の減衰からのアクセス数
+}
+
+type lfuBucket[K comparable] struct {
+	freq  uint64
+	items *list.List // *lfuEntry[K]、Front = 最も古い
+}
+
+type lfuEntry[K comparable] struct {
+	key    K
+	bucket *list.Element // freqs 内の要素
+	el     *list.Element // bucket.items 内の要素
+}
+
+// LFUOption は LFUEvictor のオプションです。
+type LFUOption func(*lfuConfig)
+
+type lfuConfig struct {
+	decayEvery int
+}
+
+// WithLFUDecay は every 回のアクセス（OnSet / ヒットした OnGet）ごとに全キーの頻度を半減させるオプションです。
+// 減衰は O(n) なので、every は容量の数倍以上を推奨します。
+func WithLFUDecay(every int) LFUOption {
+	return func(c *lfuConfig) { c.decayEvery = every }
+}
+
+// NewLFUEvictor は新しい LFUEvictor を作成します。
+func NewLFUEvictor[K comparable, V any](capacity int, opts ...LFUOption) *LFUEvictor[K, V] {
+	if capacity <= 0 {
+		capacity = 1 // 最低でも1つは保持
+	}
+	var cfg lfuConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &LFUEvictor[K, V]{
+		cap:        capacity,
+		decayEvery: max(cfg.decayEvery, 0),
+		idx:        make(map[K]*lfuEntry[K]),
+		freqs:      list.New(),
+	}
+}
+
+// Size は現在のサイズを返します。
+func (l *LFUEvictor[K, V]) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.idx)
+}
+
+// OnSet はアイテムがセットされたときに呼び出されます。
+func (l *LFUEvictor[K, V]) OnSet(key K, _ V, _ bool) (victims []K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.idx[key]; ok {
+		l.touch(e)
+		return nil
+	}
+
+	// 容量に空きを作ってから追加する（新規キー自身を追い出さないため）
+	for len(l.idx) >= l.cap {
+		victims = append(victims, l.evict())
+	}
+	l.insert(key)
+	l.tick()
+	return victims
+}
+
+// Evict は最低頻度のキーを 1 件取り除いて返します（CostEvictor）。
+func (l *LFUEvictor[K, V]) Evict() (key K, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.idx) == 0 {
+		return key, false
+	}
+	return l.evict(), true
+}
+
+// OnGet はアイテムが取得されたときに呼び出されます。
+func (l *LFUEvictor[K, V]) OnGet(key K, hit bool) {
+	if !hit {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.idx[key]; ok {
+		l.touch(e)
+	}
+}
+
+// OnDelete はアイテムが削除されたときに呼び出されます。
+func (l *LFUEvictor[K, V]) OnDelete(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.idx[key]; ok {
+		l.unlink(e)
+		delete(l.idx, key)
+	}
+}
+
+func (l *LFUEvictor[K, V]) insert(key K) {
+	front := l.freqs.Front()
+	if front == nil || front.Value.(*lfuBucket[K]).freq != 1 {
+		front = l.freqs.PushFront(&lfuBucket[K]{freq: 1, items: list.New()})
+	}
+	e := &lfuEntry[K]{key: key, bucket: front}
+	e.el = front.Value.(*lfuBucket[K]).items.PushBack(e)
+	l.idx[key] = e
+}
+
+// touch は頻度を 1 上げて次のバケットへ移します。
+func (l *LFUEvictor[K, V]) touch(e *lfuEntry[K]) {
+	cur := e.bucket
+	freq := cur.Value.(*lfuBucket[K]).freq + 1
+	next := cur.Next()
+	if next == nil || next.Value.(*lfuBucket[K]).freq != freq {
+		next = l.freqs.InsertAfter(&lfuBucket[K]{freq: freq, items: list.New()}, cur)
+	}
+	l.unlink(e)
+	e.bucket = next
+	e.el = next.Value.(*lfuBucket[K]).items.PushBack(e)
+	l.tick()
+}
+
+// unlink はエントリをバケットから外し、空になったバケットを削除します。
+func (l *LFUEvictor[K, V]) unlink(e *lfuEntry[K]) {
+	b := e.bucket.Value.(*lfuBucket[K])
+	b.items.Remove(e.el)
+	if b.items.Len() == 0 {
+		l.freqs.Remove(e.bucket)
+	}
+}
+
+// evict は最低頻度バケットの最も古いキーを取り除いて返します。
+func (l *LFUEvictor[K, V]) evict() K {
+	b := l.freqs.Front().Value.(*lfuBucket[K])
+	e := b.items.Front().Value.(*lfuEntry[K])
+	l.unlink(e)
+	delete(l.idx, e.key)
+	return e.key
+}
+
+func (l *LFUEvictor[K, V]) tick() {
+	if l.decayEvery == 0 {
+		return
+	}
+	l.ops++
+	if l.ops >= l.decayEvery {
+		l.ops = 0
+		l.decay()
+	}
+}
+
+// decay は全バケットの頻度を半減（最低 1）させ、同じ頻度になったバケットを併合します。
+// 半減は単調なので順序はそのまま保たれます。
+func (l *LFUEvictor[K, V]) decay() {
+	var prev *list.Element
+	for el := l.freqs.Front(); el != nil; {
+		next := el.Next()
+		b := el.Value.(*lfuBucket[K])
+		b.freq = max(b.freq/2, 1)
+		if prev != nil && prev.Value.(*lfuBucket[K]).freq == b.freq {
+			pb := prev.Value.(*lfuBucket[K])
+			for it := b.items.Front(); it != nil; it = it.Next() {
+				e := it.Value.(*lfuEntry[K])
+				e.bucket = prev
+				e.el = pb.items.PushBack(e)
+			}
+			l.freqs.Remove(el)
+		} else {
+			prev = el
+		}
+		el = next
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import "sync/atomic"
+
+// shardedEvictor はストアのシャードごとに独立した Evictor を持ち、キーを所属シャードの Evictor に振り分けます。
+// ヒット時の OnGet もシャード単位のロックで済むため、全体で 1 つのロックを取り合うことがありません。
+type shardedEvictor[K comparable, V any] struct {
+	shards []Evictor[K, V]
+	index  func(K) int
+	next   atomic.Uint32 // Evict で最初に試すシャード（ラウンドロビン）
+}
+
+func (e *shardedEvictor[K, V]) OnSet(key K, value V, existed bool) []K {
+	return e.shards[e.index(key)].OnSet(key, value, existed)
+}
+
+func (e *shardedEvictor[K, V]) OnGet(key K, hit bool) {
+	e.shards[e.index(key)].OnGet(key, hit)
+}
+
+func (e *shardedEvictor[K, V]) OnDelete(key K) {
+	e.shards[e.index(key)].OnDelete(key)
+}
+
+// Evict はシャードを順番に回り、CostEvictor を実装したシャードの Evictor から 1 件追い出します。
+// バイト予算はストア全体のものなので、特定のシャードに追い出しが偏らないようにします。
+func (e *shardedEvictor[K, V]) Evict() (key K, ok bool) {
+	n := uint32(len(e.shards))
+	start := e.next.Add(1)
+	for i := range n {
+		if ce, isCost := e.shards[(start+i)%n].(CostEvictor[K]); isCost {
+			if key, ok = ce.Evict(); ok {
+				return key, true
+			}
+		}
+	}
+	return key, false
+}
+
+// Size は各シャードの Evictor のサイズの合計を返します（Size を持たない Evictor は数えません）。
+func (e *shardedEvictor[K, V]) Size() int {
+	total := 0
+	for _, ev := range e.shards {
+		if sp, ok := ev.(interface{ Size() int }); ok {
+			total += sp.Size()
+		}
+	}
+	return total
+}
+
+// WithShardedEvictor はシャードごとに newEvictor で作成した Evictor を割り当てる、シャード分割のエビクションを設定します。
+// 各 Evictor の容量は capacity をシャード数で割った値（切り上げ）です。
+//
+// WithEvictor に 1 つの Evictor を渡す場合と比べ、読み込みの多い負荷でのロック競合がなくなる代わりに、
+// 追い出しはシャード内で判断されるため、キーの偏りによっては全体が capacity に達する前に追い出しが起こります。
+//
+//	st.WithShardedEvictor(10000, func(c int) store.Evictor[string, string] {
+//		return store.NewLRUEvictor[string, string](c)
+//	})
+func (s *Store[K, V]) WithShardedEvictor(capacity int, newEvictor func(capacity int) Evictor[K, V]) *Store[K, V] {
+	n := s.shardCount()
+	per := max((capacity+n-1)/n, 1)
+	se := &shardedEvictor[K, V]{
+		shards: make([]Evictor[K, V], n),
+		index:  s.shardIndex,
+	}
+	for i := range se.shards {
+		se.shards[i] = newEvictor(per)
+	}
+	return s.WithEvictor(se)
+}
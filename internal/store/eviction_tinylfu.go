@@ -0,0 +1,259 @@
+package store
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math/bits"
+	"sync"
+)
+
+// TinyLFUEvictor は W-TinyLFU 方式のエビクタです。
+//
+// 新規キーはまず小さな window LRU（容量の約 1%）に入り、window から溢れたキーは
+// main 領域（probation / protected の 2 区画 LRU）への参加を申請します。
+// main が満杯の場合は count-min sketch で推定したアクセス頻度を probation の最古キーと比べ、
+// 候補の方が多ければ入れ替え、そうでなければ候補自身を追い出します（アドミッション拒否）。
+// 頻度は容量の 10 倍のアクセスごとに半減させ、過去の人気が残り続けないようにします。
+//
+// 拒否されたキーは OnSet の victims として返るため、今回セットしたキー自身が
+// victims に含まれることがあります（Evictor の契約を参照）。
+type TinyLFUEvictor[K comparable, V any] struct {
+	windowCap    int
+	protectedCap int
+	mainCap      int
+
+	mu        sync.Mutex
+	idx       map[K]*list.Element // *tlfuItem[K]
+	window    *list.List          // Front = 最も古い
+	probation *list.List
+	protected *list.List
+	sketch    *cmSketch
+	seed      maphash.Seed
+}
+
+type tlfuSegment uint8
+
+const (
+	segWindow tlfuSegment = iota
+	segProbation
+	segProtected
+)
+
+type tlfuItem[K comparable] struct {
+	key K
+	seg tlfuSegment
+}
+
+// NewTinyLFUEvictor は新しい TinyLFUEvictor を作成します。
+func NewTinyLFUEvictor[K comparable, V any](capacity int) *TinyLFUEvictor[K, V] {
+	if capacity <= 0 {
+		capacity = 1 // 最低でも1つは保持
+	}
+	windowCap := max(capacity/100, 1)
+	mainCap := capacity - windowCap
+	return &TinyLFUEvictor[K, V]{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap/10*8 + mainCap%10*8/10, // mainCap*8/10（巨大な capacity でも溢れないよう分けて計算）
+		idx:          make(map[K]*list.Element),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		sketch:       newCMSketch(capacity),
+		seed:         maphash.MakeSeed(),
+	}
+}
+
+// Size は現在のサイズを返します。
+func (t *TinyLFUEvictor[K, V]) Size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.idx)
+}
+
+// OnSet はアイテムがセットされたときに呼び出されます。
+func (t *TinyLFUEvictor[K, V]) OnSet(key K, _ V, _ bool) (victims []K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sketch.increment(t.hash(key))
+	if el, ok := t.idx[key]; ok {
+		t.hit(el)
+		return nil
+	}
+
+	t.idx[key] = t.window.PushBack(&tlfuItem[K]{key: key, seg: segWindow})
+	for t.window.Len() > t.windowCap {
+		cand := t.window.Front()
+		t.window.Remove(cand)
+		if v, ok := t.admit(cand.Value.(*tlfuItem[K])); ok {
+			victims = append(victims, v)
+		}
+	}
+	return victims
+}
+
+// admit は window から溢れた候補を main に入れ、追い出したキー（拒否された場合は候補自身）を返します。
+func (t *TinyLFUEvictor[K, V]) admit(cand *tlfuItem[K]) (victim K, evicted bool) {
+	if t.probation.Len()+t.protected.Len() < t.mainCap {
+		cand.seg = segProbation
+		t.idx[cand.key] = t.probation.PushBack(cand)
+		return victim, false
+	}
+	vel := t.probation.Front()
+	if vel == nil {
+		vel = t.protected.Front()
+	}
+	if vel == nil {
+		// main 容量 0（capacity == 1）なら window だけで運用する
+		delete(t.idx, cand.key)
+		return cand.key, true
+	}
+	vic := vel.Value.(*tlfuItem[K])
+	if t.sketch.estimate(t.hash(cand.key)) > t.sketch.estimate(t.hash(vic.key)) {
+		t.segment(vic.seg).Remove(vel)
+		delete(t.idx, vic.key)
+		cand.seg = segProbation
+		t.idx[cand.key] = t.probation.PushBack(cand)
+		return vic.key, true
+	}
+	delete(t.idx, cand.key)
+	return cand.key, true
+}
+
+// Evict は probation → protected → window の順に、最も古いキーを 1 件取り除いて返します（CostEvictor）。
+func (t *TinyLFUEvictor[K, V]) Evict() (key K, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, l := range []*list.List{t.probation, t.protected, t.window} {
+		if el := l.Front(); el != nil {
+			it := el.Value.(*tlfuItem[K])
+			l.Remove(el)
+			delete(t.idx, it.key)
+			return it.key, true
+		}
+	}
+	return key, false
+}
+
+// OnGet はアイテムが取得されたときに呼び出されます。
+// ミスも頻度として記録し、再びセットされたときのアドミッション判定に使います。
+func (t *TinyLFUEvictor[K, V]) OnGet(key K, hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sketch.increment(t.hash(key))
+	if !hit {
+		return
+	}
+	if el, ok := t.idx[key]; ok {
+		t.hit(el)
+	}
+}
+
+// OnDelete はアイテムが削除されたときに呼び出されます。
+func (t *TinyLFUEvictor[K, V]) OnDelete(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.idx[key]; ok {
+		t.segment(el.Value.(*tlfuItem[K]).seg).Remove(el)
+		delete(t.idx, key)
+	}
+}
+
+// hit は常駐キーへのアクセスを反映します。probation のキーは protected に昇格し、
+// protected が溢れたら最古のキーを probation に戻します。
+func (t *TinyLFUEvictor[K, V]) hit(el *list.Element) {
+	it := el.Value.(*tlfuItem[K])
+	switch it.seg {
+	case segWindow:
+		t.window.MoveToBack(el)
+	case segProtected:
+		t.protected.MoveToBack(el)
+	case segProbation:
+		t.probation.Remove(el)
+		it.seg = segProtected
+		t.idx[it.key] = t.protected.PushBack(it)
+		for t.protected.Len() > t.protectedCap {
+			old := t.protected.Front()
+			t.protected.Remove(old)
+			oit := old.Value.(*tlfuItem[K])
+			oit.seg = segProbation
+			t.idx[oit.key] = t.probation.PushBack(oit)
+		}
+	}
+}
+
+func (t *TinyLFUEvictor[K, V]) segment(s tlfuSegment) *list.List {
+	switch s {
+	case segWindow:
+		return t.window
+	case segProbation:
+		return t.probation
+	default:
+		return t.protected
+	}
+}
+
+func (t *TinyLFUEvictor[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(t.seed, key)
+}
+
+// cmSketch は 4 ビット相当（最大 15）のカウンタを持つ count-min sketch です。
+type cmSketch struct {
+	rows      [cmDepth][]uint8
+	shift     uint // 64 - log2(幅)
+	additions int
+	resetAt   int
+}
+
+const cmDepth = 4
+
+// cmMaxWidth は 1 行の幅の上限です（cmDepth 行で 64 MiB）。capacity が巨大でも確保するメモリを抑えます。
+const cmMaxWidth = 1 << 24
+
+// cmSeeds は行ごとのハッシュを作るための奇数の乗数です。
+var cmSeeds = [cmDepth]uint64{0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xc2b2ae3d27d4eb4f}
+
+func newCMSketch(capacity int) *cmSketch {
+	n := min(max(capacity, 16), cmMaxWidth)
+	width := nextPowerOfTwo(n)
+	c := &cmSketch{
+		shift:   uint(64 - bits.TrailingZeros(uint(width))),
+		resetAt: 10 * n,
+	}
+	for i := range c.rows {
+		c.rows[i] = make([]uint8, width)
+	}
+	return c
+}
+
+func (c *cmSketch) increment(h uint64) {
+	for i := range c.rows {
+		j := (h * cmSeeds[i]) >> c.shift
+		if c.rows[i][j] < 15 {
+			c.rows[i][j]++
+		}
+	}
+	c.additions++
+	if c.additions >= c.resetAt {
+		c.reset()
+	}
+}
+
+func (c *cmSketch) estimate(h uint64) uint8 {
+	m := uint8(15)
+	for i := range c.rows {
+		m = min(m, c.rows[i][(h*cmSeeds[i])>>c.shift])
+	}
+	return m
+}
+
+// reset は全カウンタを半減させ、古い頻度の影響を弱めます。
+func (c *cmSketch) reset() {
+	for i := range c.rows {
+		for j := range c.rows[i] {
+			c.rows[i][j] >>= 1
+		}
+	}
+	c.additions /= 2
+}
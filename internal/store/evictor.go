@@ -4,6 +4,8 @@ package store
 type Evictor[K comparable, V any] interface {
 	// keyをセットした（existed: 既存だったか）後に呼ぶ。
 	// 返却 victims は Evictor 内部状態から既に除外済みで、Store 側が map から削除する。
+	// アドミッション制御を行う Evictor は、受け入れを拒否したキーとして今回の key 自身を
+	// victims に含めてよい（Store はセット直後のキーでも他の victims と同様に削除する）。
 	OnSet(key K, value V, existed bool) (victims []K)
 	// Get 成功/失敗で呼ぶ（hit=true ならヒット）
 	OnGet(key K, hit bool)
@@ -0,0 +1,104 @@
+package store
+
+// expiryItem は期限つきのキーを期限順に並べるための要素です。
+type expiryItem[K comparable] struct {
+	at  int64 // entry.expireAt と同じ UnixNano
+	key K
+}
+
+// expiryHeap はシャードごとに期限の近い順にキーを保持する最小ヒープです。
+//
+// 更新や削除のたびにヒープから取り除くことはせず（遅延無効化）、取り出した時点で
+// マップ上のエントリの期限が at と一致するものだけを期限切れとして扱います。
+// 同じキーを何度もセットすると古い要素が残るため、マップに比べて大きくなりすぎたら作り直します。
+type expiryHeap[K comparable] struct {
+	items []expiryItem[K]
+}
+
+func (h *expiryHeap[K]) len() int { return len(h.items) }
+
+func (h *expiryHeap[K]) push(key K, at int64) {
+	h.items = append(h.items, expiryItem[K]{at: at, key: key})
+	h.up(len(h.items) - 1)
+}
+
+// peek は最も期限の近い要素を返します。空なら ok=false。
+func (h *expiryHeap[K]) peek() (it expiryItem[K], ok bool) {
+	if len(h.items) == 0 {
+		return it, false
+	}
+	return h.items[0], true
+}
+
+func (h *expiryHeap[K]) pop() expiryItem[K] {
+	it := h.items[0]
+	last := len(h.items) - 1
+	h.items[0] = h.items[last]
+	h.items[last] = expiryItem[K]{}
+	h.items = h.items[:last]
+	if last > 0 {
+		h.down(0)
+	}
+	return it
+}
+
+// removeAt は i 番目の要素をヒープから取り除きます。
+func (h *expiryHeap[K]) removeAt(i int) {
+	last := len(h.items) - 1
+	if i != last {
+		h.items[i] = h.items[last]
+	}
+	h.items[last] = expiryItem[K]{}
+	h.items = h.items[:last]
+	if i < last {
+		h.down(i)
+		h.up(i)
+	}
+}
+
+// rebuild はマップ上で期限を持つエントリだけからヒープを作り直し、無効な要素を捨てます。
+func (h *expiryHeap[K]) rebuild(items []expiryItem[K]) {
+	h.items = items
+	for i := len(items)/2 - 1; i >= 0; i-- {
+		h.down(i)
+	}
+}
+
+func (h *expiryHeap[K]) up(i int) {
+	for i > 0 {
+		p := (i - 1) / 2
+		if h.items[p].at <= h.items[i].at {
+			return
+		}
+		h.items[p], h.items[i] = h.items[i], h.items[p]
+		i = p
+	}
+}
+
+func (h *expiryHeap[K]) down(i int) {
+	n := len(h.items)
+	for {
+		c := 2*i + 1
+		if c >= n {
+			return
+		}
+		if r := c + 1; r < n && h.items[r].at < h.items[c].at {
+			c = r
+		}
+		if h.items[i].at <= h.items[c].at {
+			return
+		}
+		h.items[i], h.items[c] = h.items[c], h.items[i]
+		i = c
+	}
+}
+
+// expireAdd は期限つきのエントリをシャードの期限ヒープに登録します。シャードロック下で呼びます。
+// バックグラウンドのクリーンアップが無効でもヒープが際限なく大きくならないよう、登録のたびに compactExpiry を試します。
+func (s *Store[K, V]) expireAdd(key K, exp int64) {
+	if exp > 0 {
+		sh := s.shardAt(s.shardIndex(key))
+		sh.exp.push(key, exp)
+		s.compactExpiry(sh)
+	}
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ExpireStrategy はバックグラウンドの TTL クリーンアップの方式を表します。
+type ExpireStrategy string
+
+const (
+	// ExpireHeap は期限ヒープから期限を迎えたキーをすべて（期限順に）削除します（既定）。
+	ExpireHeap ExpireStrategy = "heap"
+	// ExpireSampled は期限つきのキーをランダムにサンプルして削除する、Redis 方式のアクティブ期限切れです。
+	// 期限切れの割合が閾値を超える間だけ繰り返し、1 サイクルの時間予算で打ち切ります。
+	// 期限切れキーが一時的に残ることがある代わりに、1 回のクリーンアップにかかる時間に上限があります。
+	ExpireSampled ExpireStrategy = "sampled"
+)
+
+// ParseExpireStrategy は文字列から ExpireStrategy を解析します。
+func ParseExpireStrategy(s string) (ExpireStrategy, error) {
+	switch p := ExpireStrategy(s); p {
+	case ExpireHeap, ExpireSampled:
+		return p, nil
+	default:
+		return "", fmt.Errorf("store: unknown expire strategy %q", s)
+	}
+}
+
+const (
+	defaultActiveExpireSamples   = 20
+	defaultActiveExpireThreshold = 0.1
+	defaultActiveExpireBudget    = 25 * time.Millisecond
+)
+
+// activeExpireCycle はシャードを順に回り、各シャードで期限切れの割合が閾値以下になるまでサンプルと削除を繰り返します。
+// 時間予算を使い切ったら打ち切り、次のサイクルは続きのシャードから始めます。
+func (s *Store[K, V]) activeExpireCycle() {
+	samples := s.cfg.ActiveExpireSamples
+	if samples <= 0 {
+		samples = defaultActiveExpireSamples
+	}
+	threshold := s.cfg.ActiveExpireThreshold
+	if threshold <= 0 {
+		threshold = defaultActiveExpireThreshold
+	}
+	budget := s.cfg.ActiveExpireBudget
+	if budget <= 0 {
+		budget = defaultActiveExpireBudget
+	}
+
+	start := time.Now()
+	n := s.shardCount()
+	totalExpired := 0
+	defer func() {
+		if totalExpired > 0 {
+			s.cfg.Metrics.AddTTLExpired(totalExpired)
+			if sp, ok := s.evictor.(interface{ Size() int }); ok {
+				s.cfg.Metrics.SetLRUSize(sp.Size())
+			}
+		}
+	}()
+	for range n {
+		i := s.expireCursor % n
+		s.expireCursor++
+		for {
+			sampled, expired := s.sampleExpired(s.shardAt(i), samples)
+			s.cfg.Metrics.AddActiveExpireSampled(sampled)
+			s.cfg.Metrics.AddActiveExpireHits(len(expired))
+			if len(expired) > 0 {
+				totalExpired += len(expired)
+				s.removedAll(expired)
+				if s.evictor != nil {
+					for _, r := range expired {
+						s.evictor.OnDelete(r.key)
+					}
+				}
+			}
+			if time.Since(start) >= budget {
+				s.cfg.Metrics.IncActiveExpireOverrun()
+				if s.cfg.Logger != nil {
+					s.cfg.Logger.Debug("store.ttl.active_expire.overrun", "shard", i, "elapsed", time.Since(start).String())
+				}
+				return
+			}
+			if sampled == 0 || float64(len(expired)) <= threshold*float64(sampled) {
+				break
+			}
+		}
+	}
+}
+
+// sampleExpired は sh の期限つきキーを最大 samples 件ランダムに選び、期限切れのものを削除します。
+// シャードロックはこの 1 回のサンプルの間だけ保持します。
+// 削除や期限の変更で無効になった要素は、見つけたときに期限ヒープから取り除きます。
+// アクセスで期限が延びたエントリ（スライディング期限・max-idle）の要素は、延びた期限に付け替えます。
+func (s *Store[K, V]) sampleExpired(sh *shardCompact[K, V], samples int) (sampled int, expired []removedEntry[K, V]) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	now := s.now().UnixNano()
+	for range samples {
+		if sh.exp.len() == 0 {
+			break
+		}
+		idx := rand.IntN(sh.exp.len())
+		it := sh.exp.items[idx]
+		e, ok := sh.m[it.key]
+		if ok && e.expireAt > it.at && s.extendsOnAccess(e) {
+			sh.exp.removeAt(idx)
+			sh.exp.push(it.key, e.expireAt)
+			sampled++
+			continue
+		}
+		if !ok || e.expireAt != it.at {
+			sh.exp.removeAt(idx)
+			continue
+		}
+		sampled++
+		if !e.expired(now) {
+			continue
+		}
+		sh.exp.removeAt(idx)
+		delete(sh.m, it.key)
+		s.indexRemove(it.key)
+		s.addBytes(-e.cost)
+		s.notifyRemoved(EventExpire, it.key, e.ver)
+		// 失敗は aofError で記録済み。期限切れのキーは削除レコードが欠けても再生後に dropReplayedExpired で取り除かれる
+		_ = s.aofDelete(it.key)
+		expired = append(expired, removedEntry[K, V]{it.key, e.val, Expired})
+	}
+	return sampled, expired
+}
@@ -87,7 +87,7 @@ func FuzzStoreOperations(f *testing.F) {
 
 			switch op {
 			case opSet:
-				st.Set(key, val)
+				_ = st.Set(key, val)
 				me := model[key]
 				if me == nil {
 					me = &modelEntry{}
@@ -101,7 +101,7 @@ func FuzzStoreOperations(f *testing.F) {
 				// TTLを1～5msに限定し、遅延削除が発生するよう適度に短く
 				ttlMs := int(flag%5) + 1
 				ttl := time.Duration(ttlMs) * time.Millisecond
-				st.SetWithTTL(key, val, ttl)
+				_ = st.SetWithTTL(key, val, ttl)
 				me := model[key]
 				if me == nil {
 					me = &modelEntry{}
@@ -137,7 +137,7 @@ func FuzzStoreOperations(f *testing.F) {
 					}
 				}
 			case opDelete:
-				st.Delete(key)
+				_ = st.Delete(key)
 				if me := model[key]; me != nil {
 					me.deleted = true
 				}
@@ -181,7 +181,7 @@ func FuzzStoreConcurrent(f *testing.F) {
 		keys := make([]string, nKeys)
 		for i := range nKeys {
 			keys[i] = fmt.Sprintf("ck%02d", i)
-			st.Set(keys[i], "init")
+			_ = st.Set(keys[i], "init")
 		}
 		workers := int(data[1]%8) + 2
 		var seedBuf [8]byte
@@ -201,13 +201,13 @@ func FuzzStoreConcurrent(f *testing.F) {
 					k := keys[r.Intn(len(keys))]
 					switch r.Intn(4) {
 					case 0:
-						st.Set(k, "v")
+						_ = st.Set(k, "v")
 					case 1:
-						st.SetWithTTL(k, "vt", time.Duration(r.Intn(3)+1)*time.Millisecond)
+						_ = st.SetWithTTL(k, "vt", time.Duration(r.Intn(3)+1)*time.Millisecond)
 					case 2:
 						st.Get(k)
 					case 3:
-						st.Delete(k)
+						_ = st.Delete(k)
 					}
 				}
 			}(int64(w))
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotInteger は値が整数として解釈できない場合のエラーです。
+	ErrNotInteger = errors.New("store: value is not an integer")
+	// ErrNotFloat は値が浮動小数点数として解釈できない場合のエラーです。
+	ErrNotFloat = errors.New("store: value is not a valid float")
+	// ErrOverflow は加算結果がオーバーフロー (または NaN/Inf) になる場合のエラーです。
+	ErrOverflow = errors.New("store: increment would overflow")
+)
+
+// Incr はキーの整数値に delta を加算し、加算後の値を返します。
+// キーが存在しない（期限切れを含む）場合は 0 として作成し、ttl はその作成時にのみ適用します。
+// 既存キーの期限は維持されます。値の型が整数として扱えない場合は ErrNotInteger を返します。
+func (s *Store[K, V]) Incr(key K, delta int64, ttl time.Duration) (int64, error) {
+	var result int64
+	err := s.update(key, ttl, func(cur V, live bool) (V, error) {
+		var n int64
+		if live {
+			var ok bool
+			if n, ok = toInt64(cur); !ok {
+				return cur, ErrNotInteger
+			}
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return cur, ErrOverflow
+		}
+		result = n + delta
+		v, ok := fromInt64[V](result)
+		if !ok {
+			return cur, ErrNotInteger
+		}
+		return v, nil
+	})
+	return result, err
+}
+
+// IncrByFloat はキーの数値に浮動小数点数 delta を加算し、加算後の値を返します。
+// キーの作成と ttl の扱いは Incr と同じです。
+func (s *Store[K, V]) IncrByFloat(key K, delta float64, ttl time.Duration) (float64, error) {
+	var result float64
+	err := s.update(key, ttl, func(cur V, live bool) (V, error) {
+		var f float64
+		if live {
+			var ok bool
+			if f, ok = toFloat64(cur); !ok {
+				return cur, ErrNotFloat
+			}
+		}
+		result = f + delta
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return cur, ErrOverflow
+		}
+		v, ok := fromFloat64[V](result)
+		if !ok {
+			return cur, ErrNotFloat
+		}
+		return v, nil
+	})
+	return result, err
+}
+
+// update はシャードロック下で現在値から新しい値を計算してセットします。
+// 新規作成時のみ ttl を適用し、既存キーは期限を引き継ぎます。
+func (s *Store[K, V]) update(key K, ttl time.Duration, fn func(cur V, live bool) (V, error)) error {
+	now := s.now()
+	mu, mp := s.getShard(key)
+	unlockKey := s.lockKeys(key)
+	mu.Lock()
+	cur, existed := mp[key]
+	live := existed && !cur.expired(now.UnixNano())
+	next, err := fn(cur.val, live)
+	if err != nil {
+		mu.Unlock()
+		unlockKey()
+		return err
+	}
+	cost := s.costOf(key, next)
+	if err := s.checkCost(cost); err != nil {
+		mu.Unlock()
+		unlockKey()
+		return err
+	}
+	e := entry[V]{val: next, deadline: cur.deadline, cost: cost, ttl: cur.ttl, sliding: cur.sliding}
+	if !live {
+		e.deadline, e.ttl, e.sliding = 0, 0, false
+		if ttl > 0 {
+			e.deadline, e.ttl = now.Add(ttl).UnixNano(), ttl
+		}
+	}
+	// 既存キーへの書き込みもアクセスとして期限を延ばす（スライディング期限・max-idle）
+	e.expireAt = s.accessExpireAt(e, now.UnixNano())
+	if err := s.backendWrite(mu, BackendWrite[K, V]{Key: key, Value: next}); err != nil {
+		mu.Unlock()
+		unlockKey()
+		return err
+	}
+	cur, existed = mp[key] // Backend への書き込み中に TTL や Evict で消えていることがある
+	ver := s.nextVersion()
+	e.ver = ver
+	mp[key] = e
+	s.addBytes(cost - cur.cost)
+	if e.expireAt != cur.expireAt {
+		s.expireAdd(key, e.expireAt)
+	}
+	s.indexAdd(key)
+	s.notify(setEventType(live), key, next, ver)
+	aofErr := s.aofSet(key, next, e.persistedExpireAt(), ver)
+	mu.Unlock()
+	unlockKey()
+
+	if existed {
+		s.removed(key, cur.val, removalReason(cur, now.UnixNano(), Replaced))
+	}
+	s.afterSet(key, next, existed)
+	return aofErr
+}
+
+func toInt64[V any](v V) (int64, bool) {
+	switch x := any(v).(type) {
+	case string:
+		n, err := strconv.ParseInt(x, 10, 64)
+		return n, err == nil
+	case []byte:
+		n, err := strconv.ParseInt(string(x), 10, 64)
+		return n, err == nil
+	case int:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	default:
+		return 0, false
+	}
+}
+
+func fromInt64[V any](n int64) (V, bool) {
+	var v V
+	switch p := any(&v).(type) {
+	case *string:
+		*p = strconv.FormatInt(n, 10)
+	case *[]byte:
+		*p = strconv.AppendInt(nil, n, 10)
+	case *int:
+		if n < math.MinInt || n > math.MaxInt {
+			return v, false
+		}
+		*p = int(n)
+	case *int32:
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return v, false
+		}
+		*p = int32(n)
+	case *int64:
+		*p = n
+	default:
+		return v, false
+	}
+	return v, true
+}
+
+func toFloat64[V any](v V) (float64, bool) {
+	switch x := any(v).(type) {
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	case []byte:
+		f, err := strconv.ParseFloat(string(x), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	default:
+		if n, ok := toInt64(v); ok {
+			return float64(n), true
+		}
+		return 0, false
+	}
+}
+
+func fromFloat64[V any](f float64) (V, bool) {
+	var v V
+	switch p := any(&v).(type) {
+	case *string:
+		*p = strconv.FormatFloat(f, 'f', -1, 64)
+	case *[]byte:
+		*p = strconv.AppendFloat(nil, f, 'f', -1, 64)
+	case *float64:
+		*p = f
+	case *float32:
+		*p = float32(f)
+	default:
+		return v, false
+	}
+	return v, true
+}
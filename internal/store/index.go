@@ -0,0 +1,212 @@
+package store
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+const (
+	indexMaxLevel = 24 // 1/4 の昇格確率で 2^48 件程度まで十分
+	indexBranch   = 4
+)
+
+// orderedIndex はキーを順序付きで保持するスキップリストです。
+// シャードマップと並行して維持し、更新はシャードのロックを保持したまま行います
+// （ロック順序: シャード → 索引）。索引のロックを保持したままシャードをロックしてはいけません。
+type orderedIndex[K comparable] struct {
+	mu    sync.RWMutex
+	cmp   func(a, b K) int
+	head  *indexNode[K] // 番兵
+	tail  *indexNode[K] // nil なら空
+	level int
+	n     int
+}
+
+type indexNode[K comparable] struct {
+	key  K
+	prev *indexNode[K] // レベル 0 の逆方向リンク（先頭要素は nil）
+	next []*indexNode[K]
+}
+
+func newOrderedIndex[K comparable](cmp func(a, b K) int) *orderedIndex[K] {
+	return &orderedIndex[K]{
+		cmp:   cmp,
+		head:  &indexNode[K]{next: make([]*indexNode[K], indexMaxLevel)},
+		level: 1,
+	}
+}
+
+func (x *orderedIndex[K]) randomLevel() int {
+	lvl := 1
+	for lvl < indexMaxLevel && rand.IntN(indexBranch) == 0 {
+		lvl++
+	}
+	return lvl
+}
+
+// findPrev は各レベルで key 未満の最後のノードを update に格納します。
+func (x *orderedIndex[K]) findPrev(key K, update *[indexMaxLevel]*indexNode[K]) *indexNode[K] {
+	n := x.head
+	for i := x.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && x.cmp(n.next[i].key, key) < 0 {
+			n = n.next[i]
+		}
+		if update != nil {
+			update[i] = n
+		}
+	}
+	return n
+}
+
+// insert はキーを追加します。既に存在する場合は何もしません。
+func (x *orderedIndex[K]) insert(key K) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var update [indexMaxLevel]*indexNode[K]
+	p := x.findPrev(key, &update)
+	if nx := p.next[0]; nx != nil && x.cmp(nx.key, key) == 0 {
+		return
+	}
+	lvl := x.randomLevel()
+	for i := x.level; i < lvl; i++ {
+		update[i] = x.head
+	}
+	if lvl > x.level {
+		x.level = lvl
+	}
+	n := &indexNode[K]{key: key, next: make([]*indexNode[K], lvl)}
+	for i := 0; i < lvl; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+	if p != x.head {
+		n.prev = p
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n
+	} else {
+		x.tail = n
+	}
+	x.n++
+}
+
+// remove はキーを削除します。存在しない場合は何もしません。
+func (x *orderedIndex[K]) remove(key K) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var update [indexMaxLevel]*indexNode[K]
+	p := x.findPrev(key, &update)
+	n := p.next[0]
+	if n == nil || x.cmp(n.key, key) != 0 {
+		return
+	}
+	for i := range n.next {
+		update[i].next[i] = n.next[i]
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n.prev
+	} else {
+		x.tail = n.prev
+	}
+	for x.level > 1 && x.head.next[x.level-1] == nil {
+		x.level--
+	}
+	x.n--
+}
+
+func (x *orderedIndex[K]) len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.n
+}
+
+// indexBounds は走査範囲です。下限は loIncl に応じて含む/含まない、上限は常に含みません。
+type indexBounds[K comparable] struct {
+	lo, hi       K
+	hasLo, hasHi bool
+	loIncl       bool
+}
+
+// keys は範囲内のキーを昇順（reverse なら降順）で最大 n 件返します。
+func (x *orderedIndex[K]) keys(b indexBounds[K], reverse bool, n int) []K {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	out := make([]K, 0, min(n, x.n))
+	if reverse {
+		node := x.tail
+		if b.hasHi {
+			if p := x.findPrev(b.hi, nil); p != x.head {
+				node = p
+			} else {
+				node = nil
+			}
+		}
+		for ; node != nil && len(out) < n; node = node.prev {
+			if b.hasLo {
+				c := x.cmp(node.key, b.lo)
+				if c < 0 || (c == 0 && !b.loIncl) {
+					break
+				}
+			}
+			out = append(out, node.key)
+		}
+		return out
+	}
+
+	node := x.head.next[0]
+	if b.hasLo {
+		node = x.findPrev(b.lo, nil).next[0]
+		if node != nil && !b.loIncl && x.cmp(node.key, b.lo) == 0 {
+			node = node.next[0]
+		}
+	}
+	for ; node != nil && len(out) < n; node = node.next[0] {
+		if b.hasHi && x.cmp(node.key, b.hi) >= 0 {
+			break
+		}
+		out = append(out, node.key)
+	}
+	return out
+}
+
+// orderedCompare はキー型に応じた比較関数を返します。
+// 順序型以外のキーは文字列表現（fmt.Sprint）の辞書順で比較します。
+func orderedCompare[K comparable]() func(a, b K) int {
+	var zero K
+	switch any(zero).(type) {
+	case string:
+		return compareAs[string, K]
+	case int:
+		return compareAs[int, K]
+	case int8:
+		return compareAs[int8, K]
+	case int16:
+		return compareAs[int16, K]
+	case int32:
+		return compareAs[int32, K]
+	case int64:
+		return compareAs[int64, K]
+	case uint:
+		return compareAs[uint, K]
+	case uint8:
+		return compareAs[uint8, K]
+	case uint16:
+		return compareAs[uint16, K]
+	case uint32:
+		return compareAs[uint32, K]
+	case uint64:
+		return compareAs[uint64, K]
+	case float32:
+		return compareAs[float32, K]
+	case float64:
+		return compareAs[float64, K]
+	default:
+		return func(a, b K) int { return strings.Compare(keyString(a), keyString(b)) }
+	}
+}
+
+func compareAs[T cmp.Ordered, K comparable](a, b K) int {
+	return cmp.Compare(any(a).(T), any(b).(T))
+}
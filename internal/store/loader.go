@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errLoaderPanicked = errors.New("store: loader panicked")
+
+// LoaderFunc はキャッシュミス時に値を読み込む関数です。
+// 返した ttl でストアにセットされます（0 以下なら無期限）。
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (value V, ttl time.Duration, err error)
+
+// loadCall は読み込み中の 1 キー分の呼び出しです。同じキーの後続の呼び出しは done を待ちます。
+type loadCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// negativeEntry はローダーのエラーを一時的に覚えておくためのエントリです。
+type negativeEntry struct {
+	err      error
+	expireAt int64
+}
+
+// loadGroup は同じキーへの同時の読み込みを 1 回にまとめ（singleflight）、ローダーのエラーを負のキャッシュに保持します。
+type loadGroup[K comparable, V any] struct {
+	mu       sync.Mutex
+	calls    map[K]*loadCall[V]
+	negative map[K]negativeEntry
+}
+
+func newLoadGroup[K comparable, V any]() *loadGroup[K, V] {
+	return &loadGroup[K, V]{
+		calls:    make(map[K]*loadCall[V]),
+		negative: make(map[K]negativeEntry),
+	}
+}
+
+// cachedError は期限内の負のキャッシュがあればそのエラーを返します。
+func (g *loadGroup[K, V]) cachedError(key K, now int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ne, ok := g.negative[key]
+	if !ok {
+		return nil
+	}
+	if ne.expireAt <= now {
+		delete(g.negative, key)
+		return nil
+	}
+	return ne.err
+}
+
+// forget は負のキャッシュを消します（キーがセットされた場合など）。
+func (g *loadGroup[K, V]) forget(key K) {
+	g.mu.Lock()
+	delete(g.negative, key)
+	g.mu.Unlock()
+}
+
+// purge は期限切れの負のキャッシュを削除します。
+func (g *loadGroup[K, V]) purge(now int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for k, ne := range g.negative {
+		if ne.expireAt <= now {
+			delete(g.negative, k)
+		}
+	}
+}
+
+// purgeNegative は期限切れの負のキャッシュを削除します（クリーンアップのたびに呼ばれます）。
+func (s *Store[K, V]) purgeNegative() {
+	if s.cfg.NegativeTTL > 0 {
+		s.loads.purge(s.now().UnixNano())
+	}
+}
+
+// GetOrLoad はキーの値を返し、存在しなければ loader で読み込んでセットします。
+// 同じキーへの同時のミスは 1 回の loader 呼び出しにまとめられ、待機中の呼び出しは同じ結果を受け取ります。
+// loader は最初にミスした呼び出しの ctx で実行されます。待機中の呼び出しは自身の ctx が終了すると
+// ctx.Err() を返しますが、読み込み自体は続きます。
+//
+// loader に nil を渡すと WithLoader で設定したローダーを使い、それもなければ ErrNotFound を返します。
+// WithNegativeTTL を設定すると、loader のエラーはその期間キャッシュされ、再度 loader を呼ばずに返されます。
+// 読み込み中に他の呼び出しがキーをセットした場合はそちらを優先し、読み込んだ値では上書きせずにセットされた値を返します。
+func (s *Store[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	if e, ok := s.get(key); ok {
+		return e.val, nil
+	}
+	if loader == nil {
+		loader = s.loader
+	}
+	if loader == nil {
+		var zero V
+		return zero, ErrNotFound
+	}
+	return s.load(ctx, key, loader)
+}
+
+// GetItemOrLoad は GetItem と同様に値とメタデータを返し、存在しなければ WithLoader で設定したローダーで読み込んでセットします。
+// ローダーがなければ ErrNotFound を返します。読み込んだ値がセット直後に追い出された場合などは Version をゼロ値で返します。
+func (s *Store[K, V]) GetItemOrLoad(ctx context.Context, key K) (Item[K, V], error) {
+	if e, ok := s.get(key); ok {
+		return newItem(key, e), nil
+	}
+	if s.loader == nil {
+		return Item[K, V]{}, ErrNotFound
+	}
+	v, err := s.load(ctx, key, s.loader)
+	if err != nil {
+		return Item[K, V]{}, err
+	}
+	mu, mp := s.getShard(key)
+	mu.RLock()
+	cur, ok := mp[key]
+	mu.RUnlock()
+	if ok && !cur.expired(s.now().UnixNano()) {
+		return newItem(key, cur), nil
+	}
+	return Item[K, V]{Key: key, Value: v}, nil
+}
+
+func (s *Store[K, V]) load(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	g := s.loads
+	if err := g.cachedError(key, s.now().UnixNano()); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.val, c.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	c := &loadCall[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			// loader が panic した: 待機中の呼び出しにはエラーとして伝える
+			c.err = errLoaderPanicked
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	start := time.Now()
+	v, ttl, err := loader(ctx, key)
+	completed = true
+	s.cfg.Metrics.IncLoad()
+	s.cfg.Metrics.ObserveLoadDuration(time.Since(start))
+	if err != nil {
+		s.cfg.Metrics.IncLoadError()
+		if s.cfg.NegativeTTL > 0 && ctx.Err() == nil {
+			g.mu.Lock()
+			g.negative[key] = negativeEntry{err: err, expireAt: s.now().Add(s.cfg.NegativeTTL).UnixNano()}
+			g.mu.Unlock()
+		}
+		if s.cfg.Logger != nil {
+			s.cfg.Logger.Debug("store.load.error", "key", key, "err", err)
+		}
+		c.err = err
+		var zero V
+		return zero, err
+	}
+
+	c.val = v
+	// 読み込み中にセットされた値があればそちらを優先する（作成専用でセット）。Backend には書き戻さない
+	_, err = s.compareAndSwap(key, 0, v, ttl, false, false)
+	if errors.Is(err, ErrVersionMismatch) {
+		// 先にセットされた値を読み直し、自分と待機中の呼び出しにはそちらを返す
+		mu, mp := s.getShard(key)
+		mu.RLock()
+		if cur, ok := mp[key]; ok && !cur.expired(s.now().UnixNano()) {
+			c.val = cur.val
+		}
+		mu.RUnlock()
+	}
+	if err != nil && s.cfg.Logger != nil {
+		s.cfg.Logger.Debug("store.load.skip", "key", key, "err", err)
+	}
+	return c.val, nil
+}
@@ -1,43 +1,114 @@
 package store
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Set はキーと値をストアにセットします。
-func (s *Store[K, V]) Set(key K, value V) {
-	s.SetWithTTL(key, value, 0)
+// 値だけで WithMaxBytes の予算を超える場合はセットせず ErrValueTooLarge を返します。
+func (s *Store[K, V]) Set(key K, value V) error {
+	return s.SetWithTTL(key, value, 0)
 }
 
 // SetWithTTL はキーと値をストアにセットします。
-func (s *Store[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+// 値だけで WithMaxBytes の予算を超える場合はセットせず ErrValueTooLarge を返します。
+func (s *Store[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	var exp int64
 	if ttl > 0 {
-		exp = time.Now().Add(ttl).UnixNano()
+		exp = s.now().Add(ttl).UnixNano()
+	}
+	existed, _, err := s.setEntry(key, value, exp, max(ttl, 0), false, 0)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.Logger != nil {
+		if existed {
+			s.cfg.Logger.Debug("store.update", "key", key)
+		} else {
+			s.cfg.Logger.Debug("store.set", "key", key, "ttl", ttl.String())
+		}
+	}
+	return nil
+}
+
+// SetVersioned は SetWithTTL と同様にセットし、新しいバージョンを返します。
+func (s *Store[K, V]) SetVersioned(key K, value V, ttl time.Duration) (uint64, error) {
+	var exp int64
+	if ttl > 0 {
+		exp = s.now().Add(ttl).UnixNano()
+	}
+	_, ver, err := s.setEntry(key, value, exp, max(ttl, 0), false, 0)
+	return ver, err
+}
+
+// setEntry は絶対期限 (UnixNano, 0=なし) を指定してセットし、メトリクス/Evictor を更新します。
+// ttl は Touch とスライディング期限 (sliding) 用に保持します。実効期限は accessExpireAt で決めます。
+// ver が 0 なら新しいバージョンを採番して Backend にも書き込み、それ以外（復元時）はその値を引き継ぎます。
+func (s *Store[K, V]) setEntry(key K, value V, deadline int64, ttl time.Duration, sliding bool, ver uint64) (existed bool, newVer uint64, err error) {
+	cost := s.costOf(key, value)
+	if err := s.checkCost(cost); err != nil {
+		return false, 0, err
 	}
 	mu, mp := s.getShard(key)
+	unlockKey := s.lockKeys(key)
 	mu.Lock()
-	_, existed := mp[key]
-	mp[key] = entry[V]{val: value, expireAt: exp}
+	if ver == 0 {
+		if err := s.backendWrite(mu, BackendWrite[K, V]{Key: key, Value: value}); err != nil {
+			mu.Unlock()
+			unlockKey()
+			return false, 0, err
+		}
+	}
+	cur, existed := mp[key]
+	if ver == 0 {
+		ver = s.nextVersion()
+	} else {
+		s.observeVersion(ver)
+	}
+	now := s.now().UnixNano()
+	e := entry[V]{val: value, deadline: deadline, ver: ver, cost: cost, ttl: ttl, sliding: sliding}
+	e.expireAt = s.accessExpireAt(e, now)
+	mp[key] = e
+	s.addBytes(cost - cur.cost)
+	s.expireAdd(key, e.expireAt)
+	s.indexAdd(key)
+	s.notify(setEventType(existed && !cur.expired(now)), key, value, ver)
+	aofErr := s.aofSet(key, value, e.persistedExpireAt(), ver)
 	mu.Unlock()
+	unlockKey()
 
+	if existed {
+		s.removed(key, cur.val, removalReason(cur, now, Replaced))
+	}
+	s.afterSet(key, value, existed)
+	return existed, ver, aofErr
+}
+
+// afterSet はシャードロック解放後にメトリクス/Evictor を更新します。
+func (s *Store[K, V]) afterSet(key K, value V, existed bool) {
 	if existed {
 		s.cfg.Metrics.IncSetUpdate()
 	} else {
 		s.cfg.Metrics.IncSetNew()
 	}
-
-	if s.cfg.Logger != nil {
-		if existed {
-			s.cfg.Logger.Debug("store.update", "key", key)
-		} else {
-			s.cfg.Logger.Debug("store.set", "key", key, "ttl", ttl.String())
-		}
+	if s.cfg.NegativeTTL > 0 {
+		s.loads.forget(key)
 	}
 
 	if s.evictor != nil {
 		victims := s.evictor.OnSet(key, value, existed)
 		for _, vk := range victims {
-			s.deleteInternal(vk, true)
+			// vk == key ならアドミッション拒否（セットしたキー自身を削除する）
+			if vk == key && s.cfg.Logger != nil {
+				s.cfg.Logger.Debug("store.evict.rejected", "key", key)
+			}
+			// 追い出しは AOF への記録に失敗しても取り消さない（失敗は aofError で記録済み。再起動後に残っても evictor が上限内に収める）
+			_ = s.deleteInternal(vk, true)
 		}
+		// バイト予算を超えていれば、大きな値 1 件に対して複数件を追い出す
+		victims = append(victims, s.shrinkToMaxBytes()...)
 		if len(victims) > 0 {
 			s.cfg.Metrics.AddEvicted(len(victims))
 			if s.cfg.Logger != nil {
@@ -51,7 +122,33 @@ func (s *Store[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
 }
 
 // Get はキーに対応する値を取得します。
+// WithLoader でローダーを設定している場合、ミスしたキーはローダーで読み込みます（GetOrLoad と同じ）。
+// 読み込みに失敗した場合は見つからなかったものとして扱います。
 func (s *Store[K, V]) Get(key K) (V, bool) {
+	e, ok := s.get(key)
+	if !ok && s.loader != nil {
+		v, err := s.load(context.Background(), key, s.loader)
+		return v, err == nil
+	}
+	return e.val, ok
+}
+
+// GetItem はキーに対応する値とメタデータ（バージョン・期限）を取得します。
+func (s *Store[K, V]) GetItem(key K) (Item[K, V], bool) {
+	e, ok := s.get(key)
+	if !ok {
+		return Item[K, V]{}, false
+	}
+	return newItem(key, e), true
+}
+
+// GetVersioned はキーに対応する値とバージョンを取得します。
+func (s *Store[K, V]) GetVersioned(key K) (V, uint64, bool) {
+	e, ok := s.get(key)
+	return e.val, e.ver, ok
+}
+
+func (s *Store[K, V]) get(key K) (entry[V], bool) {
 	mu, mp := s.getShard(key)
 	mu.RLock()
 	e, exists := mp[key]
@@ -61,18 +158,27 @@ func (s *Store[K, V]) Get(key K) (V, bool) {
 		if s.evictor != nil {
 			s.evictor.OnGet(key, false)
 		}
-		var zero V
-		return zero, false
+		return entry[V]{}, false
 	}
-	if e.expireAt > 0 && e.expireAt <= time.Now().UnixNano() {
+	now := s.now().UnixNano()
+	if e.expired(now) {
 		// 遅延削除
 		mu.Lock()
-		// 期限内に他ゴルーチンが更新しているか再確認
+		// 他ゴルーチンが更新・期限変更・アクセスによる延長をしていないか再確認
 		cur, still := mp[key]
-		if still && cur.expireAt == e.expireAt {
+		removed := still && cur.ver == e.ver && cur.expired(now)
+		if removed {
 			delete(mp, key)
+			s.indexRemove(key)
+			s.addBytes(-cur.cost)
+			s.notifyRemoved(EventExpire, key, cur.ver)
+			// 失敗は aofError で記録済み。期限切れのキーは削除レコードが欠けても再生後に dropReplayedExpired で取り除かれる
+			_ = s.aofDelete(key)
 		}
 		mu.Unlock()
+		if removed {
+			s.removed(key, cur.val, Expired)
+		}
 		if s.evictor != nil {
 			s.evictor.OnDelete(key)
 			if sp, ok := s.evictor.(interface{ Size() int }); ok {
@@ -84,30 +190,70 @@ func (s *Store[K, V]) Get(key K) (V, bool) {
 		if s.cfg.Logger != nil {
 			s.cfg.Logger.Debug("store.ttl.expired", "key", key)
 		}
-		var zero V
-		return zero, false
+		return entry[V]{}, false
+	}
+	if s.extendsOnAccess(e) {
+		e = s.extendOnAccess(mu, mp, key, e, now)
 	}
 	s.cfg.Metrics.IncGetHit()
 	if s.evictor != nil {
 		s.evictor.OnGet(key, true)
 	}
-	return e.val, true
+	return e, true
 }
 
 // Delete はキーに対応する値を削除します。
-func (s *Store[K, V]) Delete(key K) {
-	s.deleteInternal(key, false)
+// Backend を設定している場合、ストアにないキーも Backend からは削除します。
+// write-through で Backend の削除に失敗した場合は ErrBackend を返し、ストアからも削除しません。
+func (s *Store[K, V]) Delete(key K) error {
+	return s.deleteInternal(key, false)
 }
 
-func (s *Store[K, V]) deleteInternal(key K, fromEviction bool) {
+// deleteInternal はキーを削除します。Evict による削除 (fromEviction) は Backend に反映しません。
+func (s *Store[K, V]) deleteInternal(key K, fromEviction bool) error {
 	mu, mp := s.getShard(key)
+	unlockKey := func() {}
+	if !fromEviction {
+		unlockKey = s.lockKeys(key)
+	}
 	mu.Lock()
-	_, existed := mp[key]
+	if !fromEviction {
+		if err := s.backendWrite(mu, BackendWrite[K, V]{Key: key, Delete: true}); err != nil {
+			mu.Unlock()
+			unlockKey()
+			return err
+		}
+	}
+	cur, existed := mp[key]
+	now := s.now().UnixNano()
+	var aofErr error
 	if existed {
 		delete(mp, key)
+		s.indexRemove(key)
+		s.addBytes(-cur.cost)
+		if fromEviction {
+			s.notifyRemoved(EventEvict, key, cur.ver)
+		} else {
+			s.notifyRemoved(EventDelete, key, cur.ver)
+		}
+		aofErr = s.aofDelete(key)
 	}
 	mu.Unlock()
-	if existed && s.evictor != nil && !fromEviction {
+	unlockKey()
+	if existed {
+		reason := Deleted
+		if fromEviction {
+			reason = Evicted
+		}
+		s.removed(key, cur.val, removalReason(cur, now, reason))
+		s.afterDelete(key, fromEviction)
+	}
+	return aofErr
+}
+
+// afterDelete はシャードロック解放後に Evictor を更新します。
+func (s *Store[K, V]) afterDelete(key K, fromEviction bool) {
+	if s.evictor != nil && !fromEviction {
 		s.evictor.OnDelete(key)
 		if sp, ok := s.evictor.(interface{ Size() int }); ok {
 			s.cfg.Metrics.SetLRUSize(sp.Size())
@@ -117,7 +263,7 @@ func (s *Store[K, V]) deleteInternal(key K, fromEviction bool) {
 
 // Len はストア内のアイテム数を返します。
 func (s *Store[K, V]) Len() int {
-	now := time.Now().UnixNano()
+	now := s.now().UnixNano()
 	total := 0
 	if s.cfg.EnableShardPadding {
 		for i := range s.shardsPadded {
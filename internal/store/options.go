@@ -3,6 +3,7 @@ package store
 import (
 	"time"
 
+	"github.com/amakane-hakari/kavos/internal/clock"
 	"github.com/amakane-hakari/kavos/internal/metrics"
 )
 
@@ -14,11 +15,39 @@ type logLike interface {
 
 // Config はストアの設定を表します。
 type Config struct {
-	Shards             int           // 2 の冪推奨。0/未指定なら 16
-	CleanupInterval    time.Duration // 0 で無効
-	Logger             logLike
-	Metrics            metrics.Interface
-	EnableShardPadding bool // シャードのパディングを有効にする
+	Shards          int            // 2 の冪推奨。0/未指定なら 16
+	CleanupInterval time.Duration  // 0 で無効
+	ExpireStrategy  ExpireStrategy // クリーンアップの方式。未指定なら ExpireHeap
+	Clock           clock.Clock    // 期限の計算とクリーンアップに使う時計。未指定なら clock.Real
+	MaxIdle         time.Duration  // アクセスのないまま経過するとキーを期限切れにする時間。0 で無効
+
+	ActiveExpireSamples   int           // ExpireSampled: 1 回にサンプルするキー数。0 なら 20
+	ActiveExpireThreshold float64       // ExpireSampled: 期限切れの割合がこれを超える間繰り返す。0 なら 0.1
+	ActiveExpireBudget    time.Duration // ExpireSampled: 1 サイクルの時間予算。0 なら 25ms
+	Logger                logLike
+	Metrics               metrics.Interface
+	EnableShardPadding    bool  // シャードのパディングを有効にする
+	OrderedIndex          bool  // キー順の索引を維持する (Range 用)
+	WatchBuffer           int   // Watch の購読者ごとのバッファ。0 なら 256
+	WatchHistory          int   // WatchFrom で再開できるよう保持するイベント数。0 で無効
+	MaxBytes              int64 // エントリのコスト合計の上限。0 で無効
+	Cost                  any   // func(K, V) int64。WithCost で設定する
+	OnRemove              any   // func(K, V, RemovalReason)。WithOnRemove で設定する
+
+	Loader      any           // LoaderFunc[K, V]。WithLoader で設定する
+	NegativeTTL time.Duration // ローダーのエラーをキャッシュする期間。0 で無効
+
+	Backend             any           // Backend[K, V]。WithWriteThrough / WithWriteBehind で設定する
+	WriteMode           WriteMode     // Backend への書き込み方式
+	WriteBehindInterval time.Duration // write-behind の反映間隔。0 なら 1s
+	WriteBehindBatch    int           // write-behind の 1 回の BatchStore の最大件数。0 なら 256
+	WriteBehindRetries  int           // write-behind の失敗時の再試行回数。0 なら 3
+	WriteBehindBackoff  time.Duration // write-behind の再試行の初回待ち時間 (以後倍々)。0 なら 100ms
+
+	AOFPath           string      // 空で AOF 無効
+	AOFFsync          FsyncPolicy // 未指定なら everysec
+	AOFRewritePercent int         // 前回 rewrite 後のサイズからの増加率 (%) で自動 rewrite。0 で無効
+	AOFRewriteMinSize int64       // 自動 rewrite を行う最小ファイルサイズ (bytes)
 }
 
 // Option はストアのオプションを設定する関数です。
@@ -34,6 +63,12 @@ func WithMetrics(m metrics.Interface) Option {
 	return func(c *Config) { c.Metrics = m }
 }
 
+// WithClock は期限の計算と TTL クリーンアップのティッカーに使う Clock を設定するオプションです。
+// テストでは clock/fake の Clock を渡すと、Advance で期限切れとクリーンアップを再現できます。
+func WithClock(clk clock.Clock) Option {
+	return func(c *Config) { c.Clock = clk }
+}
+
 // WithShards はストアのシャード数を設定するオプションです。
 func WithShards(n int) Option {
 	return func(c *Config) { c.Shards = n }
@@ -44,7 +79,143 @@ func WithCleanupInterval(d time.Duration) Option {
 	return func(c *Config) { c.CleanupInterval = d }
 }
 
+// WithExpireStrategy は TTL クリーンアップの方式を設定するオプションです。
+func WithExpireStrategy(st ExpireStrategy) Option {
+	return func(c *Config) { c.ExpireStrategy = st }
+}
+
+// WithActiveExpire はサンプル方式 (ExpireSampled) のアクティブ期限切れを有効にするオプションです。
+// 各シャードで samples 件ずつサンプルし、期限切れの割合が threshold を超える間繰り返します。
+// 1 サイクル（クリーンアップ間隔ごと）の処理は budget で打ち切ります。0 を指定した値は既定値を使います。
+func WithActiveExpire(samples int, threshold float64, budget time.Duration) Option {
+	return func(c *Config) {
+		c.ExpireStrategy = ExpireSampled
+		c.ActiveExpireSamples = samples
+		c.ActiveExpireThreshold = threshold
+		c.ActiveExpireBudget = budget
+	}
+}
+
+// WithMaxIdle はアクセス（Get・書き込み・Touch）のないまま d が経過したキーを期限切れにするオプションです。
+// TTL を指定したキーは、TTL とアイドル時間のどちらか早い方で期限切れになります。
+// アクセスによる延長は AOF・スナップショットに記録しないため、復元したキーのアイドル時間は復元した時点から数えます。
+func WithMaxIdle(d time.Duration) Option {
+	return func(c *Config) { c.MaxIdle = d }
+}
+
 // WithShardPadding はストアのシャードパディングを有効にするオプションです。
 func WithShardPadding() Option {
 	return func(c *Config) { c.EnableShardPadding = true }
 }
+
+// WithOrderedIndex はキー順の索引を有効にするオプションです。
+// 有効にすると Range でキーの範囲読み出しができますが、書き込みごとに索引の更新コストがかかります。
+func WithOrderedIndex() Option {
+	return func(c *Config) { c.OrderedIndex = true }
+}
+
+// WithWatchBuffer は Watch の購読者ごとのバッファサイズを設定するオプションです。
+// バッファが一杯になった購読者は切断されます。
+func WithWatchBuffer(n int) Option {
+	return func(c *Config) { c.WatchBuffer = n }
+}
+
+// WithWatchHistory は直近 n 件のイベントを保持し、WatchFrom による再開を可能にするオプションです。
+// 履歴を有効にすると購読者がいなくても書き込みのたびにストア全体で共有するロックを取るため、書き込みのスループットが下がります。
+func WithWatchHistory(n int) Option {
+	return func(c *Config) { c.WatchHistory = n }
+}
+
+// WithCost はエントリのコスト（バイト数の見積もり）を計算する関数を設定するオプションです。
+// fn の型は Store のキー・値の型と一致している必要があり、一致しない場合 Open はエラーを返します。
+// 合計は Bytes と metrics の SetStoreBytes で参照でき、WithMaxBytes の予算判定に使われます。
+func WithCost[K comparable, V any](fn func(key K, value V) int64) Option {
+	return func(c *Config) { c.Cost = fn }
+}
+
+// WithMaxBytes はエントリのコスト合計の上限を設定するオプションです。
+// 上限を超えると Evictor が（キー数の容量とは別に）合計が上限以下になるまで追い出します。
+// 1 件で上限を超える値のセットは ErrValueTooLarge で拒否されます。
+// WithCost を指定しない場合、キーが string で値が string / []byte の Store では StringCost / BytesCost を使います。
+// Evictor を設定しない場合はキー数無制限の LRU を使います。
+func WithMaxBytes(n int64) Option {
+	return func(c *Config) { c.MaxBytes = n }
+}
+
+// WithOnRemove はエントリが取り除かれるたびに呼ばれるコールバックを設定するオプションです。
+// 取り除かれた値と理由 (Evicted / Expired / Deleted / Replaced) を受け取り、キーに紐づくリソースの解放や
+// 別の階層への書き出しに使えます。コールバックはシャードロックの外で、取り除かれたエントリごとに 1 回だけ呼ばれます。
+// AOF の再生による復元では呼ばれません。
+// fn の型は Store のキー・値の型と一致している必要があり、一致しない場合 Open はエラーを返します。
+func WithOnRemove[K comparable, V any](fn func(key K, value V, reason RemovalReason)) Option {
+	return func(c *Config) { c.OnRemove = fn }
+}
+
+// WithLoader はストア全体の既定のローダーを設定するオプションです。
+// 設定すると Get はミスしたキーを自動的に読み込み、GetOrLoad に nil を渡した場合もこのローダーを使います。
+// fn の型は Store のキー・値の型と一致している必要があり、一致しない場合 Open はエラーを返します。
+func WithLoader[K comparable, V any](fn LoaderFunc[K, V]) Option {
+	return func(c *Config) { c.Loader = fn }
+}
+
+// WithNegativeTTL はローダーが返したエラーを d の間キャッシュするオプションです。
+// 期間内の同じキーの読み込みはローダーを呼ばずに同じエラーを返します。キーがセットされると破棄されます。
+func WithNegativeTTL(d time.Duration) Option {
+	return func(c *Config) { c.NegativeTTL = d }
+}
+
+// WithWriteThrough は書き込みを同期的に Backend へ反映するオプションです。
+// Backend が失敗した書き込み (Set / Delete / CompareAndSwap / Incr / Txn など) は ErrBackend を返し、ストアも変更しません。
+// Backend への書き込み中はシャードロックを外し、同じキーへの書き込みだけをキーごとのロックで待たせます。
+// Backend のメソッドから同じ Store の同じキーへ書き込むとデッドロックするので注意してください。
+// Backend はキャッシュミス時のローダーとしても使われます（WithLoader を指定した場合はそちらを優先）。
+// b の型は Store のキー・値の型と一致している必要があり、一致しない場合 Open はエラーを返します。
+func WithWriteThrough[K comparable, V any](b Backend[K, V]) Option {
+	return func(c *Config) {
+		c.Backend = b
+		c.WriteMode = WriteThrough
+	}
+}
+
+// WithWriteBehind は書き込みをキューに入れ、interval ごと（またはキューが batch 件に達するごと）に
+// BatchStore でまとめて Backend へ反映するオプションです。同じキーへの連続した書き込みは最後の 1 件にまとめます。
+// 失敗したバッチは WithWriteBehindRetry の設定で再試行し、それでも失敗した場合はキューに戻して次の周期で再試行します。
+// Close 時には残りの書き込みを反映してから停止します。0 を指定した値は既定値を使います。
+func WithWriteBehind[K comparable, V any](b Backend[K, V], interval time.Duration, batch int) Option {
+	return func(c *Config) {
+		c.Backend = b
+		c.WriteMode = WriteBehind
+		c.WriteBehindInterval = interval
+		c.WriteBehindBatch = batch
+	}
+}
+
+// WithWriteBehindRetry は write-behind の再試行回数と初回の待ち時間を設定するオプションです。
+// 待ち時間は再試行のたびに倍になります。
+func WithWriteBehindRetry(retries int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.WriteBehindRetries = retries
+		c.WriteBehindBackoff = backoff
+	}
+}
+
+// WithAOF は追記専用ログ (AOF) による永続化を有効にするオプションです。
+// 起動時 (New/Open) に既存のログを再生してストアを復元します。
+// FsyncAlways では追記・fsync に失敗した書き込み系の操作が ErrAOF を返します（メモリ上の変更は取り消しません）。
+// FsyncEverySec / FsyncNo の永続化はベストエフォートで、失敗は AOFErr とメトリクス (aof_write_errors_total) で確認します。
+func WithAOF(path string, policy FsyncPolicy) Option {
+	return func(c *Config) {
+		c.AOFPath = path
+		c.AOFFsync = policy
+	}
+}
+
+// WithAOFRewrite は AOF の自動 rewrite 条件を設定するオプションです。
+// ファイルサイズが minSize 以上、かつ前回 rewrite 後のサイズから percent% 以上増えた場合に実行します。
+// percent に 0 を指定すると自動 rewrite を無効にします。
+func WithAOFRewrite(minSize int64, percent int) Option {
+	return func(c *Config) {
+		c.AOFRewriteMinSize = minSize
+		c.AOFRewritePercent = percent
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import "errors"
+
+// ErrOrderedIndexDisabled は WithOrderedIndex なしで Range を呼んだ場合のエラーです。
+var ErrOrderedIndexDisabled = errors.New("store: ordered index disabled")
+
+// defaultRangeLimit は Range の limit 未指定時の既定値です。
+const defaultRangeLimit = 100
+
+// rangeBatch は索引のロックを保持したまま一度に集めるキーの最大数です。
+const rangeBatch = 256
+
+// Range は start 以上 end 未満のキーを昇順（reverse なら降順）で最大 limit 件返します。
+// start / end がゼロ値の場合はそれぞれ下限・上限なしとして扱います。
+// 期限切れのエントリは（削除前であっても）結果に含めません。
+//
+// 索引はバッチ単位で読み、各エントリはシャードから取り直すため、
+// 走査中に削除・追い出されたキーは返りません。結果はスナップショットではなく、
+// 走査中の更新が反映されることがあります。
+func (s *Store[K, V]) Range(start, end K, limit int, reverse bool) ([]Item[K, V], error) {
+	if s.index == nil {
+		return nil, ErrOrderedIndexDisabled
+	}
+	if limit <= 0 {
+		limit = defaultRangeLimit
+	}
+	var zero K
+	b := indexBounds[K]{lo: start, hasLo: start != zero, loIncl: true, hi: end, hasHi: end != zero}
+
+	var items []Item[K, V]
+	for len(items) < limit {
+		keys := s.index.keys(b, reverse, min(limit-len(items), rangeBatch))
+		if len(keys) == 0 {
+			break
+		}
+		now := s.now().UnixNano()
+		for _, k := range keys {
+			mu, mp := s.getShard(k)
+			mu.RLock()
+			e, ok := mp[k]
+			mu.RUnlock()
+			if ok && !e.expired(now) {
+				items = append(items, newItem(k, e))
+			}
+		}
+		last := keys[len(keys)-1]
+		if reverse {
+			b.hi, b.hasHi = last, true
+		} else {
+			b.lo, b.hasLo, b.loIncl = last, true, false
+		}
+	}
+	return items, nil
+}
+
+func (s *Store[K, V]) indexAdd(key K) {
+	if s.index != nil {
+		s.index.insert(key)
+	}
+}
+
+func (s *Store[K, V]) indexRemove(key K) {
+	if s.index != nil {
+		s.index.remove(key)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import "fmt"
+
+// RemovalReason はエントリがストアから取り除かれた理由です。
+type RemovalReason int
+
+const (
+	// Evicted は Evictor（容量・バイト予算）による追い出しです。
+	Evicted RemovalReason = iota + 1
+	// Expired は TTL の期限切れです。期限切れのエントリは、どの経路で取り除かれても Expired になります。
+	Expired
+	// Deleted は Delete / CompareAndDelete / Txn による削除です。
+	Deleted
+	// Replaced は同じキーへの書き込みによる上書きです。
+	Replaced
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	default:
+		return fmt.Sprintf("RemovalReason(%d)", int(r))
+	}
+}
+
+// removedEntry はシャードロック下で取り除いたエントリです。ロック解放後に OnRemove に渡します。
+type removedEntry[K comparable, V any] struct {
+	key    K
+	val    V
+	reason RemovalReason
+}
+
+// removalReason は取り除いたエントリが期限切れなら Expired、そうでなければ reason を返します。
+func removalReason[V any](e entry[V], now int64, reason RemovalReason) RemovalReason {
+	if e.expired(now) {
+		return Expired
+	}
+	return reason
+}
+
+// onRemoveFunc は Config.OnRemove を Store のキー・値の型に合わせて取り出します。
+func onRemoveFunc[K comparable, V any](cfg Config) (func(K, V, RemovalReason), error) {
+	if cfg.OnRemove == nil {
+		return nil, nil
+	}
+	fn, ok := cfg.OnRemove.(func(K, V, RemovalReason))
+	if !ok {
+		var k K
+		var v V
+		return nil, fmt.Errorf("store: on-remove callback %T does not match Store[%T, %T]", cfg.OnRemove, k, v)
+	}
+	return fn, nil
+}
+
+// removed は OnRemove コールバックを呼びます。シャードロックの外で、取り除いたエントリごとに 1 回だけ呼びます。
+func (s *Store[K, V]) removed(key K, val V, reason RemovalReason) {
+	if s.onRemove != nil {
+		s.onRemove(key, val, reason)
+	}
+}
+
+// removedAll は removed をまとめて呼びます。
+func (s *Store[K, V]) removedAll(rs []removedEntry[K, V]) {
+	if s.onRemove == nil {
+		return
+	}
+	for _, r := range rs {
+		s.onRemove(r.key, r.val, r.reason)
+	}
+}
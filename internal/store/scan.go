@@ -0,0 +1,179 @@
+package store
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/amakane-hakari/kavos/internal/glob"
+)
+
+// defaultScanCount は Scan の count 未指定時の既定値です。
+const defaultScanCount = 10
+
+// scanItem は Scan で集めたキーとシャード内の位置です。
+type scanItem[K comparable] struct {
+	pos uint32
+	key K
+}
+
+// Scan はカーソル位置からキーを走査し、最大 count 件のキーと次のカーソルを返します。
+// cursor に 0 を渡すと走査を開始し、次のカーソルとして 0 が返れば走査完了です。
+// match が空でなければグロブパターン（glob.Match）に一致するキーだけを返します。
+//
+// カーソルは上位 32 ビットがシャード、下位 32 ビットがシャード内の位置 (scanPos) を表し、
+// 位置はキーのハッシュから決まるため同じキーは常に同じ位置に現れます。
+// 一度に 1 シャードのロックしか保持しません。Redis の SCAN と同様に、
+// 走査の開始から終了まで存在し続けたキーは必ず 1 回返され、途中で追加・削除されたキーは
+// 返されることも返されないこともあります。
+// ハッシュが衝突したキーは同じページにまとめて返すため、その場合に限り count を超えることがあります。
+func (s *Store[K, V]) Scan(cursor uint64, match string, count int) (keys []K, next uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	idx := int(cursor >> 32)
+	first := uint32(cursor)
+	if idx >= s.shardCount() {
+		return nil, 0
+	}
+	now := s.now().UnixNano()
+	var (
+		page []scanItem[K]
+		top  posHeap
+	)
+	for {
+		// 位置の小さい順に want 件だけ残す。want 件目の位置 (top の最大値) より後ろのキーは集めず、
+		// そうしたキーが 1 つでもあればこのシャードには続きがある (more)
+		want := count - len(keys)
+		page, top = page[:0], top[:0]
+		more := false
+		sh := s.shardAt(idx)
+		sh.mu.RLock()
+		for k, e := range sh.m {
+			p := scanPos(s.hashKey(k))
+			if p < first {
+				continue
+			}
+			beyond := len(top) == want && p > top[0]
+			if (beyond && more) || e.expired(now) || (match != "" && !glob.Match(match, keyString(k))) {
+				continue
+			}
+			if beyond {
+				more = true
+				continue
+			}
+			page = append(page, scanItem[K]{p, k})
+			top.push(p, want)
+		}
+		sh.mu.RUnlock()
+		if len(top) == want {
+			for _, it := range page {
+				if it.pos > top[0] {
+					more = true
+					break
+				}
+			}
+		}
+
+		for _, it := range page {
+			// ハッシュが衝突したキーは同じ位置なので、まとめて返す
+			if !more || it.pos <= top[0] {
+				keys = append(keys, it.key)
+			}
+		}
+		if more {
+			return keys, uint64(idx)<<32 | uint64(top[0]+1)
+		}
+		idx, first = idx+1, 0
+		if idx >= s.shardCount() {
+			return keys, 0
+		}
+		if len(keys) >= count {
+			return keys, uint64(idx) << 32
+		}
+	}
+}
+
+// posHeap は Scan で位置の小さい順に n 件を選ぶための最大ヒープです（先頭が最大）。
+type posHeap []uint32
+
+// push は p を追加し、n 件を超えたら最大の位置を取り除きます。
+func (h *posHeap) push(p uint32, n int) {
+	if len(*h) == n {
+		if p >= (*h)[0] {
+			return
+		}
+		(*h)[0] = p
+		h.down(0)
+		return
+	}
+	*h = append(*h, p)
+	for i := len(*h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if (*h)[parent] >= (*h)[i] {
+			break
+		}
+		(*h)[parent], (*h)[i] = (*h)[i], (*h)[parent]
+		i = parent
+	}
+}
+
+func (h posHeap) down(i int) {
+	for {
+		l, largest := 2*i+1, i
+		if l < len(h) && h[l] > h[largest] {
+			largest = l
+		}
+		if r := l + 1; r < len(h) && h[r] > h[largest] {
+			largest = r
+		}
+		if largest == i {
+			return
+		}
+		h[i], h[largest] = h[largest], h[i]
+		i = largest
+	}
+}
+
+// All は期限切れでない全エントリを列挙するイテレータを返します。
+// シャード単位でスナップショットを取ってからロック外で yield するため、
+// ループ本体からストアを操作しても構いません。
+func (s *Store[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		type pair struct {
+			k K
+			v V
+		}
+		var buf []pair
+		for i := 0; i < s.shardCount(); i++ {
+			now := s.now().UnixNano()
+			buf = buf[:0]
+			sh := s.shardAt(i)
+			sh.mu.RLock()
+			for k, e := range sh.m {
+				if !e.expired(now) {
+					buf = append(buf, pair{k, e.val})
+				}
+			}
+			sh.mu.RUnlock()
+			for _, p := range buf {
+				if !yield(p.k, p.v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// scanPos はハッシュ値からシャード内の走査位置を決めます。
+// 整数キーはハッシュが恒等写像なので、乗算で全ビットへ拡散させます。
+func scanPos(h uint32) uint32 {
+	return h * 0x9e3779b1
+}
+
+// keyString はパターンマッチ用にキーを文字列化します。
+func keyString[K comparable](k K) string {
+	if s, ok := any(k).(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
@@ -3,23 +3,33 @@ package store
 import "sync"
 
 type shardCompact[K comparable, V any] struct {
-	mu sync.RWMutex
-	m  map[K]entry[V]
+	mu  sync.RWMutex
+	m   map[K]entry[V]
+	exp expiryHeap[K] // 期限つきのキーを期限順に保持する（TTL クリーンアップ用）
 }
 
 type shardPadding[K comparable, V any] struct {
-	mu sync.RWMutex
-	m  map[K]entry[V]
-	_  [cacheLineSize]byte // cache line padding
+	shardCompact[K, V]
+	_ [cacheLineSize]byte // cache line padding
 }
 
 func (s *Store[K, V]) getShard(key K) (rw *sync.RWMutex, m map[K]entry[V]) {
-	h := s.hashKey(key)
-	idx := int(h & s.shardMask)
+	sh := s.shardAt(s.shardIndex(key))
+	return &sh.mu, sh.m
+}
+
+func (s *Store[K, V]) shardIndex(key K) int {
+	return int(s.hashKey(key) & s.shardMask)
+}
+
+// shardAt は index 番目のシャードを返します（パディング有無を吸収）。
+func (s *Store[K, V]) shardAt(i int) *shardCompact[K, V] {
 	if s.cfg.EnableShardPadding {
-		sh := &s.shardsPadded[idx]
-		return &sh.mu, sh.m
+		return &s.shardsPadded[i].shardCompact
 	}
-	sh := &s.shardsCompact[idx]
-	return &sh.mu, sh.m
+	return &s.shardsCompact[i]
+}
+
+func (s *Store[K, V]) shardCount() int {
+	return int(s.shardMask) + 1
 }
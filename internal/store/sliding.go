@@ -0,0 +1,97 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// SetWithSlidingTTL はスライディング期限つきでキーと値をセットします。
+// Get などで読み出すたびに期限が現在から ttl 後に延び、ttl の間アクセスがなければ期限切れになります。
+// ttl が 0 以下なら SetWithTTL(key, value, 0) と同じく無期限です。
+func (s *Store[K, V]) SetWithSlidingTTL(key K, value V, ttl time.Duration) error {
+	_, err := s.SetSlidingVersioned(key, value, ttl)
+	return err
+}
+
+// SetSlidingVersioned は SetWithSlidingTTL と同様にセットし、新しいバージョンを返します。
+func (s *Store[K, V]) SetSlidingVersioned(key K, value V, ttl time.Duration) (uint64, error) {
+	_, ver, err := s.setEntry(key, value, 0, max(ttl, 0), ttl > 0, 0)
+	return ver, err
+}
+
+// CompareAndSwapSliding は CompareAndSwap と同様にセットし、期限をスライディング期限 (SetWithSlidingTTL) にします。
+func (s *Store[K, V]) CompareAndSwapSliding(key K, expectedVersion uint64, value V, ttl time.Duration) (uint64, error) {
+	return s.compareAndSwap(key, expectedVersion, value, ttl, ttl > 0, true)
+}
+
+// extendsOnAccess はアクセスでエントリの期限が延びるか（スライディング期限か WithMaxIdle）を返します。
+func (s *Store[K, V]) extendsOnAccess(e entry[V]) bool {
+	return e.sliding || s.cfg.MaxIdle > 0
+}
+
+// accessExpireAt はセットまたはアクセスした時刻 now でのエントリの実効期限 (UnixNano, 0=無期限) を返します。
+// 絶対期限 (deadline)、スライディング期限 (now+ttl)、WithMaxIdle (now+MaxIdle) のうち最も早いものです。
+func (s *Store[K, V]) accessExpireAt(e entry[V], now int64) int64 {
+	exp := e.deadline
+	if e.sliding && e.ttl > 0 {
+		exp = earliest(exp, now+int64(e.ttl))
+	}
+	if s.cfg.MaxIdle > 0 {
+		exp = earliest(exp, now+int64(s.cfg.MaxIdle))
+	}
+	return exp
+}
+
+// accessExtendDivisor は、アクセスで期限を延ばす最小の幅を、延長の幅 (スライディング期限の ttl または MaxIdle) の何分の 1 にするかです。
+// 読み出しのたびに書き込みロックを取らないよう、期限が幅の 1% 以上延びるときだけ延長します（その分だけ早く期限切れになり得ます）。
+const accessExtendDivisor = 100
+
+// accessExtension はアクセス時刻 now でエントリ e の期限を延ばす場合に、新しい期限を返します。
+// 延びる幅が accessExtendDivisor で決まる最小幅に満たなければ ok=false です（絶対期限に達する場合を除く）。
+func (s *Store[K, V]) accessExtension(e entry[V], now int64) (exp int64, ok bool) {
+	if !s.extendsOnAccess(e) {
+		return 0, false
+	}
+	exp = s.accessExpireAt(e, now)
+	if exp <= e.expireAt {
+		return 0, false
+	}
+	var window int64
+	if e.sliding && e.ttl > 0 {
+		window = int64(e.ttl)
+	}
+	if s.cfg.MaxIdle > 0 {
+		window = earliest(window, int64(s.cfg.MaxIdle))
+	}
+	if e.expireAt != 0 && exp-e.expireAt < window/accessExtendDivisor && exp != e.deadline {
+		return 0, false
+	}
+	return exp, true
+}
+
+// earliest は期限 a, b (0=なし) のうち早い方を返します。
+func earliest(a, b int64) int64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// extendOnAccess は読み出したエントリ e の期限をアクセス時刻 now に合わせて延ばし、延ばした後のエントリを返します。
+// 延びる幅が小さい場合（accessExtension）や、読み出してから他ゴルーチンが更新・期限変更していれば何もしません。
+// 期限ヒープには追加せず（古い要素を見つけた expireShard / sampleExpired が付け替えます）、AOF にも記録しません。
+func (s *Store[K, V]) extendOnAccess(mu *sync.RWMutex, mp map[K]entry[V], key K, e entry[V], now int64) entry[V] {
+	exp, ok := s.accessExtension(e, now)
+	if !ok {
+		return e
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	cur, ok := mp[key]
+	if !ok || cur.ver != e.ver || cur.expireAt != e.expireAt {
+		return e
+	}
+	cur.expireAt = exp
+	mp[key] = cur
+	return cur
+}
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+/*
+スナップショットファイルフォーマット
+
+	header : magic "KAVOSSNP" (8) | format version (1)
+	entry  : 1 (1) | expireAt (int64 BE, UnixNano, 0=無期限) | version (uint64 BE) | key 長 (uvarint) | key | value 長 (uvarint) | value
+	footer : 0 (1) | entry 数 (uint64 BE) | CRC32-IEEE (uint32 BE, 先頭から footer の entry 数までが対象)
+*/
+const (
+	snapshotMagic         = "KAVOSSNP"
+	snapshotFormatVersion = 2
+
+	snapshotTagEntry = 1
+	snapshotTagEnd   = 0
+)
+
+// ErrSnapshotCorrupt はスナップショットが破損している場合のエラーです。
+var ErrSnapshotCorrupt = errors.New("store: corrupt snapshot")
+
+// Snapshot は期限切れでない全エントリを w に書き出します。
+// ロックはシャード単位で取得するため、書き込みを全体停止させません。
+// 得られる内容は各シャードごとに一貫した時点のものです。
+func (s *Store[K, V]) Snapshot(w io.Writer) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	hdr := append([]byte(snapshotMagic), snapshotFormatVersion)
+	if _, err := bw.Write(hdr); err != nil {
+		return err
+	}
+
+	var (
+		count uint64
+		buf   []byte
+	)
+	for i := 0; i < s.shardCount(); i++ {
+		sh := s.shardAt(i)
+		now := s.now().UnixNano()
+		buf = buf[:0]
+		n := 0
+		var encErr error
+		sh.mu.RLock()
+		for k, e := range sh.m {
+			if e.expireAt > 0 && e.expireAt <= now {
+				continue
+			}
+			buf, encErr = appendSnapshotEntry(buf, k, e)
+			if encErr != nil {
+				break
+			}
+			n++
+		}
+		sh.mu.RUnlock()
+		if encErr != nil {
+			return encErr
+		}
+		// I/O はロック外で行う
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		count += uint64(n)
+	}
+
+	footer := binary.BigEndian.AppendUint64([]byte{snapshotTagEnd}, count)
+	if _, err := bw.Write(footer); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err := w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	return err
+}
+
+func appendSnapshotEntry[K comparable, V any](dst []byte, k K, e entry[V]) ([]byte, error) {
+	kb, err := marshalValue(k)
+	if err != nil {
+		return dst, err
+	}
+	vb, err := marshalValue(e.val)
+	if err != nil {
+		return dst, err
+	}
+	dst = append(dst, snapshotTagEntry)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(e.persistedExpireAt()))
+	dst = binary.BigEndian.AppendUint64(dst, e.ver)
+	dst = binary.AppendUvarint(dst, uint64(len(kb)))
+	dst = append(dst, kb...)
+	dst = binary.AppendUvarint(dst, uint64(len(vb)))
+	dst = append(dst, vb...)
+	return dst, nil
+}
+
+// Restore は opts で新しい Store を作成し（Open と同じ）、Snapshot で書き出した内容を読み込んで返します。
+// 既存のストアへ読み込む場合は (*Store).Restore を使います。読み込みに失敗した場合は作成したストアを閉じてエラーを返します。
+func Restore[K comparable, V any](r io.Reader, opts ...Option) (*Store[K, V], error) {
+	s, err := Open[K, V](opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Restore(r); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Restore は Snapshot で書き出した内容を読み込み、ストアにセットします。
+// チェックサムを検証してから反映するため、破損したスナップショットは一切反映されません。
+// 既存のキーは上書きされ、スナップショットに含まれないキーはそのまま残ります。
+// エントリのバージョンはスナップショット時点の値が引き継がれます。
+func (s *Store[K, V]) Restore(r io.Reader) error {
+	cr := &crcReader{r: bufio.NewReader(r), h: crc32.NewIEEE()}
+
+	hdr := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(cr, hdr); err != nil {
+		return fmt.Errorf("%w: header: %v", ErrSnapshotCorrupt, err)
+	}
+	if string(hdr[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: bad magic", ErrSnapshotCorrupt)
+	}
+	if v := hdr[len(snapshotMagic)]; v != snapshotFormatVersion {
+		return fmt.Errorf("store: unsupported snapshot version %d", v)
+	}
+
+	type item struct {
+		key      K
+		val      V
+		expireAt int64
+		ver      uint64
+	}
+	var items []item
+	for {
+		tag, err := cr.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		if tag == snapshotTagEnd {
+			break
+		}
+		if tag != snapshotTagEntry {
+			return fmt.Errorf("%w: unknown tag %d", ErrSnapshotCorrupt, tag)
+		}
+		var it item
+		var raw [16]byte
+		if _, err := io.ReadFull(cr, raw[:]); err != nil {
+			return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		it.expireAt = int64(binary.BigEndian.Uint64(raw[:8]))
+		it.ver = binary.BigEndian.Uint64(raw[8:])
+		kb, err := readSnapshotBytes(cr)
+		if err != nil {
+			return err
+		}
+		vb, err := readSnapshotBytes(cr)
+		if err != nil {
+			return err
+		}
+		if it.key, err = unmarshalValue[K](kb); err != nil {
+			return err
+		}
+		if it.val, err = unmarshalValue[V](vb); err != nil {
+			return err
+		}
+		items = append(items, it)
+	}
+
+	var raw [8]byte
+	if _, err := io.ReadFull(cr, raw[:]); err != nil {
+		return fmt.Errorf("%w: footer: %v", ErrSnapshotCorrupt, err)
+	}
+	if n := binary.BigEndian.Uint64(raw[:]); n != uint64(len(items)) {
+		return fmt.Errorf("%w: entry count mismatch", ErrSnapshotCorrupt)
+	}
+	want := cr.h.Sum32()
+	if _, err := io.ReadFull(cr.r, raw[:4]); err != nil {
+		return fmt.Errorf("%w: checksum: %v", ErrSnapshotCorrupt, err)
+	}
+	if binary.BigEndian.Uint32(raw[:4]) != want {
+		return fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	now := s.now().UnixNano()
+	for _, it := range items {
+		if it.expireAt > 0 && it.expireAt <= now {
+			continue
+		}
+		if _, _, err := s.setEntry(it.key, it.val, it.expireAt, 0, false, it.ver); err != nil && s.cfg.Logger != nil {
+			s.cfg.Logger.Error("store.snapshot.skip", "key", it.key, "err", err)
+		}
+	}
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Info("store.snapshot.restored", "entries", len(items))
+	}
+	return nil
+}
+
+func readSnapshotBytes(cr *crcReader) ([]byte, error) {
+	n, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if n > aofMaxRecordSize {
+		return nil, fmt.Errorf("%w: length too large", ErrSnapshotCorrupt)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cr, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	return b, nil
+}
+
+// crcReader は読み込んだバイト列の CRC を計算しながら読み込みます。
+type crcReader struct {
+	r *bufio.Reader
+	h hash.Hash32
+}
+
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	_, _ = c.h.Write(p[:n])
+	return n, err
+}
+
+func (c *crcReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		_, _ = c.h.Write([]byte{b})
+	}
+	return b, err
+}
+
+// SaveSnapshot はスナップショットを path に書き出します。
+// 一時ファイルに書き込んでから rename するため、途中で失敗しても既存ファイルは壊れません。
+func (s *Store[K, V]) SaveSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if err := s.Snapshot(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// LoadSnapshot は path のスナップショットを読み込みます。
+// ファイルが存在しない場合は fs.ErrNotExist をラップしたエラーを返します。
+func (s *Store[K, V]) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return s.Restore(f)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
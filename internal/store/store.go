@@ -1,9 +1,13 @@
 package store
 
 import (
+	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/amakane-hakari/kavos/internal/clock"
 	"github.com/amakane-hakari/kavos/internal/metrics"
 )
 
@@ -15,6 +19,21 @@ type Store[K comparable, V any] struct {
 	stopCh          chan struct{}
 	wg              sync.WaitGroup
 	evictor         Evictor[K, V]
+	index           *orderedIndex[K] // nil なら Range 無効
+	watch           *watchHub[K, V]
+	aof             *aofLog // nil なら永続化なし
+	aofRewriteCh    chan struct{}
+	aofErr          atomic.Pointer[error] // 最後に失敗した AOF への追記・fsync (AOFErr)
+	version         atomic.Uint64         // 最後に採番したバージョン
+	cost            func(K, V) int64      // nil ならコストを数えない
+	bytes           atomic.Int64          // エントリのコスト合計
+	expireCursor    int                   // activeExpireCycle が次に処理するシャード
+	loader          LoaderFunc[K, V]      // nil なら Get で読み込まない
+	loads           *loadGroup[K, V]
+	backend         Backend[K, V]      // nil なら Backend なし
+	writeBehind     *writeBehind[K, V] // nil なら write-through（または Backend なし）
+	keyLocks        []sync.Mutex       // write-through のときだけ。lockKeys を参照
+	onRemove        func(K, V, RemovalReason)
 
 	closeOnce sync.Once // Close 多重呼び出し防止
 
@@ -24,16 +43,60 @@ type Store[K comparable, V any] struct {
 }
 
 // New は新しい Store を作成します。
+// 永続化の初期化に失敗した場合は panic します。エラーを扱う場合は Open を使用してください。
 func New[K comparable, V any](opts ...Option) *Store[K, V] {
-	cfg := Config{Shards: 16, Metrics: &metrics.Noop{}}
+	s, err := Open[K, V](opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Open は新しい Store を作成し、AOF が設定されていればその内容を復元します。
+func Open[K comparable, V any](opts ...Option) (*Store[K, V], error) {
+	cfg := Config{
+		Shards:            16,
+		Metrics:           &metrics.Noop{},
+		Clock:             clock.Real,
+		AOFRewritePercent: 100,
+		AOFRewriteMinSize: 64 << 20,
+	}
 	for _, o := range opts {
 		o(&cfg)
 	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
 	if cfg.Shards < 1 {
 		cfg.Shards = 16
 	}
 	// 2 の冪に揃える
 	cfg.Shards = nextPowerOfTwo(cfg.Shards)
+	cost, err := costFunc[K, V](cfg)
+	if err != nil {
+		return nil, err
+	}
+	var loader LoaderFunc[K, V]
+	if cfg.Loader != nil {
+		fn, ok := cfg.Loader.(LoaderFunc[K, V])
+		if !ok {
+			var k K
+			var v V
+			return nil, fmt.Errorf("store: loader %T does not match Store[%T, %T]", cfg.Loader, k, v)
+		}
+		loader = fn
+	}
+	backend, err := backendFunc[K, V](cfg)
+	if err != nil {
+		return nil, err
+	}
+	if loader == nil && backend != nil {
+		loader = backendLoader(backend)
+	}
+	onRemove, err := onRemoveFunc[K, V](cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	s := &Store[K, V]{
 		cfg:             cfg,
@@ -41,6 +104,12 @@ func New[K comparable, V any](opts ...Option) *Store[K, V] {
 		cleanupInterval: cfg.CleanupInterval,
 		evictor:         nil,
 		stopCh:          make(chan struct{}),
+		watch:           newWatchHub[K, V](cfg.WatchBuffer, cfg.WatchHistory),
+		cost:            cost,
+		loader:          loader,
+		loads:           newLoadGroup[K, V](),
+		backend:         backend,
+		onRemove:        onRemove,
 	}
 	if cfg.EnableShardPadding {
 		s.shardsPadded = make([]shardPadding[K, V], cfg.Shards)
@@ -54,17 +123,103 @@ func New[K comparable, V any](opts ...Option) *Store[K, V] {
 		}
 	}
 
+	if cfg.OrderedIndex {
+		s.index = newOrderedIndex(orderedCompare[K]())
+	}
+
+	if cfg.AOFPath != "" {
+		if err := s.openAOF(cfg.AOFPath, cfg.AOFFsync); err != nil {
+			return nil, err
+		}
+		if s.aof.policy == FsyncEverySec {
+			s.wg.Add(1)
+			go s.aofSyncLoop()
+		}
+		s.aofRewriteCh = make(chan struct{}, 1)
+		s.wg.Add(1)
+		go s.aofRewriteLoop()
+	}
+
+	if backend != nil && cfg.WriteMode == WriteBehind {
+		batch := cfg.WriteBehindBatch
+		if batch <= 0 {
+			batch = defaultWriteBehindBatch
+		}
+		interval := cfg.WriteBehindInterval
+		if interval <= 0 {
+			interval = defaultWriteBehindInterval
+		}
+		s.writeBehind = newWriteBehind[K, V](batch)
+		s.wg.Add(1)
+		go s.flushLoop(interval)
+	} else if backend != nil {
+		s.keyLocks = make([]sync.Mutex, keyLockStripes)
+	}
+
 	if s.cleanupInterval > 0 {
 		s.wg.Add(1)
-		go s.cleanupLoop()
+		go s.cleanupLoop(cfg.Clock.NewTicker(s.cleanupInterval))
 	}
 
-	return s
+	if cfg.MaxBytes > 0 {
+		// バイト予算だけを守る（キー数は無制限）。WithEvictor で差し替えられる
+		s.WithEvictor(NewLRUEvictor[K, V](math.MaxInt))
+	}
+
+	return s, nil
+}
+
+// now は設定された Clock の現在時刻を返します。期限の計算と判定はすべてこの時刻で行います。
+func (s *Store[K, V]) now() time.Time {
+	return s.cfg.Clock.Now()
+}
+
+// Clock はストアが期限の計算に使う Clock を返します。
+func (s *Store[K, V]) Clock() clock.Clock {
+	return s.cfg.Clock
+}
+
+func (s *Store[K, V]) nextVersion() uint64 {
+	return s.version.Add(1)
+}
+
+// observeVersion は復元したバージョン以上から採番が続くようカウンタを進めます。
+func (s *Store[K, V]) observeVersion(v uint64) {
+	for {
+		cur := s.version.Load()
+		if cur >= v || s.version.CompareAndSwap(cur, v) {
+			return
+		}
+	}
 }
 
 // WithEvictor はストアのエビクタを設定するメソッドです。
+// 既にストアにあるキー（AOF から復元したものなど）は Evictor に登録されます。
 func (s *Store[K, V]) WithEvictor(ev Evictor[K, V]) *Store[K, V] {
 	s.evictor = ev
+	if ev == nil {
+		return s
+	}
+	var victims []K
+	for i := 0; i < s.shardCount(); i++ {
+		sh := s.shardAt(i)
+		sh.mu.RLock()
+		for k, e := range sh.m {
+			victims = append(victims, ev.OnSet(k, e.val, false)...)
+		}
+		sh.mu.RUnlock()
+	}
+	for _, vk := range victims {
+		// 追い出しは AOF への記録に失敗しても取り消さない（失敗は aofError で記録済み。再起動後に残っても evictor が上限内に収める）
+		_ = s.deleteInternal(vk, true)
+	}
+	victims = append(victims, s.shrinkToMaxBytes()...)
+	if len(victims) > 0 {
+		s.cfg.Metrics.AddEvicted(len(victims))
+	}
+	if sp, ok := ev.(interface{ Size() int }); ok {
+		s.cfg.Metrics.SetLRUSize(sp.Size())
+	}
 	return s
 }
 
@@ -74,6 +229,13 @@ func (s *Store[K, V]) Close() {
 		if s.stopCh != nil {
 			close(s.stopCh)
 		}
+		s.wg.Wait()
+		s.watch.close()
+		if s.aof != nil {
+			if err := s.aof.close(); err != nil && s.cfg.Logger != nil {
+				s.cfg.Logger.Error("store.aof.close", "err", err)
+			}
+		}
 	})
 	s.wg.Wait()
 }
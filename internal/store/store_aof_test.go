@@ -0,0 +1,414 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func TestStore_AOFReplay(t *testing.T) {
+	clk := newFakeClock()
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+
+	s, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	_ = s.Set("a", "3")
+	_ = s.Delete("b")
+	_ = s.SetWithTTL("ttl", "x", time.Hour)
+	_ = s.SetWithTTL("short", "y", 10*time.Millisecond)
+	s.Close()
+
+	clk.Advance(20 * time.Millisecond)
+
+	s2, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if v, ok := s2.Get("a"); !ok || v != "3" {
+		t.Fatalf("a want 3 got %q ok=%v", v, ok)
+	}
+	if _, ok := s2.Get("b"); ok {
+		t.Fatalf("b should be deleted")
+	}
+	if _, ok := s2.Get("ttl"); !ok {
+		t.Fatalf("ttl key should survive restart")
+	}
+	if _, ok := s2.Get("short"); ok {
+		t.Fatalf("expired key should not be restored")
+	}
+}
+
+func TestStore_AOFTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+
+	s, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	s.Close()
+
+	// 最後のレコードを途中で切る
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if err := os.Truncate(path, fi.Size()-2); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	s2, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen with truncated tail: %v", err)
+	}
+	if v, ok := s2.Get("a"); !ok || v != "1" {
+		t.Fatalf("a want 1 got %q ok=%v", v, ok)
+	}
+	if _, ok := s2.Get("b"); ok {
+		t.Fatalf("b was in the truncated record")
+	}
+	// 切り捨て後も追記できる
+	_ = s2.Set("c", "3")
+	s2.Close()
+
+	s3, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s3.Close()
+	if v, ok := s3.Get("c"); !ok || v != "3" {
+		t.Fatalf("c want 3 got %q ok=%v", v, ok)
+	}
+}
+
+func TestStore_AOFCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+
+	s, err := Open[string, int](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("a", 1)
+	_ = s.Set("b", 2)
+	s.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	b[len(b)-1] ^= 0xff // 最後のレコードの CRC を壊す
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	s2, err := Open[string, int](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if v, ok := s2.Get("a"); !ok || v != 1 {
+		t.Fatalf("a want 1 got %d ok=%v", v, ok)
+	}
+	if _, ok := s2.Get("b"); ok {
+		t.Fatalf("corrupt record should be dropped")
+	}
+}
+
+func TestStore_AOFRejectsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	if err := os.WriteFile(path, []byte("not an aof file"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Open[string, string](WithAOF(path, FsyncNo)); err == nil {
+		t.Fatalf("expected error for foreign file")
+	}
+}
+
+func TestStore_AOFEvictorSeeded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	_ = s.Set("c", "3")
+	s.Close()
+
+	s2, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	s2.WithEvictor(NewLRUEvictor[string, string](2))
+	if l := s2.Len(); l != 2 {
+		t.Fatalf("restored keys should be bounded by evictor, len=%d", l)
+	}
+}
+
+func TestStore_AOFWriteError(t *testing.T) {
+	for _, policy := range []FsyncPolicy{FsyncAlways, FsyncEverySec} {
+		t.Run(string(policy), func(t *testing.T) {
+			mx := metrics.NewSimple()
+			s, err := Open[string, string](WithMetrics(mx), WithAOF(filepath.Join(t.TempDir(), "kavos.aof"), policy))
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer s.Close()
+			if err := s.Set("ok", "v"); err != nil || s.AOFErr() != nil {
+				t.Fatalf("set: %v aof=%v", err, s.AOFErr())
+			}
+
+			// 以降の追記を失敗させる
+			s.aof.mu.Lock()
+			cerr := s.aof.f.Close()
+			s.aof.mu.Unlock()
+			if cerr != nil {
+				t.Fatalf("close: %v", cerr)
+			}
+			err = s.Set("k", "v")
+			delErr := s.Delete("ok")
+			_, txnErr := s.Txn(func(tx *Tx[string, string]) error {
+				tx.Set("t", "v", 0)
+				return nil
+			})
+			if policy == FsyncAlways {
+				for _, e := range []error{err, delErr, txnErr} {
+					if !errors.Is(e, ErrAOF) || !errors.Is(e, os.ErrClosed) {
+						t.Fatalf("want ErrAOF wrapping the cause, got %v", e)
+					}
+				}
+			} else if err != nil || delErr != nil || txnErr != nil {
+				t.Fatalf("%s should be best-effort, got %v / %v / %v", policy, err, delErr, txnErr)
+			}
+			// メモリ上の変更は取り消さない
+			if _, ok := s.Get("k"); !ok {
+				t.Fatalf("failed append should not roll back the set")
+			}
+			if !errors.Is(s.AOFErr(), ErrAOF) || mx.AOFWriteErrors.Load() < 3 {
+				t.Fatalf("want sticky error and metric, got %v errors=%d", s.AOFErr(), mx.AOFWriteErrors.Load())
+			}
+		})
+	}
+}
+
+// shortWriteFile は次の Write を途中まで書いて失敗させる aofFile です。
+type shortWriteFile struct {
+	aofFile
+	fail bool
+}
+
+func (f *shortWriteFile) Write(p []byte) (int, error) {
+	if f.fail {
+		f.fail = false
+		n, _ := f.aofFile.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return f.aofFile.Write(p)
+}
+
+func TestStore_AOFShortWriteDoesNotTearLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, string](WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := s.Set("a", "1"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	sw := &shortWriteFile{aofFile: s.aof.f, fail: true}
+	s.aof.mu.Lock()
+	s.aof.f = sw
+	s.aof.mu.Unlock()
+	if err := s.Set("torn", "x"); !errors.Is(err, ErrAOF) {
+		t.Fatalf("want ErrAOF, got %v", err)
+	}
+	// 失敗したレコードの断片が後続のレコードを巻き込まないこと
+	if err := s.Set("b", "2"); err != nil {
+		t.Fatalf("set after short write: %v", err)
+	}
+	s.Close()
+
+	s2, err := Open[string, string](WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	for _, k := range []string{"a", "b"} {
+		if _, ok := s2.Get(k); !ok {
+			t.Fatalf("%s should survive a torn append", k)
+		}
+	}
+	if _, ok := s2.Get("torn"); ok {
+		t.Fatalf("failed record should not be replayed")
+	}
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	for _, in := range []string{"always", "everysec", "no"} {
+		if p, err := ParseFsyncPolicy(in); err != nil || string(p) != in {
+			t.Fatalf("parse %q: %v %v", in, p, err)
+		}
+	}
+	if _, err := ParseFsyncPolicy("sometimes"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestStore_AOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	mx := metrics.NewSimple()
+	s, err := Open[string, string](WithAOF(path, FsyncNo), WithAOFRewrite(0, 0), WithMetrics(mx))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		_ = s.Set("hot", fmt.Sprintf("v%d", i))
+	}
+	_ = s.Set("gone", "x")
+	_ = s.Delete("gone")
+	before, _ := os.Stat(path)
+
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("rewrite should shrink the log: before=%d after=%d", before.Size(), after.Size())
+	}
+	if mx.AOFRewrites.Load() != 1 || mx.AOFRewriteInProgress.Load() {
+		t.Fatalf("unexpected rewrite metrics: done=%d inProgress=%v", mx.AOFRewrites.Load(), mx.AOFRewriteInProgress.Load())
+	}
+
+	// rewrite 後の書き込みも新しいファイルに残る
+	_ = s.Set("after", "1")
+	s.Close()
+
+	s2, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if v, ok := s2.Get("hot"); !ok || v != "v999" {
+		t.Fatalf("hot want v999 got %q", v)
+	}
+	if _, ok := s2.Get("gone"); ok {
+		t.Fatalf("gone should stay deleted")
+	}
+	if _, ok := s2.Get("after"); !ok {
+		t.Fatalf("write after rewrite lost")
+	}
+}
+
+func TestStore_AOFRewriteKeepsFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, string](WithAOF(path, FsyncNo), WithAOFRewrite(0, 0))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	_ = s.Set("k", "v")
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0o640 {
+		t.Fatalf("rewrite should keep the original mode 0640, got %#o", mode)
+	}
+}
+
+func TestStore_AOFRewriteConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, int](WithAOF(path, FsyncNo), WithAOFRewrite(0, 0))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 5000; i++ {
+		_ = s.Set(strconv.Itoa(i), i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5000; i++ {
+			_ = s.Set(strconv.Itoa(i), i*2)
+			if i%3 == 0 {
+				_ = s.Delete(strconv.Itoa(i))
+			}
+		}
+	}()
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	wg.Wait()
+	s.Close()
+
+	s2, err := Open[string, int](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	for i := 0; i < 5000; i++ {
+		v, ok := s2.Get(strconv.Itoa(i))
+		if i%3 == 0 {
+			if ok {
+				t.Fatalf("key %d should be deleted", i)
+			}
+			continue
+		}
+		if !ok || v != i*2 {
+			t.Fatalf("key %d want %d got %d ok=%v", i, i*2, v, ok)
+		}
+	}
+}
+
+func TestStore_AOFAutoRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	mx := metrics.NewSimple()
+	s, err := Open[string, string](WithAOF(path, FsyncNo), WithAOFRewrite(1024, 100), WithMetrics(mx))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer s.Close()
+	for i := 0; i < 500; i++ {
+		_ = s.Set("hot", fmt.Sprintf("v%d", i))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for mx.AOFRewrites.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("auto rewrite did not run")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_RewriteAOFDisabled(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+	if err := s.RewriteAOF(); !errors.Is(err, ErrAOFDisabled) {
+		t.Fatalf("want ErrAOFDisabled got %v", err)
+	}
+	if err := s.StartAOFRewrite(); !errors.Is(err, ErrAOFDisabled) {
+		t.Fatalf("want ErrAOFDisabled got %v", err)
+	}
+}
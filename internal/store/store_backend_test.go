@@ -0,0 +1,443 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+// memBackend は呼び出しを記録するテスト用の Backend です。fail > 0 の間は書き込みを失敗させます。
+type memBackend struct {
+	mu      sync.Mutex
+	m       map[string]string
+	stores  int
+	batches [][]BackendWrite[string, string]
+	fail    int
+}
+
+var errBackendDown = errors.New("backend down")
+
+func newMemBackend() *memBackend { return &memBackend{m: make(map[string]string)} }
+
+func (b *memBackend) Load(_ context.Context, key string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.m[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (b *memBackend) failing() bool {
+	if b.fail > 0 {
+		b.fail--
+		return true
+	}
+	return false
+}
+
+func (b *memBackend) Store(_ context.Context, key string, value string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing() {
+		return errBackendDown
+	}
+	b.stores++
+	b.m[key] = value
+	return nil
+}
+
+func (b *memBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing() {
+		return errBackendDown
+	}
+	delete(b.m, key)
+	return nil
+}
+
+func (b *memBackend) BatchStore(_ context.Context, writes []BackendWrite[string, string]) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing() {
+		return errBackendDown
+	}
+	b.batches = append(b.batches, writes)
+	for _, w := range writes {
+		if w.Delete {
+			delete(b.m, w.Key)
+		} else {
+			b.m[w.Key] = w.Value
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) get(key string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.m[key]
+	return v, ok
+}
+
+func (b *memBackend) setFail(n int) {
+	b.mu.Lock()
+	b.fail = n
+	b.mu.Unlock()
+}
+
+func TestStore_WriteThrough(t *testing.T) {
+	b := newMemBackend()
+	s := New[string, string](WithWriteThrough[string, string](b))
+	defer s.Close()
+
+	_ = s.Set("a", "1")
+	if v, ok := b.get("a"); !ok || v != "1" {
+		t.Fatalf("set should reach backend, got %q %v", v, ok)
+	}
+	if _, err := s.CompareAndSwap("a", 0, "x", 0); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("want ErrVersionMismatch got %v", err)
+	}
+	if v, _ := b.get("a"); v != "1" {
+		t.Fatalf("failed CAS should not reach backend, got %q", v)
+	}
+	_, ver, _ := s.GetVersioned("a")
+	if _, err := s.CompareAndSwap("a", ver, "2", 0); err != nil {
+		t.Fatalf("cas: %v", err)
+	}
+	if v, _ := b.get("a"); v != "2" {
+		t.Fatalf("cas should reach backend, got %q", v)
+	}
+	if _, err := s.Incr("n", 2, 0); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	if v, _ := b.get("n"); v != "2" {
+		t.Fatalf("incr should reach backend, got %q", v)
+	}
+	if _, err := s.Txn(func(tx *Tx[string, string]) error {
+		tx.Set("t1", "v", 0)
+		tx.Delete("a")
+		return nil
+	}); err != nil {
+		t.Fatalf("txn: %v", err)
+	}
+	if len(b.batches) != 1 || len(b.batches[0]) != 2 {
+		t.Fatalf("txn should use one BatchStore, got %v", b.batches)
+	}
+	if _, ok := b.get("a"); ok {
+		t.Fatalf("txn delete should reach backend")
+	}
+
+	// Backend が失敗したら書き込み自体を失敗させ、ストアも変更しない
+	b.setFail(1)
+	if err := s.Set("t1", "new"); !errors.Is(err, ErrBackend) || !errors.Is(err, errBackendDown) {
+		t.Fatalf("want ErrBackend wrapping the cause, got %v", err)
+	}
+	if v, _ := s.Get("t1"); v != "v" {
+		t.Fatalf("failed write should not change store, got %q", v)
+	}
+	b.setFail(1)
+	if err := s.Delete("t1"); !errors.Is(err, ErrBackend) {
+		t.Fatalf("want ErrBackend got %v", err)
+	}
+	if _, ok := s.Get("t1"); !ok {
+		t.Fatalf("failed delete should keep the key")
+	}
+	b.setFail(1)
+	if _, err := s.Txn(func(tx *Tx[string, string]) error {
+		tx.Set("t2", "v", 0)
+		return nil
+	}); !errors.Is(err, ErrBackend) {
+		t.Fatalf("want ErrBackend got %v", err)
+	}
+	if _, ok := s.GetItem("t2"); ok {
+		t.Fatalf("failed txn should not apply")
+	}
+}
+
+func TestStore_WriteThroughReadThrough(t *testing.T) {
+	b := newMemBackend()
+	b.m["db"] = "from-backend"
+	s := New[string, string](WithWriteThrough[string, string](b)).WithEvictor(NewLRUEvictor[string, string](1))
+	defer s.Close()
+
+	if v, ok := s.Get("db"); !ok || v != "from-backend" {
+		t.Fatalf("miss should load from backend, got %q %v", v, ok)
+	}
+	if b.stores != 0 {
+		t.Fatalf("loaded value must not be written back, stores=%d", b.stores)
+	}
+	if _, ok := s.Get("missing"); ok {
+		t.Fatalf("key missing in backend should be a miss")
+	}
+
+	// Evict はキャッシュからの削除なので Backend には残る
+	_ = s.Set("other", "x")
+	if _, ok := b.get("db"); !ok {
+		t.Fatalf("eviction must not delete from backend")
+	}
+	// ストアにないキーの Delete も Backend に反映する
+	_ = s.Delete("db")
+	if _, ok := b.get("db"); ok {
+		t.Fatalf("delete should reach backend even when not cached")
+	}
+}
+
+// blockingBackend は release が閉じられるまで Store を止めるテスト用の Backend です。
+type blockingBackend struct {
+	*memBackend
+	entered chan string
+	release chan struct{}
+}
+
+func (b *blockingBackend) Store(ctx context.Context, key, value string) error {
+	b.entered <- value
+	<-b.release
+	return b.memBackend.Store(ctx, key, value)
+}
+
+func TestStore_WriteThroughReleasesShardLock(t *testing.T) {
+	b := &blockingBackend{memBackend: newMemBackend(), entered: make(chan string, 2), release: make(chan struct{})}
+	s := New[string, string](WithShards(1), WithWriteThrough[string, string](b))
+	defer s.Close()
+	s.MSet([]BatchItem[string, string]{{Key: "slow", Value: "old"}, {Key: "other", Value: "v"}})
+	if s.hashKey("slow")%keyLockStripes == s.hashKey("other")%keyLockStripes {
+		t.Fatalf("test keys must use different key locks")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = s.Set("slow", "first")
+	}()
+	<-b.entered
+
+	// Backend への書き込み中も同じシャードの読み書きは止まらない
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, _ := s.Get("slow"); v != "old" {
+			t.Errorf("in-flight write should not be visible yet, got %q", v)
+		}
+		if err := s.Delete("other"); err != nil {
+			t.Errorf("delete: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("shard lock is held during backend I/O")
+	}
+
+	// 同じキーへの書き込みは先の書き込みが終わるまで Backend に届かない
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = s.Set("slow", "second")
+	}()
+	select {
+	case v := <-b.entered:
+		t.Fatalf("write %q reached backend while another write to the same key was in flight", v)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(b.release)
+	wg.Wait()
+
+	got, _ := s.Get("slow")
+	if v, _ := b.get("slow"); v != got {
+		t.Fatalf("store and backend disagree: store=%q backend=%q", got, v)
+	}
+}
+
+func TestStore_WriteThroughExpireDuringWrite(t *testing.T) {
+	b := &blockingBackend{memBackend: newMemBackend(), entered: make(chan string, 1), release: make(chan struct{})}
+	s := New[string, string](WithWriteThrough[string, string](b))
+	defer s.Close()
+	s.MSet([]BatchItem[string, string]{{Key: "k", Value: "old"}})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = s.Set("k", "new")
+	}()
+	<-b.entered
+
+	// Backend への書き込み中の Expire は書き込みの完了を待ち、上書きで失われない
+	expired := make(chan error, 1)
+	go func() { expired <- s.Expire("k", time.Hour) }()
+	select {
+	case err := <-expired:
+		t.Fatalf("Expire should wait for the in-flight write, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(b.release)
+	wg.Wait()
+	if err := <-expired; err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if ttl, ok := s.TTL("k"); !ok || ttl <= 0 {
+		t.Fatalf("expire during a write-through should not be lost, ttl=%v ok=%v", ttl, ok)
+	}
+}
+
+func TestStore_WriteBehindCoalesce(t *testing.T) {
+	b := newMemBackend()
+	mx := metrics.NewSimple()
+	s := New[string, string](WithWriteBehind[string, string](b, time.Hour, 0), WithMetrics(mx))
+	defer s.Close()
+
+	for i := range 100 {
+		_ = s.Set("hot", strconv.Itoa(i))
+	}
+	_ = s.Set("gone", "x")
+	_ = s.Delete("gone")
+	if n := s.PendingWrites(); n != 2 || mx.WriteBehindPending.Load() != 2 {
+		t.Fatalf("writes should be coalesced per key, pending=%d gauge=%d", n, mx.WriteBehindPending.Load())
+	}
+	if _, ok := b.get("hot"); ok {
+		t.Fatalf("write-behind should not write synchronously")
+	}
+
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(b.batches) != 1 || len(b.batches[0]) != 2 {
+		t.Fatalf("want one batch of 2 writes, got %v", b.batches)
+	}
+	if v, _ := b.get("hot"); v != "99" {
+		t.Fatalf("latest value should be flushed, got %q", v)
+	}
+	if s.PendingWrites() != 0 || mx.WriteBehindPending.Load() != 0 {
+		t.Fatalf("queue should be empty after flush")
+	}
+}
+
+func TestStore_WriteBehindBatchSize(t *testing.T) {
+	b := newMemBackend()
+	s := New[string, string](WithWriteBehind[string, string](b, time.Hour, 10))
+
+	for i := range 25 {
+		_ = s.Set(strconv.Itoa(i), "v")
+	}
+	// キューがバッチサイズに達するとバックグラウンドで反映される
+	deadline := time.Now().Add(2 * time.Second)
+	for s.PendingWrites() >= 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("full batch was not flushed, pending=%d", s.PendingWrites())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Close で残りも反映する
+	s.Close()
+	for i := range 25 {
+		if _, ok := b.get(strconv.Itoa(i)); !ok {
+			t.Fatalf("key %d not flushed on close", i)
+		}
+	}
+	for _, batch := range b.batches {
+		if len(batch) > 10 {
+			t.Fatalf("batch exceeds size: %d", len(batch))
+		}
+	}
+}
+
+func TestStore_WriteBehindRetry(t *testing.T) {
+	b := newMemBackend()
+	mx := metrics.NewSimple()
+	s := New[string, string](
+		WithWriteBehind[string, string](b, time.Hour, 0),
+		WithWriteBehindRetry(2, time.Millisecond),
+		WithMetrics(mx),
+	)
+	defer s.Close()
+
+	// 再試行の範囲内で回復すれば成功する
+	_ = s.Set("a", "1")
+	b.setFail(2)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("flush should succeed after retries: %v", err)
+	}
+	if mx.WriteBehindFlushErrors.Load() != 2 {
+		t.Fatalf("want 2 flush errors got %d", mx.WriteBehindFlushErrors.Load())
+	}
+
+	// 再試行しても失敗したらキューに戻す。その間の新しい書き込みが優先される
+	_ = s.Set("b", "old")
+	b.setFail(3)
+	if err := s.Flush(context.Background()); !errors.Is(err, ErrBackend) {
+		t.Fatalf("want ErrBackend got %v", err)
+	}
+	if s.PendingWrites() != 1 {
+		t.Fatalf("failed batch should be requeued, pending=%d", s.PendingWrites())
+	}
+	_ = s.Set("b", "new")
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if v, _ := b.get("b"); v != "new" {
+		t.Fatalf("want new got %q", v)
+	}
+}
+
+func TestStore_FileBackend(t *testing.T) {
+	dir := t.TempDir()
+	fb, err := NewFileBackend[string, int](dir)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	s := New[string, int](WithWriteBehind[string, int](fb, time.Hour, 0))
+	_ = s.Set("a/b", 1) // ファイル名に使えない文字を含むキー
+	_ = s.Set("c", 2)
+	_ = s.Delete("c")
+	long := strings.Repeat("x", 1000) // 16 進表現がファイル名の上限を超えるキー
+	_ = s.Set(long, 3)
+	s.Close()
+
+	fb2, _ := NewFileBackend[string, int](dir)
+	s2 := New[string, int](WithWriteThrough[string, int](fb2))
+	defer s2.Close()
+	if v, ok := s2.Get("a/b"); !ok || v != 1 {
+		t.Fatalf("want 1 got %d %v", v, ok)
+	}
+	if _, ok := s2.Get("c"); ok {
+		t.Fatalf("deleted key should not be loaded")
+	}
+	if v, ok := s2.Get(long); !ok || v != 3 {
+		t.Fatalf("long key: want 3 got %d %v", v, ok)
+	}
+	if err := fb2.Delete(context.Background(), long); err != nil {
+		t.Fatalf("delete long key: %v", err)
+	}
+	if _, err := fb2.Load(context.Background(), long); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("long key should be deleted, got %v", err)
+	}
+	if _, err := fb2.Load(context.Background(), "c"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound got %v", err)
+	}
+
+	if _, err := Open[string, string](WithWriteThrough[string, int](fb)); err == nil {
+		t.Fatalf("expected error for mismatched backend")
+	}
+}
+
+func TestParseWriteMode(t *testing.T) {
+	for _, in := range []string{"write-through", "write-behind"} {
+		if m, err := ParseWriteMode(in); err != nil || string(m) != in {
+			t.Fatalf("parse %q: %v %v", in, m, err)
+		}
+	}
+	if _, err := ParseWriteMode("write-around"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func TestStore_MGetMSetMDelete(t *testing.T) {
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	rec := &removalRecorder{}
+	s := New[string, string](WithClock(clk), WithMetrics(mx), WithOnRemove(rec.record))
+	defer s.Close()
+
+	_ = s.Set("old", "0")
+	res := s.MSet([]BatchItem[string, string]{
+		{Key: "a", Value: "1"},
+		{Key: "old", Value: "2", TTL: time.Minute},
+		{Key: "a", Value: "3"}, // 同じキーは後のものが優先
+	})
+	if len(res) != 3 || res[0].Key != "a" || res[1].Key != "old" || res[2].Key != "a" {
+		t.Fatalf("results should follow argument order: %+v", res)
+	}
+	if res[0].Found || !res[1].Found || !res[2].Found || res[2].Version <= res[0].Version {
+		t.Fatalf("unexpected set results: %+v", res)
+	}
+	if !res[1].ExpireAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Fatalf("per-item ttl not applied: %v", res[1].ExpireAt)
+	}
+	rec.expect(t, removal{"old", "0", Replaced}, removal{"a", "1", Replaced})
+	if mx.SetNew.Load() != 2 || mx.SetUpdate.Load() != 2 {
+		t.Fatalf("set metrics: new=%d update=%d", mx.SetNew.Load(), mx.SetUpdate.Load())
+	}
+
+	got := s.MGet([]string{"a", "missing", "old"})
+	if !got[0].Found || got[0].Value != "3" || got[0].Version != res[2].Version || got[1].Found || got[1].Key != "missing" || got[2].Value != "2" {
+		t.Fatalf("unexpected get results: %+v", got)
+	}
+	if mx.GetHit.Load() != 2 || mx.GetMiss.Load() != 1 {
+		t.Fatalf("get metrics: hit=%d miss=%d", mx.GetHit.Load(), mx.GetMiss.Load())
+	}
+
+	// 期限切れのキーは遅延削除される
+	clk.Advance(time.Minute)
+	if got := s.MGet([]string{"old"}); got[0].Found {
+		t.Fatalf("expired key should be a miss")
+	}
+	rec.expect(t, removal{"old", "2", Expired})
+
+	del := s.MDelete([]string{"a", "missing", "a"})
+	if !del[0].Found || del[1].Found || del[2].Found || del[0].Err != nil {
+		t.Fatalf("unexpected delete results: %+v", del)
+	}
+	rec.expect(t, removal{"a", "3", Deleted})
+	if s.Len() != 0 {
+		t.Fatalf("want empty store got %d", s.Len())
+	}
+}
+
+func TestStore_MSetPartialFailure(t *testing.T) {
+	s := New[string, string](WithMaxBytes(1000))
+	defer s.Close()
+
+	res := s.MSet([]BatchItem[string, string]{
+		{Key: "ok", Value: "v"},
+		{Key: "big", Value: strings.Repeat("x", 1000)},
+	})
+	if res[0].Err != nil || !errors.Is(res[1].Err, ErrValueTooLarge) {
+		t.Fatalf("only the large value should fail: %v / %v", res[0].Err, res[1].Err)
+	}
+	if _, ok := s.Get("ok"); !ok {
+		t.Fatalf("other items should be set")
+	}
+
+	// write-through の失敗はシャード単位
+	b := newMemBackend()
+	s2 := New[string, string](WithShards(1), WithWriteThrough[string, string](b))
+	defer s2.Close()
+	_ = s2.Set("keep", "v")
+	b.setFail(1)
+	res = s2.MSet([]BatchItem[string, string]{{Key: "x", Value: "1"}, {Key: "y", Value: "2"}})
+	for _, r := range res {
+		if !errors.Is(r.Err, ErrBackend) {
+			t.Fatalf("want ErrBackend got %v", r.Err)
+		}
+	}
+	if got := s2.MGet([]string{"x", "y"}); got[0].Found || got[1].Found {
+		t.Fatalf("failed items must not be set")
+	}
+	b.setFail(1)
+	if del := s2.MDelete([]string{"keep"}); !errors.Is(del[0].Err, ErrBackend) {
+		t.Fatalf("want ErrBackend got %v", del[0].Err)
+	}
+	if _, ok := s2.Get("keep"); !ok {
+		t.Fatalf("failed delete should keep the key")
+	}
+
+	s2.MSet([]BatchItem[string, string]{{Key: "x", Value: "1"}, {Key: "y", Value: "2"}})
+	if len(b.batches) != 1 || len(b.batches[0]) != 2 {
+		t.Fatalf("one shard should use one BatchStore, got %v", b.batches)
+	}
+}
+
+func TestStore_BatchSliding(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	s.MSet([]BatchItem[string, string]{
+		{Key: "sess", Value: "v", TTL: 10 * time.Second, Sliding: true},
+		{Key: "abs", Value: "v", TTL: 10 * time.Second},
+	})
+	clk.Advance(8 * time.Second)
+	got := s.MGet([]string{"sess", "abs"})
+	if !got[0].ExpireAt.Equal(clk.Now().Add(10 * time.Second)) {
+		t.Fatalf("MGet should extend sliding ttl, got %v", got[0].ExpireAt)
+	}
+	clk.Advance(8 * time.Second)
+	if got := s.MGet([]string{"sess", "abs"}); !got[0].Found || got[1].Found {
+		t.Fatalf("want sliding key alive and absolute key expired: %+v", got)
+	}
+}
+
+func TestStore_BatchGroups(t *testing.T) {
+	s := New[string, string](WithShards(8))
+	defer s.Close()
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	groups := s.batchGroups(len(keys), func(i int) string { return keys[i] })
+	seen := 0
+	for j, g := range groups {
+		if j > 0 && groups[j-1].shard >= g.shard {
+			t.Fatalf("groups should be sorted by shard and unique")
+		}
+		for k, i := range g.idx {
+			if s.shardIndex(keys[i]) != g.shard || (k > 0 && g.idx[k-1] >= i) {
+				t.Fatalf("group %d has wrong or unordered index %d", g.shard, i)
+			}
+			seen++
+		}
+	}
+	if seen != len(keys) {
+		t.Fatalf("want %d keys grouped got %d", len(keys), seen)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"math/rand"
 	"runtime"
+	"strconv"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/amakane-hakari/kavos/internal/metrics"
 )
@@ -70,7 +72,7 @@ func runOneBenchmark(b *testing.B, cfg benchConfig) {
 	for i := 0; i < cfg.warmKeys; i++ {
 		k := fmt.Sprintf("k%05d", i)
 		v := fmt.Sprintf("v%05d", i)
-		st.Set(k, v)
+		_ = st.Set(k, v)
 		keys[i] = k
 	}
 
@@ -90,10 +92,10 @@ func runOneBenchmark(b *testing.B, cfg benchConfig) {
 				// 新規 or 既存更新を混合 (10% 新規: eviction 誘発)
 				if r.Intn(10) == 0 {
 					k := fmt.Sprintf("n%d_%d", r.Intn(1_000_000), i)
-					st.Set(k, "x")
+					_ = st.Set(k, "x")
 				} else {
 					k := keys[r.Intn(localLen)]
-					st.Set(k, "u")
+					_ = st.Set(k, "u")
 				}
 				setCounter.Add(1)
 			}
@@ -140,3 +142,237 @@ func BenchmarkLRUOnly(b *testing.B) {
 		ev.OnGet(k, true)
 	}
 }
+
+// zipfTrace は Zipf 分布に従うキー列を生成します（s > 1、固定シード）。
+func zipfTrace(n, keys int, s float64, seed int64) []int {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), s, 1, uint64(keys-1))
+	trace := make([]int, n)
+	for i := range trace {
+		trace[i] = int(z.Uint64())
+	}
+	return trace
+}
+
+// hitRatio はキャッシュの読み込みを模擬し、ミス時にセットしたときのヒット率を返します。
+func hitRatio[K comparable](ev Evictor[K, struct{}], trace []K) float64 {
+	resident := make(map[K]struct{})
+	hits := 0
+	for _, k := range trace {
+		_, hit := resident[k]
+		ev.OnGet(k, hit)
+		if hit {
+			hits++
+			continue
+		}
+		resident[k] = struct{}{}
+		for _, v := range ev.OnSet(k, struct{}{}, false) {
+			delete(resident, v)
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+// evictorPolicy はヒット率比較の対象となる Evictor の生成関数です。
+type evictorPolicy struct {
+	name string
+	new  func(capacity int) Evictor[string, struct{}]
+}
+
+var evictorPolicies = []evictorPolicy{
+	{"LRU", func(c int) Evictor[string, struct{}] { return NewLRUEvictor[string, struct{}](c) }},
+	{"LFU", func(c int) Evictor[string, struct{}] { return NewLFUEvictor[string, struct{}](c, WithLFUDecay(10*c)) }},
+	{"TinyLFU", func(c int) Evictor[string, struct{}] { return NewTinyLFUEvictor[string, struct{}](c) }},
+	{"ARC", func(c int) Evictor[string, struct{}] { return NewARCEvictor[string, struct{}](c) }},
+}
+
+// BenchmarkEvictorHitRatio_Zipf は Zipf 分布のトレースで各 Evictor のヒット率を比較します。
+// go test -bench=HitRatio -run=^$ ./internal/store で hit% を確認できます。
+func BenchmarkEvictorHitRatio_Zipf(b *testing.B) {
+	const (
+		capacity = 1_000
+		keys     = 100_000
+	)
+	for _, s := range []float64{1.01, 1.2} {
+		ints := zipfTrace(1_000_000, keys, s, 42)
+		trace := make([]string, len(ints))
+		for i, k := range ints {
+			trace[i] = strconv.Itoa(k)
+		}
+		for _, p := range evictorPolicies {
+			b.Run(fmt.Sprintf("s=%.2f/%s", s, p.name), func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					ratio = hitRatio(p.new(capacity), trace)
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+		}
+	}
+}
+
+// BenchmarkStore_EvictionContention は読み込み中心の並列負荷で、全体 1 つの LRU と
+// シャードごとの LRU のロック競合を比較します。
+//
+//	go test -run '^$' -bench EvictionContention -cpu=1,4,16 ./internal/store
+func BenchmarkStore_EvictionContention(b *testing.B) {
+	const (
+		shards   = 64
+		capacity = 50_000
+		keys     = 40_000
+	)
+	modes := []struct {
+		name  string
+		setup func(*Store[string, string])
+	}{
+		{"none", func(*Store[string, string]) {}},
+		{"global-lru", func(st *Store[string, string]) {
+			st.WithEvictor(NewLRUEvictor[string, string](capacity))
+		}},
+		{"sharded-lru", func(st *Store[string, string]) {
+			st.WithShardedEvictor(capacity, func(c int) Evictor[string, string] {
+				return NewLRUEvictor[string, string](c)
+			})
+		}},
+	}
+	ks := make([]string, keys)
+	for i := range ks {
+		ks[i] = fmt.Sprintf("k%05d", i)
+	}
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			st := New[string, string](WithShards(shards), WithMetrics(&metrics.Noop{}))
+			defer st.Close()
+			m.setup(st)
+			for _, k := range ks {
+				_ = st.Set(k, "v")
+			}
+			var seed atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(seed.Add(1)))
+				for pb.Next() {
+					k := ks[r.Intn(keys)]
+					if r.Intn(20) == 0 {
+						_ = st.Set(k, "u")
+					} else {
+						st.Get(k)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStore_CleanupPause は期限なしのキーを大量に持つストアで、1% のキーが期限を迎えたときの
+// TTL クリーンアップ 1 回あたりの所要時間（書き込みロックによる停止時間の合計）を測ります。
+// FullScan は比較用に、以前の実装と同じくシャードのマップ全体を走査して期限切れを探します。
+//
+//	go test -run '^$' -bench CleanupPause -benchtime 10x ./internal/store
+func BenchmarkStore_CleanupPause(b *testing.B) {
+	for _, n := range []int{1_000_000, 10_000_000} {
+		b.Run(fmt.Sprintf("keys=%dM", n/1_000_000), func(b *testing.B) {
+			if n > 1_000_000 && testing.Short() {
+				b.Skip("skipping 10M keys in short mode")
+			}
+			st := New[int, int](WithShards(64), WithMetrics(&metrics.Noop{}))
+			defer st.Close()
+			for i := 0; i < n; i++ {
+				_ = st.Set(i, i)
+			}
+			due := n / 100
+			for _, bc := range []struct {
+				name    string
+				cleanup func()
+			}{
+				{"Heap", st.scanExpired},
+				{"FullScan", func() { fullScanExpired(st) }},
+			} {
+				b.Run(bc.name, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						b.StopTimer()
+						for k := 0; k < due; k++ {
+							_ = st.SetWithTTL(n+k, k, time.Nanosecond)
+						}
+						b.StartTimer()
+						bc.cleanup()
+					}
+				})
+			}
+		})
+	}
+}
+
+// fullScanExpired はシャードごとに書き込みロックを取り、マップ全体を走査して期限切れのキーを削除します。
+func fullScanExpired[K comparable, V any](s *Store[K, V]) {
+	now := s.now().UnixNano()
+	for i := range s.shardCount() {
+		sh := s.shardAt(i)
+		sh.mu.Lock()
+		for k, e := range sh.m {
+			if e.expired(now) {
+				delete(sh.m, k)
+				s.indexRemove(k)
+				s.addBytes(-e.cost)
+				s.notifyRemoved(EventExpire, k, e.ver)
+				_ = s.aofDelete(k)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// BenchmarkStore_MGet は 200 キーの取得を Get の繰り返しと MGet で比較します。
+func BenchmarkStore_MGet(b *testing.B) {
+	s := New[string, string](WithShards(16))
+	defer s.Close()
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+		_ = s.Set(keys[i], "v")
+	}
+	b.Run("Get", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, k := range keys {
+					s.Get(k)
+				}
+			}
+		})
+	})
+	b.Run("MGet", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				s.MGet(keys)
+			}
+		})
+	})
+}
+
+// BenchmarkStore_ParallelGetMaxIdle は WithMaxIdle を設定したストアへの並列 Get を、
+// MaxIdle なしの場合と比較します。アクセスのたびに書き込みロックを取ると並列度が上がるほど差が開きます。
+//
+//	go test -run '^$' -bench ParallelGetMaxIdle -cpu=1,4,16 ./internal/store
+func BenchmarkStore_ParallelGetMaxIdle(b *testing.B) {
+	const keys = 10_000
+	ks := make([]string, keys)
+	for i := range ks {
+		ks[i] = fmt.Sprintf("k%05d", i)
+	}
+	for _, idle := range []time.Duration{0, time.Minute} {
+		b.Run(fmt.Sprintf("maxIdle=%v", idle), func(b *testing.B) {
+			st := New[string, string](WithMaxIdle(idle), WithMetrics(&metrics.Noop{}))
+			defer st.Close()
+			for _, k := range ks {
+				_ = st.Set(k, "v")
+			}
+			var seed atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(seed.Add(1)))
+				for pb.Next() {
+					st.Get(ks[r.Intn(keys)])
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore_GetVersioned(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	if _, _, ok := s.GetVersioned("a"); ok {
+		t.Fatalf("missing key should not be found")
+	}
+	_ = s.Set("a", "1")
+	_, v1, ok := s.GetVersioned("a")
+	if !ok || v1 == 0 {
+		t.Fatalf("expected version, got %d ok=%v", v1, ok)
+	}
+	_ = s.Set("b", "x")
+	_ = s.Set("a", "2")
+	val, v2, _ := s.GetVersioned("a")
+	if val != "2" || v2 <= v1 {
+		t.Fatalf("version should increase: v1=%d v2=%d", v1, v2)
+	}
+	// 削除後の再作成でもバージョンは戻らない
+	_ = s.Delete("a")
+	_ = s.Set("a", "3")
+	if _, v3, _ := s.GetVersioned("a"); v3 <= v2 {
+		t.Fatalf("version should not go back after recreate: v2=%d v3=%d", v2, v3)
+	}
+}
+
+func TestStore_CompareAndSwap(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	v1, err := s.CompareAndSwap("a", 0, "1", 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.CompareAndSwap("a", 0, "x", 0); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("create-only on existing key: want ErrVersionMismatch got %v", err)
+	}
+	v2, err := s.CompareAndSwap("a", v1, "2", 0)
+	if err != nil || v2 <= v1 {
+		t.Fatalf("swap: v=%d err=%v", v2, err)
+	}
+	if _, err := s.CompareAndSwap("a", v1, "stale", 0); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("stale version: want ErrVersionMismatch got %v", err)
+	}
+	if _, err := s.CompareAndSwap("missing", 42, "x", 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing: want ErrNotFound got %v", err)
+	}
+	if v, _ := s.Get("a"); v != "2" {
+		t.Fatalf("a want 2 got %q", v)
+	}
+
+	// 期限切れは存在しない扱い
+	_ = s.SetWithTTL("ttl", "x", 10*time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
+	if _, err := s.CompareAndSwap("ttl", 0, "y", 0); err != nil {
+		t.Fatalf("create over expired entry: %v", err)
+	}
+}
+
+func TestStore_CompareAndDelete(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	_ = s.Set("a", "1")
+	_, v, _ := s.GetVersioned("a")
+	if err := s.CompareAndDelete("a", v+1); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("want ErrVersionMismatch got %v", err)
+	}
+	if err := s.CompareAndDelete("a", v); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Fatalf("a should be deleted")
+	}
+	if err := s.CompareAndDelete("a", v); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound got %v", err)
+	}
+}
+
+func TestStore_CompareAndSwapConcurrent(t *testing.T) {
+	s := New[string, int]()
+	defer s.Close()
+	_ = s.Set("counter", 0)
+
+	const workers, incs = 8, 100
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < incs; {
+				n, ver, _ := s.GetVersioned("counter")
+				if _, err := s.CompareAndSwap("counter", ver, n+1, 0); err == nil {
+					i++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n, _ := s.Get("counter"); n != workers*incs {
+		t.Fatalf("lost update: want %d got %d", workers*incs, n)
+	}
+}
+
+func TestStore_VersionPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("a", "1")
+	_ = s.Set("a", "2")
+	_, ver, _ := s.GetVersioned("a")
+	s.Close()
+
+	s2, err := Open[string, string](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if _, got, _ := s2.GetVersioned("a"); got != ver {
+		t.Fatalf("version after replay want %d got %d", ver, got)
+	}
+	_ = s2.Set("b", "x")
+	if _, vb, _ := s2.GetVersioned("b"); vb <= ver {
+		t.Fatalf("new versions must continue after replayed ones: %d <= %d", vb, ver)
+	}
+}
@@ -3,21 +3,219 @@ package store
 import (
 	"testing"
 	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
 )
 
 func TestStore_BackgroundCleanup(t *testing.T) {
-	s := New[string, string](WithCleanupInterval(100 * time.Millisecond))
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	s := New[string, string](WithClock(clk), WithMetrics(mx), WithCleanupInterval(100*time.Millisecond))
 	defer s.Close()
 
-	s.SetWithTTL("k", "v", 30*time.Millisecond)
+	_ = s.SetWithTTL("k", "v", 30*time.Millisecond)
 
 	if _, ok := s.Get("k"); !ok {
 		t.Fatalf("should exist before expiry")
 	}
 
-	time.Sleep(70 * time.Millisecond)
+	// 期限は過ぎたがクリーンアップのティッカーはまだ発火しない
+	clk.Advance(70 * time.Millisecond)
+	if _, ok := s.shardAt(s.shardIndex("k")).m["k"]; !ok {
+		t.Fatalf("cleanup should not run before the interval")
+	}
 
+	clk.Advance(30 * time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for mx.TTLExpired.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("background cleanup did not run")
+		}
+		time.Sleep(time.Millisecond)
+	}
 	if _, ok := s.Get("k"); ok {
 		t.Fatalf("expected cleaned key")
 	}
 }
+
+func TestStore_CleanupFollowsTTLChanges(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithShards(1))
+	defer s.Close()
+
+	_ = s.SetWithTTL("short", "v", 10*time.Millisecond)
+	_ = s.SetWithTTL("extended", "v", 10*time.Millisecond)
+	_ = s.SetWithTTL("extended", "v", time.Hour) // 期限を延長
+	_ = s.SetWithTTL("persisted", "v", 10*time.Millisecond)
+	_ = s.Set("persisted", "v") // 無期限に変更
+	_ = s.SetWithTTL("recreated", "v", 10*time.Millisecond)
+	_ = s.Delete("recreated")
+	_ = s.Set("recreated", "v")
+	if _, err := s.Incr("counter", 1, 10*time.Millisecond); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	if _, err := s.Incr("counter", 1, time.Hour); err != nil { // 既存キーは期限を維持
+		t.Fatalf("incr: %v", err)
+	}
+
+	clk.Advance(20 * time.Millisecond)
+	s.scanExpired()
+
+	sh := s.shardAt(0)
+	for k, want := range map[string]bool{"short": false, "extended": true, "persisted": true, "recreated": true, "counter": false} {
+		if _, ok := sh.m[k]; ok != want {
+			t.Fatalf("%s: present=%v want %v", k, ok, want)
+		}
+	}
+	if sh.exp.len() != 1 {
+		t.Fatalf("only the extended key should remain scheduled, heap=%d", sh.exp.len())
+	}
+}
+
+func TestStore_CleanupBatchesAndCompacts(t *testing.T) {
+	clk := newFakeClock()
+	s := New[int, int](WithClock(clk), WithShards(1))
+	defer s.Close()
+
+	n := 3*expireBatch + 10
+	for i := 0; i < n; i++ {
+		_ = s.SetWithTTL(i, i, time.Millisecond)
+	}
+	_ = s.SetWithTTL(-1, 0, time.Hour)
+	clk.Advance(5 * time.Millisecond)
+	s.scanExpired()
+	if s.Len() != 1 {
+		t.Fatalf("expected all due keys removed across batches, len=%d", s.Len())
+	}
+
+	// 同じキーの期限を何度も延長すると無効な要素が溜まるが、しきい値を超えたら作り直す
+	sh := s.shardAt(0)
+	for i := 0; i < expireBatch; i++ {
+		_ = s.SetWithTTL(-1, 0, time.Hour+time.Duration(i))
+	}
+	if sh.exp.len() <= 1 {
+		t.Fatalf("expected stale heap items, heap=%d", sh.exp.len())
+	}
+	for i := 0; i < 5*expireBatch; i++ {
+		_ = s.SetWithTTL(-1, 0, time.Hour+time.Duration(i))
+	}
+	if sh.exp.len() > 2+expireBatch {
+		t.Fatalf("expected heap to be compacted, got %d", sh.exp.len())
+	}
+	if _, ok := s.Get(-1); !ok {
+		t.Fatalf("compaction must keep live keys")
+	}
+}
+
+func TestStore_ExpiryHeapBoundedWithoutCleanup(t *testing.T) {
+	// 既定の設定 (CleanupInterval=0) では expireShard が動かないので、書き込み側で作り直す
+	s := New[string, string]()
+	defer s.Close()
+
+	for i := 0; i < 100_000; i++ {
+		_ = s.SetWithTTL("k", "v", time.Hour)
+	}
+	sh := s.shardAt(s.shardIndex("k"))
+	if limit := 2*len(sh.m) + expireBatch; sh.exp.len() > limit {
+		t.Fatalf("heap should stay bounded, heap=%d limit=%d", sh.exp.len(), limit)
+	}
+	if _, ok := s.Get("k"); !ok {
+		t.Fatalf("compaction must keep live keys")
+	}
+}
+
+func TestStore_ActiveExpireCycle(t *testing.T) {
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	s := New[int, int](WithClock(clk), WithShards(4), WithMetrics(mx), WithActiveExpire(20, 0.1, time.Second))
+	defer s.Close()
+
+	for i := 0; i < 1000; i++ {
+		_ = s.SetWithTTL(i, i, time.Millisecond)
+		_ = s.Set(10000+i, i)
+	}
+	for i := 0; i < 100; i++ {
+		_ = s.SetWithTTL(20000+i, i, time.Hour)
+	}
+	clk.Advance(5 * time.Millisecond)
+	s.activeExpireCycle()
+
+	remaining := 0
+	for i := 0; i < s.shardCount(); i++ {
+		for k := range s.shardAt(i).m {
+			if k < 1000 {
+				remaining++
+			}
+		}
+	}
+	// 期限切れの割合が 10% 以下になるまで繰り返すので、残るのはごく一部
+	if remaining > 100 {
+		t.Fatalf("expected most expired keys removed, %d remain", remaining)
+	}
+	hits := mx.ActiveExpireHits.Load()
+	if hits != uint64(1000-remaining) || mx.TTLExpired.Load() != hits {
+		t.Fatalf("hits=%d ttl_expired=%d removed=%d", hits, mx.TTLExpired.Load(), 1000-remaining)
+	}
+	if mx.ActiveExpireSampled.Load() < hits {
+		t.Fatalf("sampled %d < hits %d", mx.ActiveExpireSampled.Load(), hits)
+	}
+	if mx.ActiveExpireOverruns.Load() != 0 {
+		t.Fatalf("unexpected overrun")
+	}
+	for i := 0; i < 100; i++ {
+		if _, ok := s.Get(20000 + i); !ok {
+			t.Fatalf("unexpired key %d removed", 20000+i)
+		}
+	}
+}
+
+func TestStore_ActiveExpireBudget(t *testing.T) {
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	s := New[int, int](WithClock(clk), WithShards(4), WithMetrics(mx), WithActiveExpire(1, 0.1, time.Nanosecond))
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = s.SetWithTTL(i, i, time.Millisecond)
+	}
+	clk.Advance(5 * time.Millisecond)
+	s.activeExpireCycle()
+	if mx.ActiveExpireOverruns.Load() != 1 || mx.ActiveExpireSampled.Load() != 1 {
+		t.Fatalf("expected one sample then overrun, sampled=%d overruns=%d",
+			mx.ActiveExpireSampled.Load(), mx.ActiveExpireOverruns.Load())
+	}
+	// 打ち切った次のサイクルは続きのシャードから始める
+	if s.expireCursor != 1 {
+		t.Fatalf("expected cursor 1, got %d", s.expireCursor)
+	}
+	s.activeExpireCycle()
+	if s.expireCursor != 2 {
+		t.Fatalf("expected cursor 2, got %d", s.expireCursor)
+	}
+}
+
+func TestStore_ActiveExpireDropsStaleItems(t *testing.T) {
+	s := New[int, int](WithShards(1), WithActiveExpire(100, 0.1, time.Second))
+	defer s.Close()
+
+	for i := 0; i < 50; i++ {
+		_ = s.SetWithTTL(i, i, time.Hour)
+		_ = s.Delete(i)
+	}
+	_ = s.SetWithTTL(-1, 0, time.Hour)
+	s.activeExpireCycle()
+	if n := s.shardAt(0).exp.len(); n != 1 {
+		t.Fatalf("expected stale items dropped, heap=%d", n)
+	}
+}
+
+func TestParseExpireStrategy(t *testing.T) {
+	for _, v := range []string{"heap", "sampled"} {
+		if _, err := ParseExpireStrategy(v); err != nil {
+			t.Fatalf("%s: %v", v, err)
+		}
+	}
+	if _, err := ParseExpireStrategy("wheel"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
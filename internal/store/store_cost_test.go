@@ -0,0 +1,170 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func TestStore_CostAccounting(t *testing.T) {
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	s := New[string, string](WithClock(clk), WithMetrics(mx), WithCost(func(_ string, v string) int64 {
+		return int64(len(v))
+	}))
+	defer s.Close()
+
+	_ = s.Set("a", "12345")
+	_ = s.Set("b", "123")
+	if got := s.Bytes(); got != 8 {
+		t.Fatalf("expected 8 bytes, got %d", got)
+	}
+	_ = s.Set("a", "1") // 更新は差分を反映
+	if got := s.Bytes(); got != 4 {
+		t.Fatalf("expected 4 bytes after update, got %d", got)
+	}
+	_ = s.Delete("b")
+	if got := s.Bytes(); got != 1 {
+		t.Fatalf("expected 1 byte after delete, got %d", got)
+	}
+	_ = s.SetWithTTL("c", "1234", 10*time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
+	s.Get("c") // 遅延削除
+	if got := s.Bytes(); got != 1 {
+		t.Fatalf("expected 1 byte after expiry, got %d", got)
+	}
+	if _, err := s.Incr("n", 100, 0); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	if got := s.Bytes(); got != 4 {
+		t.Fatalf("expected 4 bytes after incr, got %d", got)
+	}
+	if got := mx.StoreBytes.Load(); got != s.Bytes() {
+		t.Fatalf("metrics store bytes %d != %d", got, s.Bytes())
+	}
+}
+
+func TestStore_MaxBytesEvictsSeveralVictims(t *testing.T) {
+	mx := metrics.NewSimple()
+	s := New[string, string](WithMetrics(mx), WithMaxBytes(1000))
+	defer s.Close()
+
+	// 1 件 64+2+20 = 86 bytes を 10 件 (860 bytes)
+	for i := 0; i < 10; i++ {
+		if err := s.Set(string(rune('a'+i))+"0", strings.Repeat("v", 20)); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+	if s.Len() != 10 || s.Bytes() != 860 {
+		t.Fatalf("expected 10 keys / 860 bytes, got %d / %d", s.Len(), s.Bytes())
+	}
+
+	// 64+3+500 = 567 bytes。合計 1000 以下になるまで古い順に 5 件追い出す
+	if err := s.Set("big", strings.Repeat("x", 500)); err != nil {
+		t.Fatalf("set big: %v", err)
+	}
+	if s.Bytes() > 1000 {
+		t.Fatalf("bytes %d exceed budget", s.Bytes())
+	}
+	if _, ok := s.Get("big"); !ok {
+		t.Fatalf("big value should be stored")
+	}
+	for i, want := range []bool{false, false, false, false, false, true, true, true, true, true} {
+		if _, ok := s.Get(string(rune('a'+i)) + "0"); ok != want {
+			t.Fatalf("key %d: present=%v want %v", i, ok, want)
+		}
+	}
+	if mx.Evicted.Load() != 5 {
+		t.Fatalf("expected 5 evictions, got %d", mx.Evicted.Load())
+	}
+	if mx.StoreBytes.Load() != s.Bytes() {
+		t.Fatalf("metrics store bytes %d != %d", mx.StoreBytes.Load(), s.Bytes())
+	}
+}
+
+func TestStore_MaxBytesRejectsOversizedValue(t *testing.T) {
+	s := New[string, []byte](WithMaxBytes(128))
+	defer s.Close()
+
+	_ = s.Set("keep", []byte("v"))
+	big := make([]byte, 128)
+	if err := s.Set("big", big); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+	if _, err := s.CompareAndSwap("big", 0, big, 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("cas: expected ErrValueTooLarge, got %v", err)
+	}
+	_, err := s.Txn(func(tx *Tx[string, []byte]) error {
+		tx.Set("other", []byte("ok"), 0)
+		tx.Set("big", big, 0)
+		return nil
+	})
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("txn: expected ErrValueTooLarge, got %v", err)
+	}
+	if _, ok := s.Get("big"); ok {
+		t.Fatalf("oversized value must not be stored")
+	}
+	if _, ok := s.Get("other"); ok {
+		t.Fatalf("aborted txn must not apply other ops")
+	}
+	if _, ok := s.Get("keep"); !ok {
+		t.Fatalf("rejecting a value must not evict existing keys")
+	}
+}
+
+func TestStore_MaxBytesWithEvictors(t *testing.T) {
+	policies := map[string]func() Evictor[string, string]{
+		"lru":     func() Evictor[string, string] { return NewLRUEvictor[string, string](1000) },
+		"lfu":     func() Evictor[string, string] { return NewLFUEvictor[string, string](1000) },
+		"tinylfu": func() Evictor[string, string] { return NewTinyLFUEvictor[string, string](1000) },
+		"arc":     func() Evictor[string, string] { return NewARCEvictor[string, string](1000) },
+	}
+	for name, newEv := range policies {
+		t.Run(name, func(t *testing.T) {
+			s := New[string, string](WithMaxBytes(10_000)).WithEvictor(newEv())
+			defer s.Close()
+			for i := 0; i < 500; i++ {
+				// 値の大きさを 0〜990 bytes で変える
+				_ = s.Set(strings.Repeat("k", 1+i%7)+string(rune('a'+i%26))+string(rune('0'+i%10))+string(rune(i)),
+					strings.Repeat("v", (i*37)%1000))
+				if s.Bytes() > 10_000 {
+					t.Fatalf("bytes %d exceed budget after %d sets", s.Bytes(), i)
+				}
+			}
+			if s.Len() == 0 {
+				t.Fatalf("store should not be empty")
+			}
+		})
+	}
+
+	t.Run("sharded", func(t *testing.T) {
+		s := New[string, string](WithShards(4), WithMaxBytes(10_000)).
+			WithShardedEvictor(1000, func(c int) Evictor[string, string] { return NewLRUEvictor[string, string](c) })
+		defer s.Close()
+		for i := 0; i < 500; i++ {
+			_ = s.Set(string(rune(0x4e00+i)), strings.Repeat("v", 200))
+		}
+		if s.Bytes() > 10_000 {
+			t.Fatalf("bytes %d exceed budget", s.Bytes())
+		}
+		// 追い出しはシャードを順に回るため、どのシャードにもキーが残る
+		for i := 0; i < s.shardCount(); i++ {
+			if len(s.shardAt(i).m) == 0 {
+				t.Fatalf("shard %d was drained", i)
+			}
+		}
+	})
+}
+
+func TestOpen_CostTypeMismatch(t *testing.T) {
+	if _, err := Open[string, int](WithCost(StringCost)); err == nil {
+		t.Fatalf("expected error for mismatched cost function")
+	}
+	if _, err := Open[int, int](WithMaxBytes(100)); err == nil {
+		t.Fatalf("expected error when no cost function is available")
+	}
+}
@@ -1,18 +1,24 @@
 package store
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
 
 func TestStore_LRUEviction(t *testing.T) {
 	s := New[string, string]().WithEvictor(NewLRUEvictor[string, string](2))
 
-	s.Set("a", "1")
-	s.Set("b", "2")
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
 
 	if v, ok := s.Get("a"); !ok || v != "1" {
 		t.Fatalf("expected a")
 	}
 
-	s.Set("c", "3")
+	_ = s.Set("c", "3")
 
 	if _, ok := s.Get("b"); ok {
 		t.Fatalf("b should be evicted")
@@ -24,9 +30,246 @@ func TestStore_LRUEviction(t *testing.T) {
 		t.Fatalf("c should remain")
 	}
 
-	s.Set("d", "4")
+	_ = s.Set("d", "4")
 
 	if _, ok := s.Get("a"); ok {
 		t.Fatalf("a should evicted after adding d")
 	}
 }
+
+func TestStore_LFUEviction(t *testing.T) {
+	s := New[string, string]().WithEvictor(NewLFUEvictor[string, string](2))
+
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	s.Get("a")
+	s.Get("a")
+	s.Get("b")
+
+	_ = s.Set("c", "3") // b (頻度 2) が a (頻度 3) より先に追い出される
+	if _, ok := s.Get("b"); ok {
+		t.Fatalf("b should be evicted")
+	}
+	if _, ok := s.Get("a"); !ok {
+		t.Fatalf("a should remain")
+	}
+
+	_ = s.Set("d", "4") // c と d は頻度 1、古い c が追い出される
+	if _, ok := s.Get("c"); ok {
+		t.Fatalf("c should be evicted")
+	}
+	if _, ok := s.Get("d"); !ok {
+		t.Fatalf("d should remain")
+	}
+}
+
+func TestLFUEvictor_ScanResistant(t *testing.T) {
+	ev := NewLFUEvictor[string, int](10)
+	hot := []string{"h0", "h1", "h2", "h3", "h4"}
+	for _, k := range hot {
+		ev.OnSet(k, 0, false)
+		for i := 0; i < 3; i++ {
+			ev.OnGet(k, true)
+		}
+	}
+	// 一度きりのキーを大量に流す
+	for i := 0; i < 1000; i++ {
+		for _, v := range ev.OnSet(fmt.Sprint("scan", i), 0, false) {
+			for _, k := range hot {
+				if v == k {
+					t.Fatalf("hot key %s evicted by scan", k)
+				}
+			}
+		}
+	}
+	if ev.Size() != 10 {
+		t.Fatalf("expected size 10, got %d", ev.Size())
+	}
+
+	ev.OnDelete("h0")
+	ev.OnDelete("missing")
+	if ev.Size() != 9 {
+		t.Fatalf("expected size 9 after delete, got %d", ev.Size())
+	}
+}
+
+func TestLFUEvictor_Decay(t *testing.T) {
+	// 減衰なしでは過去の人気キーが残り続け、減衰ありではいずれ追い出される
+	for _, tc := range []struct {
+		name     string
+		opts     []LFUOption
+		wantKept bool
+	}{
+		{"no-decay", nil, true},
+		{"decay", []LFUOption{WithLFUDecay(8)}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := NewLFUEvictor[string, int](2, tc.opts...)
+			ev.OnSet("old", 0, false)
+			for i := 0; i < 20; i++ {
+				ev.OnGet("old", true)
+			}
+			// 新しいキー x を繰り返し使いつつ、他のキーを入れ替える
+			ev.OnSet("x", 0, false)
+			evicted := false
+			for i := 0; i < 200 && !evicted; i++ {
+				ev.OnGet("x", true)
+				ev.OnGet("x", true)
+				for _, v := range ev.OnSet(fmt.Sprint("y", i), 0, false) {
+					if v == "old" {
+						evicted = true
+					}
+				}
+			}
+			if evicted == tc.wantKept {
+				t.Fatalf("old evicted=%v, want kept=%v", evicted, tc.wantKept)
+			}
+		})
+	}
+}
+
+// rejectingEvictor はすべての新規キーの受け入れを拒否するテスト用 Evictor です。
+type rejectingEvictor struct{}
+
+func (rejectingEvictor) OnSet(key string, _ string, existed bool) []string {
+	if existed {
+		return nil
+	}
+	return []string{key}
+}
+func (rejectingEvictor) OnGet(string, bool) {}
+func (rejectingEvictor) OnDelete(string)    {}
+
+func TestStore_EvictorRejectsNewKey(t *testing.T) {
+	s := New[string, string]().WithEvictor(rejectingEvictor{})
+	_ = s.Set("a", "1")
+	if _, ok := s.Get("a"); ok {
+		t.Fatalf("rejected key should be deleted")
+	}
+	if s.Len() != 0 {
+		t.Fatalf("expected empty store, got %d", s.Len())
+	}
+}
+
+func TestStore_TinyLFUEviction(t *testing.T) {
+	s := New[string, string]().WithEvictor(NewTinyLFUEvictor[string, string](3))
+
+	// window 1 / main 2
+	_ = s.Set("hot", "1")
+	_ = s.Set("warm", "2")
+	_ = s.Set("x", "3")
+	for i := 0; i < 5; i++ {
+		s.Get("hot")
+		s.Get("warm")
+	}
+	// 一度きりのキーは頻度で負けて拒否され、hot / warm は残る
+	for i := 0; i < 50; i++ {
+		_ = s.Set(fmt.Sprint("scan", i), "v")
+	}
+	for _, k := range []string{"hot", "warm"} {
+		if _, ok := s.Get(k); !ok {
+			t.Fatalf("%s should survive scan", k)
+		}
+	}
+	if s.Len() != 3 {
+		t.Fatalf("expected 3 keys, got %d", s.Len())
+	}
+}
+
+func TestTinyLFUEvictor_Segments(t *testing.T) {
+	ev := NewTinyLFUEvictor[int, int](100) // window 1 / main 99 / protected 79
+	for i := 0; i < 100; i++ {
+		if v := ev.OnSet(i, 0, false); len(v) != 0 {
+			t.Fatalf("unexpected victims while filling: %v", v)
+		}
+	}
+	if ev.Size() != 100 {
+		t.Fatalf("expected size 100, got %d", ev.Size())
+	}
+	// 頻繁に読まれるキーは protected に昇格する
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 3; j++ {
+			ev.OnGet(i, true)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if seg := ev.idx[i].Value.(*tlfuItem[int]).seg; seg != segProtected {
+			t.Fatalf("key %d should be protected, got %d", i, seg)
+		}
+	}
+	// 溢れても容量は守られ、何かしらが追い出される
+	victims := ev.OnSet(1000, 0, false)
+	if len(victims) != 1 || ev.Size() != 100 {
+		t.Fatalf("expected exactly one victim, got %v size=%d", victims, ev.Size())
+	}
+	ev.OnDelete(0)
+	if ev.Size() != 99 {
+		t.Fatalf("expected 99 after delete, got %d", ev.Size())
+	}
+}
+
+func TestTinyLFUEvictor_HugeCapacity(t *testing.T) {
+	ev := NewTinyLFUEvictor[int, int](math.MaxInt)
+	if w := len(ev.sketch.rows[0]); w != cmMaxWidth {
+		t.Fatalf("sketch width should be clamped to %d, got %d", cmMaxWidth, w)
+	}
+	if ev.protectedCap <= 0 || ev.protectedCap > ev.mainCap || ev.sketch.resetAt <= 0 {
+		t.Fatalf("caps should not overflow: protected=%d main=%d resetAt=%d", ev.protectedCap, ev.mainCap, ev.sketch.resetAt)
+	}
+	for i := 0; i < 1000; i++ {
+		if v := ev.OnSet(i, 0, false); len(v) != 0 {
+			t.Fatalf("unexpected victims: %v", v)
+		}
+	}
+	ev.OnGet(1, true)
+	if ev.Size() != 1000 || ev.sketch.estimate(ev.hash(1)) == 0 {
+		t.Fatalf("evictor should keep working, size=%d", ev.Size())
+	}
+}
+
+func TestTinyLFUEvictor_BeatsLRUOnZipf(t *testing.T) {
+	trace := zipfTrace(100_000, 10_000, 1.1, 1)
+	lru := hitRatio(NewLRUEvictor[int, struct{}](500), trace)
+	tlfu := hitRatio(NewTinyLFUEvictor[int, struct{}](500), trace)
+	if tlfu <= lru {
+		t.Fatalf("expected TinyLFU hit ratio (%.3f) > LRU (%.3f)", tlfu, lru)
+	}
+}
+
+func TestStore_ShardedEviction(t *testing.T) {
+	mx := metrics.NewSimple()
+	s := New[int, int](WithShards(4), WithMetrics(mx)).WithShardedEvictor(40, func(c int) Evictor[int, int] {
+		if c != 10 {
+			t.Fatalf("expected per-shard capacity 10, got %d", c)
+		}
+		return NewLRUEvictor[int, int](c)
+	})
+
+	for i := 0; i < 1000; i++ {
+		_ = s.Set(i, i)
+	}
+	// 各シャードが 10 件ずつ保持する
+	for i := 0; i < s.shardCount(); i++ {
+		if n := len(s.shardAt(i).m); n != 10 {
+			t.Fatalf("shard %d: expected 10 keys, got %d", i, n)
+		}
+	}
+	if s.Len() != 40 || mx.LRUSize.Load() != 40 {
+		t.Fatalf("expected 40 keys, len=%d lru_size=%d", s.Len(), mx.LRUSize.Load())
+	}
+	if mx.Evicted.Load() != 960 {
+		t.Fatalf("expected 960 evictions, got %d", mx.Evicted.Load())
+	}
+
+	// 読まれたキーはシャード内で残る
+	s.Get(999)
+	for i := 1000; i < 1100; i++ {
+		if s.shardIndex(i) == s.shardIndex(999) {
+			_ = s.Set(i, i)
+			s.Get(999)
+		}
+	}
+	if _, ok := s.Get(999); !ok {
+		t.Fatalf("recently used key should remain in its shard")
+	}
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateTraces = flag.Bool("update-traces", false, "testdata/traces の合成トレースを再生成する")
+
+// hitRatioCapacity はトレース比較で使うキャッシュ容量です。
+const hitRatioCapacity = 1_000
+
+// TestEvictorHitRatioTraces は testdata/traces 以下のトレース（1 行 1 キー、.trace または .trace.gz）で
+// 各 Evictor のヒット率を比較します。結果は go test -v -run HitRatioTraces ./internal/store で表示されます。
+// 実運用で記録したトレースを同じ形式で置けば、そのまま比較に使えます。
+func TestEvictorHitRatioTraces(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping hit ratio comparison in short mode")
+	}
+	if *updateTraces {
+		writeSyntheticTraces(t, "testdata/traces")
+	}
+	paths, err := filepath.Glob("testdata/traces/*.trace*")
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no traces")
+	}
+
+	header := fmt.Sprintf("%-12s", "trace")
+	for _, p := range evictorPolicies {
+		header += fmt.Sprintf(" %8s", p.name)
+	}
+	t.Log(header)
+	for _, path := range paths {
+		trace := loadTrace(t, path)
+		ratios := make(map[string]float64, len(evictorPolicies))
+		row := fmt.Sprintf("%-12s", strings.SplitN(filepath.Base(path), ".", 2)[0])
+		for _, p := range evictorPolicies {
+			r := hitRatio(p.new(hitRatioCapacity), trace)
+			ratios[p.name] = r
+			row += fmt.Sprintf(" %7.2f%%", r*100)
+		}
+		t.Log(row)
+
+		for name, r := range ratios {
+			if r < 0 || r > 1 {
+				t.Fatalf("%s/%s: hit ratio out of range: %f", path, name, r)
+			}
+		}
+		// ARC は最近性に適応するため、どのトレースでも LRU を大きく下回らない
+		if ratios["ARC"] < ratios["LRU"]-0.01 {
+			t.Errorf("%s: ARC (%.3f) is notably worse than LRU (%.3f)", path, ratios["ARC"], ratios["LRU"])
+		}
+	}
+}
+
+func TestStore_ARCEviction(t *testing.T) {
+	s := New[string, string]().WithEvictor(NewARCEvictor[string, string](2))
+
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	s.Get("a") // a は T2 へ
+
+	_ = s.Set("c", "3") // T1 の b が追い出され B1 へ
+	if _, ok := s.Get("b"); ok {
+		t.Fatalf("b should be evicted")
+	}
+	if _, ok := s.Get("a"); !ok {
+		t.Fatalf("a should remain")
+	}
+	if _, ok := s.Get("c"); !ok {
+		t.Fatalf("c should remain")
+	}
+}
+
+func TestARCEvictor_GhostsAndAdaptation(t *testing.T) {
+	ev := NewARCEvictor[int, int](4)
+	for i := 0; i < 4; i++ {
+		ev.OnSet(i, 0, false)
+	}
+	ev.OnGet(0, true)
+	ev.OnGet(1, true) // T1 = {2, 3}, T2 = {0, 1}
+	for i := 4; i < 6; i++ {
+		if v := ev.OnSet(i, 0, false); len(v) != 1 || v[0] != i-2 {
+			t.Fatalf("expected victim %d, got %v", i-2, v)
+		}
+	}
+	// ゴーストは Size に含まれない
+	if ev.Size() != 4 || ev.b1.Len() == 0 {
+		t.Fatalf("size=%d b1=%d", ev.Size(), ev.b1.Len())
+	}
+
+	// B1 のゴーストへの再セットは T1 の目標サイズを広げ、T2 に入る
+	p := ev.p
+	ghost := ev.b1.Back().Value.(*arcItem[int]).key
+	ev.OnSet(ghost, 0, false)
+	if ev.p <= p {
+		t.Fatalf("p should grow on B1 hit: before=%d after=%d", p, ev.p)
+	}
+	if in := ev.idx[ghost].Value.(*arcItem[int]).in; in != arcT2 {
+		t.Fatalf("ghost hit should go to T2, got %d", in)
+	}
+	if ev.Size() != 4 {
+		t.Fatalf("size should stay at capacity, got %d", ev.Size())
+	}
+
+	ev.OnDelete(ghost)
+	if _, ok := ev.idx[ghost]; ok || ev.Size() != 3 {
+		t.Fatalf("deleted key should be forgotten, size=%d", ev.Size())
+	}
+	if ev.t1.Len()+ev.t2.Len()+ev.b1.Len()+ev.b2.Len() > 2*ev.cap {
+		t.Fatalf("directory exceeds 2c")
+	}
+}
+
+func loadTrace(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip %s: %v", path, err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+	var trace []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if k := strings.TrimSpace(sc.Text()); k != "" {
+			trace = append(trace, k)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return trace
+}
+
+// writeSyntheticTraces は代表的なアクセスパターンの合成トレースを書き出します。
+func writeSyntheticTraces(t *testing.T, dir string) {
+	t.Helper()
+	const n = 100_000
+	r := rand.New(rand.NewSource(7))
+	zipf := rand.NewZipf(r, 1.1, 1, 49_999)
+
+	traces := []struct {
+		name string
+		gen  func(i int) string
+	}{
+		// 偏りの強い読み込み
+		{"zipf", func(int) string { return fmt.Sprint("z", zipf.Uint64()) }},
+		// 容量より少し大きい集合の繰り返し（LRU が苦手）と人気キーの混在
+		{"loop", func(i int) string {
+			if r.Intn(4) == 0 {
+				return fmt.Sprint("z", zipf.Uint64())
+			}
+			return fmt.Sprint("l", i%(hitRatioCapacity*6/5))
+		}},
+		// 人気キーの間に一度きりの長いスキャンが挟まる
+		{"scan", func(i int) string {
+			if (i/5_000)%4 == 3 {
+				return fmt.Sprint("s", i)
+			}
+			return fmt.Sprint("z", zipf.Uint64())
+		}},
+		// 途中で人気キーの集合が入れ替わる
+		{"shift", func(i int) string {
+			return fmt.Sprint("w", i*2/n, "-", zipf.Uint64())
+		}},
+	}
+	for _, tr := range traces {
+		path := filepath.Join(dir, tr.name+".trace.gz")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("create %s: %v", path, err)
+		}
+		gz := gzip.NewWriter(f)
+		w := bufio.NewWriter(gz)
+		for i := 0; i < n; i++ {
+			// bufio.Writer の書き込みエラーは Flush で返されます
+			_, _ = fmt.Fprintln(w, tr.gen(i))
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("gzip %s: %v", path, err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("close %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore_Incr(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	if n, err := s.Incr("c", 5, 0); err != nil || n != 5 {
+		t.Fatalf("create: n=%d err=%v", n, err)
+	}
+	if n, err := s.Incr("c", -7, 0); err != nil || n != -2 {
+		t.Fatalf("decr: n=%d err=%v", n, err)
+	}
+	if v, _ := s.Get("c"); v != "-2" {
+		t.Fatalf("stored value want -2 got %q", v)
+	}
+
+	_ = s.Set("text", "abc")
+	if _, err := s.Incr("text", 1, 0); !errors.Is(err, ErrNotInteger) {
+		t.Fatalf("want ErrNotInteger got %v", err)
+	}
+	if v, _ := s.Get("text"); v != "abc" {
+		t.Fatalf("value must not change on error")
+	}
+
+	_ = s.Set("big", "9223372036854775807")
+	if _, err := s.Incr("big", 1, 0); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("want ErrOverflow got %v", err)
+	}
+}
+
+func TestStore_IncrTTLOnlyOnCreate(t *testing.T) {
+	s := New[string, int64]()
+	defer s.Close()
+
+	if _, err := s.Incr("c", 1, time.Hour); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	it, _ := s.GetItem("c")
+	if it.ExpireAt.IsZero() {
+		t.Fatalf("ttl should be set on creation")
+	}
+	if _, err := s.Incr("c", 1, time.Minute); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	it2, _ := s.GetItem("c")
+	if !it2.ExpireAt.Equal(it.ExpireAt) || it2.Value != 2 {
+		t.Fatalf("existing ttl must be kept: %v -> %v (value %d)", it.ExpireAt, it2.ExpireAt, it2.Value)
+	}
+
+	_ = s.Set("p", 10)
+	if _, err := s.Incr("p", 1, time.Hour); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	if it, _ := s.GetItem("p"); !it.ExpireAt.IsZero() {
+		t.Fatalf("ttl must not be applied to existing key")
+	}
+}
+
+func TestStore_IncrConcurrent(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				if _, err := s.Incr("c", 1, 0); err != nil {
+					t.Errorf("incr: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := s.Get("c"); v != "4000" {
+		t.Fatalf("want 4000 got %s", v)
+	}
+}
+
+func TestStore_IncrByFloat(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	_ = s.Set("f", "10")
+	if f, err := s.IncrByFloat("f", 0.5, 0); err != nil || f != 10.5 {
+		t.Fatalf("f=%v err=%v", f, err)
+	}
+	if v, _ := s.Get("f"); v != "10.5" {
+		t.Fatalf("stored value want 10.5 got %q", v)
+	}
+	if _, err := s.IncrByFloat("f", math.Inf(1), 0); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("want ErrOverflow got %v", err)
+	}
+	_ = s.Set("text", "abc")
+	if _, err := s.IncrByFloat("text", 1, 0); !errors.Is(err, ErrNotFloat) {
+		t.Fatalf("want ErrNotFloat got %v", err)
+	}
+	// 小数を持つ値に整数加算はできない
+	if _, err := s.Incr("f", 1, 0); !errors.Is(err, ErrNotInteger) {
+		t.Fatalf("want ErrNotInteger got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func TestStore_GetOrLoadSingleflight(t *testing.T) {
+	mx := metrics.NewSimple()
+	s := New[string, string](WithMetrics(mx))
+	defer s.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(_ context.Context, key string) (string, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "loaded:" + key, 0, nil
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	results := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := s.GetOrLoad(context.Background(), "k", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+			}
+			results[i] = v
+		}()
+	}
+	// 全員が待ち合わせるまで読み込みを止めておく
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 loader call, got %d", calls.Load())
+	}
+	for i, v := range results {
+		if v != "loaded:k" {
+			t.Fatalf("result %d: %q", i, v)
+		}
+	}
+	if v, ok := s.Get("k"); !ok || v != "loaded:k" {
+		t.Fatalf("loaded value should be cached, got %q %v", v, ok)
+	}
+	if mx.Loads.Load() != 1 || mx.LoadErrors.Load() != 0 || mx.LoadNanos.Load() <= 0 {
+		t.Fatalf("loads=%d errors=%d nanos=%d", mx.Loads.Load(), mx.LoadErrors.Load(), mx.LoadNanos.Load())
+	}
+}
+
+func TestStore_GetOrLoadTTL(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, int](WithClock(clk))
+	defer s.Close()
+
+	var calls int
+	loader := func(context.Context, string) (int, time.Duration, error) {
+		calls++
+		return calls, time.Minute, nil
+	}
+	for range 3 {
+		if v, err := s.GetOrLoad(context.Background(), "k", loader); err != nil || v != 1 {
+			t.Fatalf("want 1 got %d %v", v, err)
+		}
+	}
+	clk.Advance(time.Minute)
+	if v, err := s.GetOrLoad(context.Background(), "k", loader); err != nil || v != 2 {
+		t.Fatalf("expired value should be reloaded, got %d %v", v, err)
+	}
+}
+
+func TestStore_GetOrLoadNegativeTTL(t *testing.T) {
+	clk := newFakeClock()
+	mx := metrics.NewSimple()
+	s := New[string, string](WithClock(clk), WithMetrics(mx), WithNegativeTTL(time.Second))
+	defer s.Close()
+
+	errBackend := errors.New("backend down")
+	var calls int
+	loader := func(context.Context, string) (string, time.Duration, error) {
+		calls++
+		return "", 0, errBackend
+	}
+	for range 3 {
+		if _, err := s.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, errBackend) {
+			t.Fatalf("want backend error got %v", err)
+		}
+	}
+	if calls != 1 || mx.LoadErrors.Load() != 1 {
+		t.Fatalf("error should be cached: calls=%d load_errors=%d", calls, mx.LoadErrors.Load())
+	}
+
+	clk.Advance(time.Second)
+	_, _ = s.GetOrLoad(context.Background(), "k", loader)
+	if calls != 2 {
+		t.Fatalf("negative entry should expire, calls=%d", calls)
+	}
+
+	// セットされたキーは負のキャッシュを破棄する
+	_ = s.Set("k", "v")
+	_ = s.Delete("k")
+	if _, err := s.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, errBackend) || calls != 3 {
+		t.Fatalf("set should clear negative entry: calls=%d err=%v", calls, err)
+	}
+
+	clk.Advance(time.Second)
+	s.purgeNegative()
+	if n := len(s.loads.negative); n != 0 {
+		t.Fatalf("expired negative entries should be purged, got %d", n)
+	}
+}
+
+func TestStore_GetOrLoadWithoutNegativeTTL(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	var calls int
+	loader := func(context.Context, string) (string, time.Duration, error) {
+		calls++
+		return "", 0, errors.New("fail")
+	}
+	_, _ = s.GetOrLoad(context.Background(), "k", loader)
+	_, _ = s.GetOrLoad(context.Background(), "k", loader)
+	if calls != 2 {
+		t.Fatalf("errors should not be cached by default, calls=%d", calls)
+	}
+	if _, err := s.GetOrLoad(context.Background(), "k", nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("no loader: want ErrNotFound got %v", err)
+	}
+}
+
+func TestStore_WithLoaderGet(t *testing.T) {
+	var calls atomic.Int32
+	s := New[string, int](WithLoader(func(_ context.Context, key string) (int, time.Duration, error) {
+		calls.Add(1)
+		if key == "bad" {
+			return 0, 0, errors.New("not a number")
+		}
+		n, err := strconv.Atoi(key)
+		return n * 10, 0, err
+	}))
+	defer s.Close()
+
+	if v, ok := s.Get("4"); !ok || v != 40 {
+		t.Fatalf("Get should load, got %d %v", v, ok)
+	}
+	if v, ok := s.Get("4"); !ok || v != 40 || calls.Load() != 1 {
+		t.Fatalf("second Get should hit, got %d %v calls=%d", v, ok, calls.Load())
+	}
+	if _, ok := s.Get("bad"); ok {
+		t.Fatalf("failed load should be a miss")
+	}
+	if v, err := s.GetOrLoad(context.Background(), "7", nil); err != nil || v != 70 {
+		t.Fatalf("GetOrLoad(nil) should use default loader, got %d %v", v, err)
+	}
+
+	if _, err := Open[string, string](WithLoader(func(context.Context, string) (int, time.Duration, error) {
+		return 0, 0, nil
+	})); err == nil {
+		t.Fatalf("expected error for mismatched loader")
+	}
+}
+
+func TestStore_GetItemOrLoad(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithLoader(func(_ context.Context, key string) (string, time.Duration, error) {
+		if key == "missing" {
+			return "", 0, ErrNotFound
+		}
+		return "loaded-" + key, time.Minute, nil
+	}))
+	defer s.Close()
+
+	it, err := s.GetItemOrLoad(context.Background(), "k")
+	if err != nil || it.Value != "loaded-k" || it.Version == 0 || !it.ExpireAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Fatalf("should load with metadata, got %+v %v", it, err)
+	}
+	if again, err := s.GetItemOrLoad(context.Background(), "k"); err != nil || again != it {
+		t.Fatalf("second call should hit, got %+v %v", again, err)
+	}
+	if _, err := s.GetItemOrLoad(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound got %v", err)
+	}
+
+	plain := New[string, string]()
+	defer plain.Close()
+	if _, err := plain.GetItemOrLoad(context.Background(), "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("no loader: want ErrNotFound got %v", err)
+	}
+}
+
+func TestStore_GetOrLoadKeepsConcurrentSet(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(context.Context, string) (string, time.Duration, error) {
+		close(started)
+		<-release
+		return "stale", 0, nil
+	}
+	const n = 5
+	results := make(chan string, n)
+	for range n {
+		go func() {
+			v, err := s.GetOrLoad(context.Background(), "k", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+			}
+			results <- v
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond) // 待機中の呼び出しが揃うのを待つ
+	// 読み込み中に別の goroutine が書き込んだ
+	_ = s.Set("k", "newer")
+	close(release)
+
+	for range n {
+		if v := <-results; v != "newer" {
+			t.Fatalf("caller should receive the concurrently set value, got %q", v)
+		}
+	}
+	if cur, _ := s.Get("k"); cur != "newer" {
+		t.Fatalf("concurrent set should win, got %q", cur)
+	}
+}
+
+func TestStore_GetOrLoadWaiterContext(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, _ := s.GetOrLoad(context.Background(), "k", func(context.Context, string) (string, time.Duration, error) {
+			close(started)
+			<-release
+			return "v", 0, nil
+		})
+		done <- v
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.GetOrLoad(ctx, "k", func(context.Context, string) (string, time.Duration, error) {
+		t.Errorf("waiter must not call its own loader")
+		return "", 0, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled got %v", err)
+	}
+
+	close(release)
+	if v := <-done; v != "v" {
+		t.Fatalf("leader should finish loading, got %q", v)
+	}
+}
+
+func TestStore_GetOrLoadPanic(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { _ = recover() }()
+		_, _ = s.GetOrLoad(context.Background(), "k", func(context.Context, string) (string, time.Duration, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	errCh := make(chan error)
+	go func() {
+		_, err := s.GetOrLoad(context.Background(), "k", func(context.Context, string) (string, time.Duration, error) {
+			return "unused", 0, nil
+		})
+		errCh <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-errCh; !errors.Is(err, errLoaderPanicked) {
+		t.Fatalf("waiter should observe panic as error, got %v", err)
+	}
+}
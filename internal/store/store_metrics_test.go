@@ -17,14 +17,15 @@ func newTestMetrics() (*testMetrics, *metrics.Simple) {
 }
 
 func TestStore_MetricsBasic(t *testing.T) {
+	clk := newFakeClock()
 	tm, simple := newTestMetrics()
-	s := New[string, string](WithMetrics(tm.m))
-	s.Set("a", "1")
-	s.Set("a", "2")
-	s.SetWithTTL("b", "3", 30*time.Millisecond)
+	s := New[string, string](WithClock(clk), WithMetrics(tm.m))
+	_ = s.Set("a", "1")
+	_ = s.Set("a", "2")
+	_ = s.SetWithTTL("b", "3", 30*time.Millisecond)
 	_, _ = s.Get("a")
 	_, _ = s.Get("missing")
-	time.Sleep(40 * time.Millisecond)
+	clk.Advance(40 * time.Millisecond)
 	_, _ = s.Get("b")
 
 	if simple.SetNew.Load() != 2 {
@@ -48,19 +49,19 @@ func TestStore_LRUSizeMetric(t *testing.T) {
 	mx := metrics.NewSimple()
 	st := New[string, string](WithMetrics(mx)).WithEvictor(NewLRUEvictor[string, string](2))
 
-	st.Set("a", "1")
+	_ = st.Set("a", "1")
 	if mx.LRUSize.Load() != 1 {
 		t.Fatalf("LRUSize want 1 got %d", mx.LRUSize.Load())
 	}
-	st.Set("b", "2")
+	_ = st.Set("b", "2")
 	if mx.LRUSize.Load() != 2 {
 		t.Fatalf("LRUSize want 2 got %d", mx.LRUSize.Load())
 	}
-	st.Set("c", "3")
+	_ = st.Set("c", "3")
 	if mx.LRUSize.Load() != 2 {
 		t.Fatalf("LRUSize want 2 got %d", mx.LRUSize.Load())
 	}
-	st.Delete("b")
+	_ = st.Delete("b")
 	if mx.LRUSize.Load() != 1 {
 		t.Fatalf("LRUSize want 1 got %d", mx.LRUSize.Load())
 	}
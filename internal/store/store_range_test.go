@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func rangeKeys[K comparable, V any](t *testing.T, s *Store[K, V], start, end K, limit int, reverse bool) []K {
+	t.Helper()
+	items, err := s.Range(start, end, limit, reverse)
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	keys := make([]K, len(items))
+	for i, it := range items {
+		keys[i] = it.Key
+	}
+	return keys
+}
+
+func TestStore_RangeDisabled(t *testing.T) {
+	s := New[string, string]()
+	defer s.Close()
+	if _, err := s.Range("", "", 10, false); !errors.Is(err, ErrOrderedIndexDisabled) {
+		t.Fatalf("expected ErrOrderedIndexDisabled, got %v", err)
+	}
+}
+
+func TestStore_Range(t *testing.T) {
+	s := New[string, string](WithOrderedIndex())
+	defer s.Close()
+
+	for _, k := range []string{"m:03", "m:01", "m:05", "m:02", "m:04", "a", "z"} {
+		_ = s.Set(k, "v-"+k)
+	}
+
+	if got := fmt.Sprint(rangeKeys(t, s, "m:", "m;", 0, false)); got != "[m:01 m:02 m:03 m:04 m:05]" {
+		t.Fatalf("forward: %s", got)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, "m:02", "m:05", 0, false)); got != "[m:02 m:03 m:04]" {
+		t.Fatalf("start inclusive / end exclusive: %s", got)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, "m:", "m;", 2, true)); got != "[m:05 m:04]" {
+		t.Fatalf("reverse limit: %s", got)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, "", "", 0, false)); got != "[a m:01 m:02 m:03 m:04 m:05 z]" {
+		t.Fatalf("unbounded: %s", got)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, "m:04", "", 0, true)); got != "[z m:05 m:04]" {
+		t.Fatalf("reverse unbounded end: %s", got)
+	}
+
+	items, _ := s.Range("m:01", "m:02", 0, false)
+	if len(items) != 1 || items[0].Value != "v-m:01" || items[0].Version == 0 {
+		t.Fatalf("unexpected item: %+v", items)
+	}
+
+	_ = s.Delete("m:03")
+	_ = s.Set("m:01", "updated")
+	if got := fmt.Sprint(rangeKeys(t, s, "m:", "m;", 0, false)); got != "[m:01 m:02 m:04 m:05]" {
+		t.Fatalf("after delete: %s", got)
+	}
+}
+
+func TestStore_RangeIntKeys(t *testing.T) {
+	s := New[int, int](WithOrderedIndex())
+	defer s.Close()
+
+	for _, k := range []int{10, -5, 3, 0, 7} {
+		_ = s.Set(k, k)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, 0, 0, 0, false)); got != "[-5 0 3 7 10]" {
+		t.Fatalf("numeric order: %s", got)
+	}
+	if got := fmt.Sprint(rangeKeys(t, s, 3, 10, 0, true)); got != "[7 3]" {
+		t.Fatalf("numeric reverse: %s", got)
+	}
+}
+
+func TestStore_RangeSkipsExpiredAndEvicted(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithOrderedIndex())
+	defer s.Close()
+	s.WithEvictor(NewLRUEvictor[string, string](3))
+
+	_ = s.SetWithTTL("k1", "v", 10*time.Millisecond)
+	_ = s.Set("k2", "v")
+	_ = s.Set("k3", "v")
+	clk.Advance(20 * time.Millisecond)
+	if got := fmt.Sprint(rangeKeys(t, s, "", "", 0, false)); got != "[k2 k3]" {
+		t.Fatalf("expired should be skipped: %s", got)
+	}
+
+	// k1 の枠は期限切れのまま残っているため、k4 で k1 が、k5 で k2 が追い出される
+	_ = s.Set("k4", "v")
+	_ = s.Set("k5", "v")
+	if got := fmt.Sprint(rangeKeys(t, s, "", "", 0, false)); got != "[k3 k4 k5]" {
+		t.Fatalf("evicted should be removed: %s", got)
+	}
+	if n := s.index.len(); n != 3 {
+		t.Fatalf("index should shrink with evictions, len=%d", n)
+	}
+}
+
+func TestStore_RangeLargeBatches(t *testing.T) {
+	s := New[string, int](WithOrderedIndex())
+	defer s.Close()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		_ = s.Set(fmt.Sprintf("k%05d", i), i)
+	}
+	for i := 0; i < n; i += 2 {
+		_ = s.Delete(fmt.Sprintf("k%05d", i))
+	}
+	for _, reverse := range []bool{false, true} {
+		keys := rangeKeys(t, s, "", "", n, reverse)
+		if len(keys) != n/2 {
+			t.Fatalf("reverse=%v: expected %d keys, got %d", reverse, n/2, len(keys))
+		}
+		sorted := sort.SliceIsSorted(keys, func(i, j int) bool {
+			if reverse {
+				return keys[i] > keys[j]
+			}
+			return keys[i] < keys[j]
+		})
+		if !sorted {
+			t.Fatalf("reverse=%v: keys not ordered", reverse)
+		}
+	}
+}
+
+func TestStore_RangeRestoredFromAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.aof")
+	s, err := Open[string, string](WithAOF(path, FsyncAlways), WithOrderedIndex())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Set("b", "2")
+	_ = s.Set("a", "1")
+	_ = s.Set("c", "3")
+	_ = s.Delete("b")
+	s.Close()
+
+	s2, err := Open[string, string](WithAOF(path, FsyncAlways), WithOrderedIndex())
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if got := fmt.Sprint(rangeKeys(t, s2, "", "", 0, false)); got != "[a c]" {
+		t.Fatalf("restored: %s", got)
+	}
+}
+
+func TestStore_RangeConcurrent(t *testing.T) {
+	s := New[int, int](WithOrderedIndex(), WithShards(8))
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(seed, seed))
+			for i := 0; i < 2000; i++ {
+				k := r.IntN(200)
+				if r.IntN(3) == 0 {
+					_ = s.Delete(k)
+				} else {
+					_ = s.Set(k, i)
+				}
+				if i%100 == 0 {
+					_, _ = s.Range(0, 0, 50, r.IntN(2) == 0)
+				}
+			}
+		}(uint64(g))
+	}
+	wg.Wait()
+
+	// 索引とシャードの内容が一致している
+	keys := rangeKeys(t, s, 0, 0, 1000, false)
+	if len(keys) != s.Len() || s.index.len() != s.Len() {
+		t.Fatalf("index out of sync: range=%d index=%d len=%d", len(keys), s.index.len(), s.Len())
+	}
+	for _, k := range keys {
+		if _, ok := s.Get(k); !ok {
+			t.Fatalf("missing %d", k)
+		}
+	}
+}
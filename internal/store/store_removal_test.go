@@ -0,0 +1,207 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type removal struct {
+	key    string
+	val    string
+	reason RemovalReason
+}
+
+// removalRecorder は OnRemove の呼び出しを記録します。
+type removalRecorder struct {
+	mu  sync.Mutex
+	got []removal
+}
+
+func (r *removalRecorder) record(key, val string, reason RemovalReason) {
+	r.mu.Lock()
+	r.got = append(r.got, removal{key, val, reason})
+	r.mu.Unlock()
+}
+
+func (r *removalRecorder) take() []removal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	got := r.got
+	r.got = nil
+	return got
+}
+
+func (r *removalRecorder) expect(t *testing.T, want ...removal) {
+	t.Helper()
+	got := r.take()
+	if len(got) != len(want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want %v got %v", want, got)
+		}
+	}
+}
+
+func TestStore_OnRemoveReasons(t *testing.T) {
+	clk := newFakeClock()
+	rec := &removalRecorder{}
+	s := New[string, string](WithClock(clk), WithOnRemove(rec.record))
+	defer s.Close()
+
+	_ = s.Set("a", "1")
+	rec.expect(t)
+	_ = s.Set("a", "2")
+	rec.expect(t, removal{"a", "1", Replaced})
+	_ = s.Delete("a")
+	rec.expect(t, removal{"a", "2", Deleted})
+	_ = s.Delete("a") // 存在しないキーでは呼ばれない
+	rec.expect(t)
+
+	_, ver, _ := s.GetVersioned("a")
+	ver, _ = s.CompareAndSwap("c", ver, "c1", 0)
+	ver, _ = s.CompareAndSwap("c", ver, "c2", 0)
+	rec.expect(t, removal{"c", "c1", Replaced})
+	_ = s.CompareAndDelete("c", ver)
+	rec.expect(t, removal{"c", "c2", Deleted})
+
+	_ = s.Set("n", "1")
+	_, _ = s.Incr("n", 1, 0)
+	rec.expect(t, removal{"n", "1", Replaced})
+	_, _ = s.Txn(func(tx *Tx[string, string]) error {
+		tx.Set("n", "x", 0)
+		tx.Delete("n")
+		return nil
+	})
+	rec.expect(t, removal{"n", "2", Replaced}, removal{"n", "x", Deleted})
+
+	// 期限切れのエントリは、上書きや削除で取り除かれても Expired
+	_ = s.SetWithTTL("t1", "v", time.Second)
+	_ = s.SetWithTTL("t2", "v", time.Second)
+	_ = s.SetWithTTL("t3", "v", time.Second)
+	clk.Advance(time.Second)
+	s.Get("t1")
+	_ = s.Set("t2", "new")
+	_ = s.Delete("t3")
+	rec.expect(t, removal{"t1", "v", Expired}, removal{"t2", "v", Expired}, removal{"t3", "v", Expired})
+}
+
+func TestStore_OnRemoveEvictedAndCleanup(t *testing.T) {
+	clk := newFakeClock()
+	rec := &removalRecorder{}
+	s := New[string, string](WithClock(clk), WithCleanupInterval(time.Second), WithOnRemove(rec.record)).
+		WithEvictor(NewLRUEvictor[string, string](2))
+	defer s.Close()
+
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	_ = s.Set("c", "3")
+	rec.expect(t, removal{"a", "1", Evicted})
+
+	_ = s.SetWithTTL("b", "ttl", time.Second)
+	rec.expect(t, removal{"b", "2", Replaced})
+	clk.Advance(time.Second)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if got := rec.take(); len(got) > 0 {
+			if len(got) != 1 || got[0] != (removal{"b", "ttl", Expired}) {
+				t.Fatalf("unexpected removals %v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cleanup did not report the expired key")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_OnRemoveExactlyOnce(t *testing.T) {
+	for _, strategy := range []ExpireStrategy{ExpireHeap, ExpireSampled} {
+		t.Run(string(strategy), func(t *testing.T) {
+			clk := newFakeClock()
+			var mu sync.Mutex
+			counts := map[string]int{}
+			s := New[string, string](WithClock(clk), WithExpireStrategy(strategy), WithOnRemove(func(key, _ string, reason RemovalReason) {
+				if reason != Expired {
+					t.Errorf("unexpected reason %v for %s", reason, key)
+				}
+				mu.Lock()
+				counts[key]++
+				mu.Unlock()
+			}))
+			defer s.Close()
+
+			const n = 2000
+			for i := range n {
+				_ = s.SetWithTTL(strconv.Itoa(i), "v", time.Second)
+			}
+			clk.Advance(time.Second)
+
+			// 遅延削除・クリーンアップが同時に同じキーを取り除こうとしても 1 回だけ呼ばれる
+			var wg sync.WaitGroup
+			for g := range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := g; i < n; i += 2 {
+						s.Get(strconv.Itoa(i))
+					}
+				}()
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if strategy == ExpireSampled {
+					for range 50 {
+						s.activeExpireCycle()
+					}
+				} else {
+					s.scanExpired()
+				}
+			}()
+			wg.Wait()
+			s.scanExpired()
+
+			if len(counts) != n {
+				t.Fatalf("want %d removed keys got %d", n, len(counts))
+			}
+			for k, c := range counts {
+				if c != 1 {
+					t.Fatalf("key %s reported %d times", k, c)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_OnRemoveOutsideLock(t *testing.T) {
+	var s *Store[string, string]
+	calls := 0
+	s = New[string, string](WithOnRemove(func(key, _ string, reason RemovalReason) {
+		// シャードロック下で呼ばれていればデッドロックする
+		s.Len()
+		if key == "a" && reason == Deleted {
+			_ = s.Set("archived:a", "x")
+		}
+		calls++
+	}))
+	defer s.Close()
+
+	_ = s.Set("a", "1")
+	_ = s.Set("a", "2")
+	_ = s.Delete("a")
+	if calls != 2 {
+		t.Fatalf("want 2 calls got %d", calls)
+	}
+	if _, ok := s.Get("archived:a"); !ok {
+		t.Fatalf("callback should be able to write to the store")
+	}
+
+	if _, err := Open[string, string](WithOnRemove(func(string, int, RemovalReason) {})); err == nil {
+		t.Fatalf("expected error for mismatched callback")
+	}
+}
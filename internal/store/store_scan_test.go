@@ -0,0 +1,198 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func scanAll[K comparable, V any](s *Store[K, V], match string, count int) []K {
+	var all []K
+	var cursor uint64
+	for {
+		keys, next := s.Scan(cursor, match, count)
+		all = append(all, keys...)
+		if next == 0 {
+			return all
+		}
+		cursor = next
+	}
+}
+
+func TestStore_ScanAll(t *testing.T) {
+	s := New[string, int](WithShards(4))
+	defer s.Close()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		_ = s.Set(fmt.Sprintf("k%04d", i), i)
+	}
+
+	keys := scanAll(s, "", 7)
+	if len(keys) != n {
+		t.Fatalf("expected %d keys, got %d", n, len(keys))
+	}
+	seen := make(map[string]bool, n)
+	for _, k := range keys {
+		if seen[k] {
+			t.Fatalf("duplicate key %q", k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestStore_ScanHonorsCount(t *testing.T) {
+	s := New[string, int](WithShards(4))
+	defer s.Close()
+
+	const n = 20_000
+	for i := 0; i < n; i++ {
+		_ = s.Set(fmt.Sprintf("k%05d", i), i)
+	}
+	// ストアが大きくても 1 ページは count 件を超えない
+	seen := make(map[string]bool, n)
+	var cursor uint64
+	for {
+		keys, next := s.Scan(cursor, "", 10)
+		if len(keys) > 10 {
+			t.Fatalf("page exceeds count: %d keys", len(keys))
+		}
+		if next != 0 && len(keys) != 10 {
+			t.Fatalf("non-final page should be full, got %d keys", len(keys))
+		}
+		for _, k := range keys {
+			if seen[k] {
+				t.Fatalf("duplicate key %q", k)
+			}
+			seen[k] = true
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != n {
+		t.Fatalf("expected %d keys, got %d", n, len(seen))
+	}
+}
+
+func TestStore_ScanMatch(t *testing.T) {
+	s := New[string, int]()
+	defer s.Close()
+
+	_ = s.Set("user:1", 1)
+	_ = s.Set("user:2", 2)
+	_ = s.Set("users/3", 3)
+	_ = s.Set("order:1", 4)
+
+	keys := scanAll(s, "user:*", 100)
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestStore_ScanIntKeys(t *testing.T) {
+	s := New[int, int](WithShards(2))
+	defer s.Close()
+
+	for i := 0; i < 200; i++ {
+		_ = s.Set(i, i)
+	}
+	// 整数キーでも 1 ページに偏らず分割される
+	keys, next := s.Scan(0, "", 10)
+	if next == 0 || len(keys) >= 200 {
+		t.Fatalf("expected partial page, got %d keys next=%d", len(keys), next)
+	}
+	if got := len(scanAll(s, "1?", 10)); got != 10 {
+		t.Fatalf("expected 10 keys matching 1?, got %d", got)
+	}
+}
+
+func TestStore_ScanSkipsExpired(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, int](WithClock(clk))
+	defer s.Close()
+
+	_ = s.Set("live", 1)
+	_ = s.SetWithTTL("gone", 2, 10*time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
+
+	keys := scanAll(s, "", 10)
+	if len(keys) != 1 || keys[0] != "live" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestStore_ScanConcurrentModification(t *testing.T) {
+	s := New[string, int](WithShards(8))
+	defer s.Close()
+
+	const stable = 500
+	for i := 0; i < stable; i++ {
+		_ = s.Set(fmt.Sprintf("stable%d", i), i)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			k := fmt.Sprintf("churn%d", i%100)
+			_ = s.Set(k, i)
+			_ = s.Delete(k)
+		}
+	}()
+
+	keys := scanAll(s, "stable*", 5)
+	close(stop)
+	wg.Wait()
+
+	seen := make(map[string]int)
+	for _, k := range keys {
+		seen[k]++
+	}
+	for i := 0; i < stable; i++ {
+		if c := seen[fmt.Sprintf("stable%d", i)]; c != 1 {
+			t.Fatalf("stable%d returned %d times", i, c)
+		}
+	}
+}
+
+func TestStore_All(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, int](WithClock(clk))
+	defer s.Close()
+
+	_ = s.Set("a", 1)
+	_ = s.Set("b", 2)
+	_ = s.SetWithTTL("c", 3, 10*time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
+
+	got := map[string]int{}
+	for k, v := range s.All() {
+		got[k] = v
+	}
+	if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Fatalf("unexpected entries: %v", got)
+	}
+
+	// 途中で打ち切れる・ループ内で更新できる
+	n := 0
+	for k := range s.All() {
+		_ = s.Delete(k)
+		n++
+		break
+	}
+	if n != 1 || s.Len() != 1 {
+		t.Fatalf("expected early break and delete, n=%d len=%d", n, s.Len())
+	}
+}
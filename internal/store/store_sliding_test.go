@@ -0,0 +1,202 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_SlidingTTL(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	_ = s.SetWithSlidingTTL("sess", "v", 10*time.Second)
+	_ = s.SetWithTTL("abs", "v", 10*time.Second)
+	for range 3 {
+		clk.Advance(8 * time.Second)
+		if _, ok := s.Get("sess"); !ok {
+			t.Fatalf("sliding key should be extended by each Get")
+		}
+		if ttl, _ := s.TTL("sess"); ttl != 10*time.Second {
+			t.Fatalf("want ttl reset to 10s got %v", ttl)
+		}
+	}
+	if _, ok := s.Get("abs"); ok {
+		t.Fatalf("absolute ttl must not be extended by Get")
+	}
+	// GetItem の期限も延長後の値
+	clk.Advance(time.Second)
+	if it, _ := s.GetItem("sess"); !it.ExpireAt.Equal(clk.Now().Add(10 * time.Second)) {
+		t.Fatalf("unexpected expire at %v", it.ExpireAt)
+	}
+	// TTL はアクセスとして扱わない
+	clk.Advance(9 * time.Second)
+	s.TTL("sess")
+	clk.Advance(time.Second)
+	if _, ok := s.Get("sess"); ok {
+		t.Fatalf("sliding key should expire after ttl without access")
+	}
+
+	ver, err := s.CompareAndSwapSliding("cas", 0, "v", 10*time.Second)
+	if err != nil {
+		t.Fatalf("cas: %v", err)
+	}
+	clk.Advance(8 * time.Second)
+	s.Get("cas")
+	clk.Advance(8 * time.Second)
+	if _, cur, ok := s.GetVersioned("cas"); !ok || cur != ver {
+		t.Fatalf("sliding cas key should be extended without changing version")
+	}
+
+	// Expire で通常の期限に戻る
+	_ = s.Expire("cas", 10*time.Second)
+	clk.Advance(8 * time.Second)
+	s.Get("cas")
+	clk.Advance(2 * time.Second)
+	if _, ok := s.Get("cas"); ok {
+		t.Fatalf("Expire should turn off sliding")
+	}
+}
+
+func TestStore_MaxIdle(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithMaxIdle(10*time.Second))
+	defer s.Close()
+
+	_ = s.Set("idle", "v")
+	_ = s.Set("active", "1")
+	_ = s.SetWithTTL("short", "v", 15*time.Second)
+	_ = s.SetWithSlidingTTL("sliding", "v", time.Minute)
+	if ttl, _ := s.TTL("idle"); ttl != 10*time.Second {
+		t.Fatalf("max-idle should apply to keys without ttl, got %v", ttl)
+	}
+	for range 2 {
+		clk.Advance(8 * time.Second)
+		s.Get("active")
+		s.Get("short")
+		s.Get("sliding")
+	}
+	if _, ok := s.Get("idle"); ok {
+		t.Fatalf("idle key should expire")
+	}
+	if _, ok := s.Get("short"); ok {
+		t.Fatalf("absolute ttl should win over idle extension")
+	}
+	if ttl, _ := s.TTL("sliding"); ttl != 10*time.Second {
+		t.Fatalf("idle time should bound sliding ttl, got %v", ttl)
+	}
+
+	// 書き込みと Touch もアクセス
+	clk.Advance(8 * time.Second)
+	_, _ = s.Incr("active", 0, 0)
+	clk.Advance(8 * time.Second)
+	_ = s.Touch("active")
+	clk.Advance(8 * time.Second)
+	if _, ok := s.Get("active"); !ok {
+		t.Fatalf("writes and Touch should count as access")
+	}
+	// Persist してもアイドル期限は残る
+	_ = s.Persist("active")
+	clk.Advance(10 * time.Second)
+	if _, ok := s.Get("active"); ok {
+		t.Fatalf("max-idle should still apply after Persist")
+	}
+}
+
+func TestStore_SlidingExtensionThrottled(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	_ = s.SetWithSlidingTTL("k", "v", 10*time.Second)
+	set, _ := s.GetItem("k")
+
+	// ttl の 1% (100ms) 未満しか延びないアクセスでは期限を書き換えない
+	clk.Advance(50 * time.Millisecond)
+	if it, _ := s.GetItem("k"); !it.ExpireAt.Equal(set.ExpireAt) {
+		t.Fatalf("small extension should be skipped, got %v want %v", it.ExpireAt, set.ExpireAt)
+	}
+	res := s.MGet([]string{"k"})
+	if !res[0].ExpireAt.Equal(set.ExpireAt) {
+		t.Fatalf("MGet should skip small extension too, got %v", res[0].ExpireAt)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if it, _ := s.GetItem("k"); !it.ExpireAt.Equal(clk.Now().Add(10 * time.Second)) {
+		t.Fatalf("extension should apply once it exceeds the step, got %v", it.ExpireAt)
+	}
+
+	// 絶対期限に達する延長は幅が小さくても行う
+	s2 := New[string, string](WithClock(clk), WithMaxIdle(time.Hour))
+	defer s2.Close()
+	_ = s2.SetWithTTL("capped", "v", time.Hour+time.Second)
+	clk.Advance(2 * time.Second)
+	if it, _ := s2.GetItem("capped"); !it.ExpireAt.Equal(clk.Now().Add(time.Hour - time.Second)) {
+		t.Fatalf("extension reaching the deadline should apply, got %v", it.ExpireAt)
+	}
+}
+
+func TestStore_SlidingCleanup(t *testing.T) {
+	for _, strategy := range []ExpireStrategy{ExpireHeap, ExpireSampled} {
+		t.Run(string(strategy), func(t *testing.T) {
+			clk := newFakeClock()
+			rec := &removalRecorder{}
+			s := New[string, string](WithClock(clk), WithExpireStrategy(strategy), WithMaxIdle(time.Minute), WithOnRemove(rec.record))
+			defer s.Close()
+			cleanup := func() {
+				if strategy == ExpireSampled {
+					for range 20 {
+						s.activeExpireCycle()
+					}
+				}
+				s.scanExpired()
+			}
+
+			_ = s.SetWithSlidingTTL("hot", "v", 10*time.Second)
+			_ = s.SetWithSlidingTTL("cold", "v", 10*time.Second)
+			_ = s.Set("idle", "v")
+			for range 3 {
+				clk.Advance(8 * time.Second)
+				s.Get("hot")
+				cleanup()
+			}
+			// 延長前の期限で登録した要素が残っていても、アクセスのあったキーは取り除かない
+			rec.expect(t, removal{"cold", "v", Expired})
+			if s.Len() != 2 {
+				t.Fatalf("want 2 keys got %d", s.Len())
+			}
+
+			clk.Advance(time.Minute)
+			cleanup()
+			if s.Len() != 0 {
+				t.Fatalf("cleanup should remove keys after the extended expiry, len=%d", s.Len())
+			}
+			if got := rec.take(); len(got) != 2 {
+				t.Fatalf("want 2 removals got %v", got)
+			}
+		})
+	}
+}
+
+func TestStore_SlidingAOFReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	clk := newFakeClock()
+	s, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.SetWithSlidingTTL("sess", "v", 10*time.Second)
+	clk.Advance(8 * time.Second)
+	s.Get("sess")
+	s.Close()
+
+	// アクセスによる延長は記録しないので、最後に書き込んだ時点の期限で復元する
+	s2, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncAlways))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if ttl, ok := s2.TTL("sess"); !ok || ttl != 2*time.Second {
+		t.Fatalf("want 2s got %v %v", ttl, ok)
+	}
+}
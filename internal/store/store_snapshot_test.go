@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_SnapshotRestore(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithShards(4))
+	defer s.Close()
+	for i := 0; i < 100; i++ {
+		_ = s.Set(fmt.Sprintf("k%03d", i), fmt.Sprintf("v%03d", i))
+	}
+	_ = s.SetWithTTL("ttl", "x", time.Hour)
+	_ = s.SetWithTTL("short", "y", 10*time.Millisecond)
+	clk.Advance(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	s2 := New[string, string](WithClock(clk), WithShards(16))
+	defer s2.Close()
+	if err := s2.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if l := s2.Len(); l != 101 {
+		t.Fatalf("len want 101 got %d", l)
+	}
+	if v, ok := s2.Get("k042"); !ok || v != "v042" {
+		t.Fatalf("k042 want v042 got %q", v)
+	}
+	_, ver, _ := s.GetVersioned("k042")
+	if _, got, _ := s2.GetVersioned("k042"); got != ver {
+		t.Fatalf("version should be preserved: want %d got %d", ver, got)
+	}
+	if _, ok := s2.Get("ttl"); !ok {
+		t.Fatalf("ttl key should be restored")
+	}
+	if _, ok := s2.Get("short"); ok {
+		t.Fatalf("expired key should not be in snapshot")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := New[string, int]()
+	defer s.Close()
+	_ = s.Set("a", 1)
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	s2, err := Restore[string, int](bytes.NewReader(buf.Bytes()), WithShards(4))
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	defer s2.Close()
+	if v, ok := s2.Get("a"); !ok || v != 1 || s2.shardCount() != 4 {
+		t.Fatalf("want a=1 with 4 shards, got %d %v shards=%d", v, ok, s2.shardCount())
+	}
+
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+	if s3, err := Restore[string, int](bytes.NewReader(data)); !errors.Is(err, ErrSnapshotCorrupt) || s3 != nil {
+		t.Fatalf("want ErrSnapshotCorrupt and nil store, got %v %v", s3, err)
+	}
+}
+
+func TestStore_RestoreCorrupt(t *testing.T) {
+	s := New[string, int]()
+	defer s.Close()
+	_ = s.Set("a", 1)
+	_ = s.Set("b", 2)
+
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	b := buf.Bytes()
+
+	flipped := append([]byte(nil), b...)
+	flipped[len(flipped)/2] ^= 0xff
+	truncated := b[:len(b)-3]
+
+	for name, data := range map[string][]byte{"flipped": flipped, "truncated": truncated} {
+		s2 := New[string, int]()
+		err := s2.Restore(bytes.NewReader(data))
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if s2.Len() != 0 {
+			t.Fatalf("%s: corrupt snapshot must not be applied partially", name)
+		}
+		s2.Close()
+	}
+}
+
+func TestStore_SaveLoadSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.snap")
+
+	s := New[string, string]()
+	defer s.Close()
+
+	s2 := New[string, string]()
+	defer s2.Close()
+	if err := s2.LoadSnapshot(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+
+	_ = s.Set("a", "1")
+	if err := s.SaveSnapshot(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	_ = s.Set("a", "2")
+	if err := s.SaveSnapshot(path); err != nil {
+		t.Fatalf("save again: %v", err)
+	}
+	if err := s2.LoadSnapshot(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if v, ok := s2.Get("a"); !ok || v != "2" {
+		t.Fatalf("a want 2 got %q", v)
+	}
+}
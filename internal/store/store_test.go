@@ -5,12 +5,14 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/amakane-hakari/kavos/internal/clock/fake"
 )
 
 func TestStore_SetGetDelete(t *testing.T) {
 	s := New[string, string]()
 
-	s.Set("foo", "bar")
+	_ = s.Set("foo", "bar")
 	if v, ok := s.Get("foo"); !ok || v != "bar" {
 		t.Fatalf("expected bar, got %v", v)
 	}
@@ -19,7 +21,7 @@ func TestStore_SetGetDelete(t *testing.T) {
 		t.Fatalf("expected baz to not exist")
 	}
 
-	s.Delete("foo")
+	_ = s.Delete("foo")
 	if _, ok := s.Get("foo"); ok {
 		t.Fatalf("expected foo to be deleted")
 	}
@@ -35,11 +37,11 @@ func TestStore_Concurrency(t *testing.T) {
 		go func(i int) {
 			defer wg.Done()
 			k := "k" + strconv.Itoa(i)
-			s.Set(k, "v")
+			_ = s.Set(k, "v")
 			if _, ok := s.Get(k); !ok {
 				t.Errorf("missing key %s", k)
 			}
-			s.Delete(k)
+			_ = s.Delete(k)
 		}(i)
 	}
 	wg.Wait()
@@ -50,16 +52,22 @@ func TestStore_Concurrency(t *testing.T) {
 }
 
 func TestStore_TTLExpiration(t *testing.T) {
-	s := New[string, string]()
-	s.SetWithTTL("ephemeral", "x", 50*time.Millisecond)
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	_ = s.SetWithTTL("ephemeral", "x", 50*time.Millisecond)
 
 	if v, ok := s.Get("ephemeral"); !ok || v != "x" {
 		t.Fatalf("expected present before expiry")
 	}
 
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(70 * time.Millisecond)
 
 	if _, ok := s.Get("ephemeral"); ok {
 		t.Fatalf("expected expired key")
 	}
 }
+
+// newFakeClock は TTL のテストで使う、Advance で時間を進める Clock を作成します。
+func newFakeClock() *fake.Clock {
+	return fake.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
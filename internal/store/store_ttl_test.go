@@ -0,0 +1,166 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_TTLAndExpire(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	if _, ok := s.TTL("missing"); ok {
+		t.Fatalf("missing key should report ok=false")
+	}
+	if err := s.Expire("missing", time.Second); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound got %v", err)
+	}
+
+	_ = s.Set("k", "v")
+	_, ver, _ := s.GetVersioned("k")
+	if ttl, ok := s.TTL("k"); !ok || ttl != 0 {
+		t.Fatalf("persistent key: ttl=%v ok=%v", ttl, ok)
+	}
+
+	if err := s.Expire("k", time.Minute); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	clk.Advance(15 * time.Second)
+	if ttl, ok := s.TTL("k"); !ok || ttl != 45*time.Second {
+		t.Fatalf("want 45s got %v %v", ttl, ok)
+	}
+	// 値とバージョンは変わらない
+	if v, cur, _ := s.GetVersioned("k"); v != "v" || cur != ver {
+		t.Fatalf("expire must not rewrite the value: %q ver %d -> %d", v, ver, cur)
+	}
+
+	if err := s.Persist("k"); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	clk.Advance(time.Hour)
+	if ttl, ok := s.TTL("k"); !ok || ttl != 0 {
+		t.Fatalf("persisted key: ttl=%v ok=%v", ttl, ok)
+	}
+
+	at := clk.Now().Add(10 * time.Second)
+	if err := s.ExpireAt("k", at); err != nil {
+		t.Fatalf("expire at: %v", err)
+	}
+	if it, _ := s.GetItem("k"); !it.ExpireAt.Equal(at) {
+		t.Fatalf("want expire at %v got %v", at, it.ExpireAt)
+	}
+	clk.Advance(10 * time.Second)
+	if _, ok := s.Get("k"); ok {
+		t.Fatalf("key should expire at the given time")
+	}
+
+	// 0 以下の TTL や過去の時刻は直ちに期限切れ
+	_ = s.Set("a", "1")
+	_ = s.Set("b", "2")
+	_ = s.Expire("a", 0)
+	_ = s.ExpireAt("b", clk.Now().Add(-time.Second))
+	if _, ok := s.Get("a"); ok {
+		t.Fatalf("Expire(0) should expire immediately")
+	}
+	if _, ok := s.TTL("b"); ok {
+		t.Fatalf("ExpireAt in the past should expire immediately")
+	}
+}
+
+func TestStore_Touch(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk), WithCleanupInterval(time.Second))
+	defer s.Close()
+
+	_ = s.SetWithTTL("k", "v", 10*time.Second)
+	clk.Advance(8 * time.Second)
+	if err := s.Touch("k"); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	clk.Advance(8 * time.Second)
+	if _, ok := s.Get("k"); !ok {
+		t.Fatalf("touched key should still be alive")
+	}
+	if ttl, _ := s.TTL("k"); ttl != 2*time.Second {
+		t.Fatalf("want 2s got %v", ttl)
+	}
+
+	// Expire の TTL で以後の Touch も延ばす
+	_ = s.Expire("k", time.Minute)
+	clk.Advance(30 * time.Second)
+	_ = s.Touch("k")
+	if ttl, _ := s.TTL("k"); ttl != time.Minute {
+		t.Fatalf("want 1m got %v", ttl)
+	}
+
+	// 期限のないキーは変わらない
+	_ = s.Set("p", "v")
+	_ = s.Touch("p")
+	if ttl, ok := s.TTL("p"); !ok || ttl != 0 {
+		t.Fatalf("touch must not add a ttl: %v %v", ttl, ok)
+	}
+
+	clk.Advance(time.Minute)
+	if err := s.Touch("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound got %v", err)
+	}
+}
+
+func TestStore_CleanupFollowsExpire(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+
+	_ = s.SetWithTTL("shortened", "v", time.Hour)
+	_ = s.SetWithTTL("persisted", "v", time.Second)
+	_ = s.Expire("shortened", time.Second)
+	_ = s.Persist("persisted")
+	clk.Advance(time.Second)
+	s.scanExpired()
+
+	sh := s.shardAt(s.shardIndex("shortened"))
+	sh.mu.RLock()
+	_, ok := sh.m["shortened"]
+	sh.mu.RUnlock()
+	if ok {
+		t.Fatalf("cleanup should remove the key with a shortened ttl")
+	}
+	if _, ok := s.Get("persisted"); !ok {
+		t.Fatalf("cleanup must not remove a persisted key")
+	}
+}
+
+func TestStore_ExpireAOFReplay(t *testing.T) {
+	clk := newFakeClock()
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.SetWithTTL("persisted", "v", time.Second)
+	_ = s.Persist("persisted")
+	_ = s.Set("expiring", "v")
+	_ = s.Expire("expiring", time.Minute)
+	_ = s.Set("gone", "v")
+	_ = s.Expire("gone", time.Second)
+	s.Close()
+
+	clk.Advance(2 * time.Second)
+	s2, err := Open[string, string](WithClock(clk), WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if ttl, ok := s2.TTL("persisted"); !ok || ttl != 0 {
+		t.Fatalf("persist should survive restart: %v %v", ttl, ok)
+	}
+	if ttl, ok := s2.TTL("expiring"); !ok || ttl != 58*time.Second {
+		t.Fatalf("expire should survive restart: %v %v", ttl, ok)
+	}
+	if _, ok := s2.Get("gone"); ok {
+		t.Fatalf("expired key should not be restored")
+	}
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/amakane-hakari/kavos/internal/metrics"
+)
+
+func TestStore_TxnCommit(t *testing.T) {
+	mx := metrics.NewSimple()
+	s := New[string, int](WithMetrics(mx))
+	defer s.Close()
+	_ = s.Set("a", 1)
+	_ = s.Set("b", 2)
+
+	res, err := s.Txn(func(tx *Tx[string, int]) error {
+		tx.Get("a")
+		tx.Set("a", 10, 0)
+		tx.Delete("b")
+		tx.Set("c", 3, 0)
+		tx.Get("a")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("txn: %v", err)
+	}
+	if len(res) != 5 {
+		t.Fatalf("want 5 results got %d", len(res))
+	}
+	if !res[0].Found || res[0].Value != 1 {
+		t.Fatalf("first get should see old value: %+v", res[0])
+	}
+	if !res[2].Found {
+		t.Fatalf("delete should report existing key")
+	}
+	if res[4].Value != 10 || res[4].Version != res[1].Version {
+		t.Fatalf("get after set should see the write: %+v", res[4])
+	}
+	if v, _ := s.Get("a"); v != 10 {
+		t.Fatalf("a want 10 got %d", v)
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Fatalf("b should be deleted")
+	}
+	if v, _ := s.Get("c"); v != 3 {
+		t.Fatalf("c want 3 got %d", v)
+	}
+	if mx.SetNew.Load() != 3 || mx.SetUpdate.Load() != 1 {
+		t.Fatalf("unexpected set metrics new=%d update=%d", mx.SetNew.Load(), mx.SetUpdate.Load())
+	}
+}
+
+func TestStore_TxnAbort(t *testing.T) {
+	s := New[string, int]()
+	defer s.Close()
+	_ = s.Set("a", 1)
+	_, ver, _ := s.GetVersioned("a")
+
+	res, err := s.Txn(func(tx *Tx[string, int]) error {
+		tx.Check("a", ver)
+		tx.Check("new", 0)
+		tx.Check("b", 1)
+		tx.Set("a", 2, 0)
+		tx.Set("new", 1, 0)
+		return nil
+	})
+	if !errors.Is(err, ErrTxnAborted) {
+		t.Fatalf("want ErrTxnAborted got %v", err)
+	}
+	if res[0].Err != nil || res[1].Err != nil || !errors.Is(res[2].Err, ErrNotFound) {
+		t.Fatalf("unexpected check results: %+v", res)
+	}
+	if v, _ := s.Get("a"); v != 1 {
+		t.Fatalf("aborted txn must not apply writes")
+	}
+	if _, ok := s.Get("new"); ok {
+		t.Fatalf("aborted txn must not create keys")
+	}
+
+	if _, err := s.Txn(func(tx *Tx[string, int]) error {
+		tx.Set("a", 3, 0)
+		return errors.New("boom")
+	}); err == nil || err.Error() != "boom" {
+		t.Fatalf("fn error should be returned, got %v", err)
+	}
+	if v, _ := s.Get("a"); v != 1 {
+		t.Fatalf("txn must not apply when fn fails")
+	}
+}
+
+func TestStore_TxnConcurrentTransfer(t *testing.T) {
+	s := New[string, int](WithShards(8))
+	defer s.Close()
+	const accounts = 16
+	for i := 0; i < accounts; i++ {
+		_ = s.Set(fmt.Sprintf("acct%d", i), 100)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				from := fmt.Sprintf("acct%d", (w+i)%accounts)
+				to := fmt.Sprintf("acct%d", (w*7+i*3+1)%accounts)
+				if from == to {
+					continue
+				}
+				for {
+					fv, fver, _ := s.GetVersioned(from)
+					tv, tver, _ := s.GetVersioned(to)
+					_, err := s.Txn(func(tx *Tx[string, int]) error {
+						tx.Check(from, fver)
+						tx.Check(to, tver)
+						tx.Set(from, fv-1, 0)
+						tx.Set(to, tv+1, 0)
+						return nil
+					})
+					if err == nil {
+						break
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := 0; i < accounts; i++ {
+		v, _ := s.Get(fmt.Sprintf("acct%d", i))
+		total += v
+	}
+	if total != accounts*100 {
+		t.Fatalf("total should be preserved: want %d got %d", accounts*100, total)
+	}
+}
+
+func TestStore_TxnEvictorAndAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kavos.aof")
+	s, err := Open[string, int](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	s.WithEvictor(NewLRUEvictor[string, int](2))
+	if _, err := s.Txn(func(tx *Tx[string, int]) error {
+		tx.Set("a", 1, 0)
+		tx.Set("b", 2, 0)
+		tx.Set("c", 3, 0)
+		return nil
+	}); err != nil {
+		t.Fatalf("txn: %v", err)
+	}
+	if l := s.Len(); l != 2 {
+		t.Fatalf("evictor should bound txn writes, len=%d", l)
+	}
+	s.Close()
+
+	s2, err := Open[string, int](WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s2.Close()
+	if _, ok := s2.Get("a"); ok {
+		t.Fatalf("evicted key should stay deleted after replay")
+	}
+	if v, _ := s2.Get("c"); v != 3 {
+		t.Fatalf("c want 3 got %d", v)
+	}
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func recvEvent[K comparable, V any](t *testing.T, ch <-chan Event[K, V]) Event[K, V] {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatalf("watch channel closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for event")
+	}
+	return Event[K, V]{}
+}
+
+func TestStore_WatchEvents(t *testing.T) {
+	clk := newFakeClock()
+	s := New[string, string](WithClock(clk))
+	defer s.Close()
+	s.WithEvictor(NewLRUEvictor[string, string](2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := s.Watch(ctx, "user:")
+
+	_ = s.Set("user:1", "a")
+	_ = s.Set("other", "x") // prefix 外
+	_ = s.Set("user:1", "b")
+	_ = s.Delete("user:1")
+	_ = s.SetWithTTL("user:2", "c", 5*time.Millisecond)
+	clk.Advance(10 * time.Millisecond)
+	s.Get("user:2")
+	_ = s.Set("user:3", "d")
+	_ = s.Set("user:4", "e") // other を追い出す（prefix 外）
+	_ = s.Set("user:5", "f") // user:3 を追い出す
+
+	want := []struct {
+		typ EventType
+		key string
+		val string
+	}{
+		{EventSet, "user:1", "a"},
+		{EventUpdate, "user:1", "b"},
+		{EventDelete, "user:1", ""},
+		{EventSet, "user:2", "c"},
+		{EventExpire, "user:2", ""},
+		{EventSet, "user:3", "d"},
+		{EventSet, "user:4", "e"},
+		{EventSet, "user:5", "f"},
+		{EventEvict, "user:3", ""},
+	}
+	var lastID, setVer uint64
+	for i, w := range want {
+		ev := recvEvent(t, ch)
+		if ev.Type != w.typ || ev.Key != w.key || ev.Value != w.val {
+			t.Fatalf("event %d: got %s %s=%q, want %s %s=%q", i, ev.Type, ev.Key, ev.Value, w.typ, w.key, w.val)
+		}
+		if ev.ID <= lastID || ev.Version == 0 {
+			t.Fatalf("event %d: bad id/version %+v (last id %d)", i, ev, lastID)
+		}
+		lastID = ev.ID
+		if ev.Type == EventUpdate {
+			setVer = ev.Version
+		}
+		if ev.Type == EventDelete && ev.Version != setVer {
+			t.Fatalf("delete should carry removed version %d, got %d", setVer, ev.Version)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatalf("expected no more events")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel not closed after cancel")
+	}
+}
+
+func TestStore_WatchSlowConsumerDisconnected(t *testing.T) {
+	s := New[string, int](WithWatchBuffer(4))
+	defer s.Close()
+
+	slow := s.Watch(context.Background(), "")
+	for i := 0; i < 10; i++ {
+		_ = s.Set(fmt.Sprintf("k%d", i), i)
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != 4 {
+		t.Fatalf("expected buffered 4 events before disconnect, got %d", n)
+	}
+
+	// 書き込みはブロックされず、新しい購読者は受け取れる
+	fresh := s.Watch(context.Background(), "")
+	_ = s.Set("after", 1)
+	if ev := recvEvent(t, fresh); ev.Key != "after" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+}
+
+func TestStore_WatchFrom(t *testing.T) {
+	s := New[string, int](WithWatchHistory(5))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := s.Watch(ctx, "")
+	_ = s.Set("a", 1)
+	first := recvEvent(t, ch)
+	_ = s.Set("b", 2)
+	_ = s.Set("x", 9)
+	_ = s.Set("c", 3)
+
+	resumed, err := s.WatchFrom(ctx, "", first.ID)
+	if err != nil {
+		t.Fatalf("WatchFrom: %v", err)
+	}
+	for _, k := range []string{"b", "x", "c"} {
+		if ev := recvEvent(t, resumed); ev.Key != k {
+			t.Fatalf("resume: expected %s got %+v", k, ev)
+		}
+	}
+	_ = s.Set("d", 4)
+	if ev := recvEvent(t, resumed); ev.Key != "d" {
+		t.Fatalf("live after resume: %+v", ev)
+	}
+
+	// 最新 ID からの再開はバックログなし
+	latest, err := s.WatchFrom(ctx, "", first.ID+4)
+	if err != nil {
+		t.Fatalf("WatchFrom latest: %v", err)
+	}
+	select {
+	case ev := <-latest:
+		t.Fatalf("unexpected backlog %+v", ev)
+	default:
+	}
+
+	// 履歴から溢れた ID / 未来の ID は再開できない
+	for i := 0; i < 10; i++ {
+		_ = s.Set("fill", i)
+	}
+	if _, err := s.WatchFrom(ctx, "", first.ID); !errors.Is(err, ErrWatchResumeUnavailable) {
+		t.Fatalf("expected ErrWatchResumeUnavailable, got %v", err)
+	}
+	if _, err := s.WatchFrom(ctx, "", 1<<40); !errors.Is(err, ErrWatchResumeUnavailable) {
+		t.Fatalf("expected ErrWatchResumeUnavailable for future id, got %v", err)
+	}
+}
+
+func TestStore_WatchClosedOnStoreClose(t *testing.T) {
+	s := New[string, int]()
+	ch := s.Watch(context.Background(), "")
+	s.Close()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatalf("expected closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel not closed on store Close")
+	}
+}
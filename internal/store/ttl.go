@@ -0,0 +1,102 @@
+package store
+
+import "time"
+
+// TTL はキーの残り有効期間を返します。期限のないキーは 0、存在しない（期限切れを含む）キーは ok=false を返します。
+// Get と異なりヒット/ミスのメトリクスや Evictor のアクセス記録には影響しません。
+func (s *Store[K, V]) TTL(key K) (ttl time.Duration, ok bool) {
+	mu, mp := s.getShard(key)
+	mu.RLock()
+	e, exists := mp[key]
+	mu.RUnlock()
+	now := s.now().UnixNano()
+	if !exists || e.expired(now) {
+		return 0, false
+	}
+	if e.expireAt == 0 {
+		return 0, true
+	}
+	return time.Duration(e.expireAt - now), true
+}
+
+// Expire はキーの期限を現在から ttl 後に変更します。値とバージョンは変わりません。
+// スライディング期限のキーは通常の期限に戻ります（WithMaxIdle のアイドル期限は引き続き適用されます）。
+// ttl が 0 以下ならキーは直ちに期限切れになります。キーが存在しなければ ErrNotFound を返します。
+func (s *Store[K, V]) Expire(key K, ttl time.Duration) error {
+	return s.changeExpire(key, func(e *entry[V], now time.Time) {
+		e.sliding = false
+		if ttl <= 0 {
+			e.deadline, e.ttl = now.UnixNano(), 0
+			return
+		}
+		e.deadline, e.ttl = now.Add(ttl).UnixNano(), ttl
+	})
+}
+
+// ExpireAt はキーの期限を時刻 t に変更します。t が現在以前ならキーは直ちに期限切れになります。
+// 絶対時刻での期限なので、以後の Touch やアクセスでは延長されません。キーが存在しなければ ErrNotFound を返します。
+func (s *Store[K, V]) ExpireAt(key K, t time.Time) error {
+	return s.changeExpire(key, func(e *entry[V], now time.Time) {
+		e.deadline, e.ttl, e.sliding = t.UnixNano(), 0, false
+		if !t.After(now) {
+			e.deadline = now.UnixNano()
+		}
+	})
+}
+
+// Persist はキーの期限（スライディング期限を含む）を取り除き、無期限にします。
+// WithMaxIdle を設定している場合はアイドル期限だけが残ります。キーが存在しなければ ErrNotFound を返します。
+func (s *Store[K, V]) Persist(key K) error {
+	return s.changeExpire(key, func(e *entry[V], _ time.Time) {
+		e.deadline, e.ttl, e.sliding = 0, 0, false
+	})
+}
+
+// Touch はキーへのアクセスを記録し、セット時（または Expire）の TTL で期限を現在から延ばします。
+// スライディング期限と WithMaxIdle の期限も Get と同様に延びます。
+// 期限のないキーや ExpireAt で期限を設定したキーは期限が変わりません。キーが存在しなければ ErrNotFound を返します。
+// AOF やスナップショットから復元したキーはセット時の TTL を保持しないため延長されません。
+func (s *Store[K, V]) Touch(key K) error {
+	err := s.changeExpire(key, func(e *entry[V], now time.Time) {
+		if !e.sliding && e.ttl > 0 {
+			e.deadline = now.Add(e.ttl).UnixNano()
+		}
+	})
+	if err == nil && s.evictor != nil {
+		s.evictor.OnGet(key, true)
+	}
+	return err
+}
+
+// changeExpire はシャードロック下で fn にエントリの期限 (deadline / ttl / sliding) を変更させ、
+// 現在時刻から実効期限を計算し直します。値・バージョンは変えず、Backend にも反映しません。
+// 期限切れにした場合は遅延削除とクリーンアップで取り除かれます。
+// write-through の Backend への書き込み中に変更して上書きで失われないよう、キーのロックも取ります。
+func (s *Store[K, V]) changeExpire(key K, fn func(e *entry[V], now time.Time)) error {
+	unlockKey := s.lockKeys(key)
+	defer unlockKey()
+	now := s.now()
+	mu, mp := s.getShard(key)
+	mu.Lock()
+	defer mu.Unlock()
+	e, exists := mp[key]
+	if !exists || e.expired(now.UnixNano()) {
+		return ErrNotFound
+	}
+	prev := e
+	fn(&e, now)
+	e.expireAt = s.accessExpireAt(e, now.UnixNano())
+	if e.expireAt == prev.expireAt && e.deadline == prev.deadline && e.ttl == prev.ttl && e.sliding == prev.sliding {
+		return nil
+	}
+	mp[key] = e
+	s.expireAdd(key, e.expireAt)
+	var aofErr error
+	if e.persistedExpireAt() != prev.persistedExpireAt() {
+		aofErr = s.aofExpire(key, e.persistedExpireAt())
+	}
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Debug("store.expire", "key", key, "expire_at", e.expireAt)
+	}
+	return aofErr
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrTxnAborted は前提条件 (Check) を満たさずトランザクションが中止された場合のエラーです。
+var ErrTxnAborted = errors.New("store: transaction aborted")
+
+// TxOpType はトランザクション内の操作種別です。
+type TxOpType int
+
+const (
+	// TxGet は値の取得です。
+	TxGet TxOpType = iota
+	// TxSet は値のセットです。
+	TxSet
+	// TxDelete はキーの削除です。
+	TxDelete
+	// TxCheck はバージョンの前提条件です (0 = 存在しないこと)。
+	TxCheck
+)
+
+type txOp[K comparable, V any] struct {
+	typ     TxOpType
+	key     K
+	val     V
+	ttl     time.Duration
+	version uint64
+}
+
+// Tx はトランザクション内の操作を収集します。
+// 操作は Txn の関数が返った後、まとめて実行されます。
+type Tx[K comparable, V any] struct {
+	ops []txOp[K, V]
+}
+
+// Get は値の取得を追加します。同じトランザクション内で先に追加した Set/Delete の結果が見えます。
+func (tx *Tx[K, V]) Get(key K) {
+	tx.ops = append(tx.ops, txOp[K, V]{typ: TxGet, key: key})
+}
+
+// Set は値のセットを追加します。
+func (tx *Tx[K, V]) Set(key K, value V, ttl time.Duration) {
+	tx.ops = append(tx.ops, txOp[K, V]{typ: TxSet, key: key, val: value, ttl: ttl})
+}
+
+// Delete はキーの削除を追加します。
+func (tx *Tx[K, V]) Delete(key K) {
+	tx.ops = append(tx.ops, txOp[K, V]{typ: TxDelete, key: key})
+}
+
+// Check はキーの現在のバージョンが version であることを前提条件として追加します。
+// version に 0 を指定するとキーが存在しないことを要求します。
+// 前提条件はすべての操作に先立ち、トランザクション開始時点の状態に対して評価されます。
+func (tx *Tx[K, V]) Check(key K, version uint64) {
+	tx.ops = append(tx.ops, txOp[K, V]{typ: TxCheck, key: key, version: version})
+}
+
+// TxResult はトランザクション内の各操作の結果です。
+type TxResult[K comparable, V any] struct {
+	Op      TxOpType
+	Key     K
+	Found   bool   // Get/Delete/Check: キーが存在したか
+	Value   V      // Get: 値
+	Version uint64 // Get/Check: 現在のバージョン, Set: 新しいバージョン
+	Err     error  // Check: 失敗理由 (ErrVersionMismatch / ErrNotFound)
+}
+
+// Txn は fn で収集した操作を複数キーにまたがってアトミックに実行します。
+// 関係するシャードをインデックス順にロックし（デッドロック回避）、前提条件をすべて満たした場合のみ
+// 全操作を適用します。満たさない場合は何も適用せず、結果と ErrTxnAborted を返します。
+// fn がエラーを返した場合や、Set の値が WithMaxBytes の予算を超える場合 (ErrValueTooLarge) も何も適用しません。
+// Backend を設定している場合、Set / Delete は BatchStore でまとめて反映し、write-through で失敗した場合は ErrBackend を返して何も適用しません。
+// FsyncAlways の AOF への追記に失敗した場合は、適用済みの結果とともに ErrAOF を返します。
+func (s *Store[K, V]) Txn(fn func(tx *Tx[K, V]) error) ([]TxResult[K, V], error) {
+	tx := &Tx[K, V]{}
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+	if len(tx.ops) == 0 {
+		return nil, nil
+	}
+	costs := make([]int64, len(tx.ops))
+	for i, op := range tx.ops {
+		if op.typ != TxSet {
+			continue
+		}
+		costs[i] = s.costOf(op.key, op.val)
+		if err := s.checkCost(costs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	idx := make([]int, 0, len(tx.ops))
+	keys := make([]K, 0, len(tx.ops))
+	for _, op := range tx.ops {
+		idx = append(idx, s.shardIndex(op.key))
+		if op.typ != TxGet {
+			keys = append(keys, op.key)
+		}
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	// write-through では Backend への書き込み中にシャードロックを外すので、書き込むキーと前提条件のキーを先にロックする
+	unlockKeys := s.lockKeys(keys...)
+	mus := make([]*sync.RWMutex, 0, len(idx))
+	for _, i := range idx {
+		mu := &s.shardAt(i).mu
+		mu.Lock()
+		mus = append(mus, mu)
+	}
+	unlock := func() {
+		for _, mu := range mus {
+			mu.Unlock()
+		}
+		unlockKeys()
+	}
+
+	now := s.now()
+	nowNano := now.UnixNano()
+	results := make([]TxResult[K, V], len(tx.ops))
+	aborted := false
+	for i, op := range tx.ops {
+		results[i].Op = op.typ
+		results[i].Key = op.key
+		if op.typ != TxCheck {
+			continue
+		}
+		_, mp := s.getShard(op.key)
+		cur, existed := mp[op.key]
+		if err := checkVersion(cur, existed, op.version, nowNano); err != nil {
+			results[i].Err = err
+			aborted = true
+		}
+	}
+	if aborted {
+		unlock()
+		return results, ErrTxnAborted
+	}
+	if s.backend != nil {
+		var writes []BackendWrite[K, V]
+		for _, op := range tx.ops {
+			switch op.typ {
+			case TxSet:
+				writes = append(writes, BackendWrite[K, V]{Key: op.key, Value: op.val})
+			case TxDelete:
+				writes = append(writes, BackendWrite[K, V]{Key: op.key, Delete: true})
+			}
+		}
+		if err := s.backendWriteBatch(mus, writes); err != nil {
+			unlock()
+			return nil, err
+		}
+	}
+
+	type change struct {
+		key     K
+		val     V
+		existed bool
+		deleted bool
+	}
+	var (
+		changes []change
+		removed []removedEntry[K, V]
+		recs    []aofRecord
+		aofErr  error
+	)
+	for i, op := range tx.ops {
+		_, mp := s.getShard(op.key)
+		cur, existed := mp[op.key]
+		live := existed && !cur.expired(nowNano)
+		switch op.typ {
+		case TxGet:
+			results[i].Found = live
+			if live {
+				if exp, ok := s.accessExtension(cur, nowNano); ok {
+					cur.expireAt = exp
+					mp[op.key] = cur
+				}
+				results[i].Value = cur.val
+				results[i].Version = cur.ver
+			}
+		case TxCheck:
+			results[i].Found = live
+			if live {
+				results[i].Version = cur.ver
+			}
+		case TxSet:
+			ver := s.nextVersion()
+			e := entry[V]{val: op.val, ver: ver, cost: costs[i], ttl: max(op.ttl, 0)}
+			if op.ttl > 0 {
+				e.deadline = now.Add(op.ttl).UnixNano()
+			}
+			e.expireAt = s.accessExpireAt(e, nowNano)
+			mp[op.key] = e
+			s.addBytes(costs[i] - cur.cost)
+			s.expireAdd(op.key, e.expireAt)
+			s.indexAdd(op.key)
+			s.notify(setEventType(live), op.key, op.val, ver)
+			if s.aof != nil {
+				if rec, err := s.aofSetRecord(op.key, op.val, e.persistedExpireAt(), ver); err != nil {
+					aofErr = s.aofError(err)
+				} else {
+					recs = append(recs, rec)
+				}
+			}
+			results[i].Found = true
+			results[i].Version = ver
+			changes = append(changes, change{key: op.key, val: op.val, existed: existed})
+			if existed {
+				removed = append(removed, removedEntry[K, V]{op.key, cur.val, removalReason(cur, nowNano, Replaced)})
+			}
+		case TxDelete:
+			results[i].Found = live
+			if existed {
+				delete(mp, op.key)
+				s.indexRemove(op.key)
+				s.addBytes(-cur.cost)
+				s.notifyRemoved(EventDelete, op.key, cur.ver)
+				if s.aof != nil {
+					if rec, err := s.aofDeleteRecord(op.key); err != nil {
+						aofErr = s.aofError(err)
+					} else {
+						recs = append(recs, rec)
+					}
+				}
+				changes = append(changes, change{key: op.key, deleted: true})
+				removed = append(removed, removedEntry[K, V]{op.key, cur.val, removalReason(cur, nowNano, Deleted)})
+			}
+		}
+	}
+	if err := s.aofMulti(recs); err != nil {
+		aofErr = err
+	}
+	unlock()
+
+	for _, r := range results {
+		if r.Op != TxGet {
+			continue
+		}
+		if r.Found {
+			s.cfg.Metrics.IncGetHit()
+		} else {
+			s.cfg.Metrics.IncGetMiss()
+		}
+		if s.evictor != nil {
+			s.evictor.OnGet(r.Key, r.Found)
+		}
+	}
+	s.removedAll(removed)
+	for _, c := range changes {
+		if c.deleted {
+			s.afterDelete(c.key, false)
+		} else {
+			s.afterSet(c.key, c.val, c.existed)
+		}
+	}
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Debug("store.txn", "ops", len(tx.ops), "shards", len(idx))
+	}
+	return results, aofErr
+}
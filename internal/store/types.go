@@ -1,8 +1,44 @@
 package store
 
+import "time"
+
+// Item はキーに対応する値とメタデータを表します。
+type Item[K comparable, V any] struct {
+	Key      K
+	Value    V
+	Version  uint64
+	ExpireAt time.Time // 無期限の場合はゼロ値
+}
+
 type entry[V any] struct {
 	val      V
-	expireAt int64 // 0 = no expiry (UnixNano)
+	expireAt int64         // 0 = no expiry (UnixNano)。deadline・スライディング期限・max-idle のうち最も早いもの
+	deadline int64         // 絶対期限 (UnixNano, 0=なし)。アクセスで延ばすときもこれを超えない
+	ver      uint64        // セットごとに Store 全体で単調増加するバージョン
+	cost     int64         // WithCost/WithMaxBytes 有効時のコスト
+	ttl      time.Duration // セット時の TTL。Touch やスライディング期限で期限を延ばすのに使う (0 = 延ばさない)
+	sliding  bool          // アクセスのたびに期限を ttl 延ばす
+}
+
+func (e entry[V]) expired(now int64) bool {
+	return e.expireAt > 0 && e.expireAt <= now
+}
+
+// persistedExpireAt は AOF・スナップショットに記録する期限です。
+// アクセスによる延長は記録しないため、スライディング期限は現在の期限を絶対期限として、それ以外は deadline を記録します。
+func (e entry[V]) persistedExpireAt() int64 {
+	if e.sliding {
+		return e.expireAt
+	}
+	return e.deadline
+}
+
+func newItem[K comparable, V any](key K, e entry[V]) Item[K, V] {
+	it := Item[K, V]{Key: key, Value: e.val, Version: e.ver}
+	if e.expireAt > 0 {
+		it.ExpireAt = time.Unix(0, e.expireAt)
+	}
+	return it
 }
 
 const cacheLineSize = 64
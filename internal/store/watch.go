@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWatchResumeUnavailable は指定したイベント ID から再開できない場合のエラーです。
+// 履歴（WithWatchHistory）から既に消えている、またはストアの再起動で ID が巻き戻った場合に返ります。
+var ErrWatchResumeUnavailable = errors.New("store: watch resume unavailable")
+
+// defaultWatchBuffer は購読者ごとのバッファの既定値です。
+const defaultWatchBuffer = 256
+
+// EventType はキー変更イベントの種類（理由）です。
+type EventType uint8
+
+const (
+	// EventSet は新規キーの作成です。
+	EventSet EventType = iota + 1
+	// EventUpdate は既存キーの上書きです。
+	EventUpdate
+	// EventDelete は明示的な削除です。
+	EventDelete
+	// EventExpire は TTL 切れによる削除です。
+	EventExpire
+	// EventEvict は Evictor による追い出しです。
+	EventEvict
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event はキーの変更を表します。
+// 削除系（EventDelete / EventExpire / EventEvict）では Value はゼロ値で、
+// Version は削除されたエントリのバージョンです。
+type Event[K comparable, V any] struct {
+	ID      uint64 // ストア内で単調増加する ID（再開に使用）
+	Type    EventType
+	Key     K
+	Value   V
+	Version uint64
+}
+
+// watchHub は購読者への配信と再開用の履歴を管理します。
+// publish はシャードのロック下で呼ばれるため、同じキーのイベントは変更順に並びます
+// （ロック順序: シャード → hub）。
+type watchHub[K comparable, V any] struct {
+	seq    atomic.Uint64
+	active atomic.Bool // 購読者または履歴があれば true
+
+	mu      sync.Mutex
+	subs    map[*watcher[K, V]]struct{}
+	bufSize int
+	closed  bool
+
+	// 履歴のリングバッファ
+	hist     []Event[K, V]
+	histHead int // 最古のイベントの位置
+	histLen  int
+}
+
+type watcher[K comparable, V any] struct {
+	prefix string
+	ch     chan Event[K, V]
+	done   chan struct{} // 登録解除で close
+}
+
+func newWatchHub[K comparable, V any](bufSize, history int) *watchHub[K, V] {
+	if bufSize <= 0 {
+		bufSize = defaultWatchBuffer
+	}
+	h := &watchHub[K, V]{
+		subs:    make(map[*watcher[K, V]]struct{}),
+		bufSize: bufSize,
+	}
+	if history > 0 {
+		h.hist = make([]Event[K, V], history)
+		h.active.Store(true)
+	}
+	return h
+}
+
+// publish はイベントを採番して配信します。購読者のバッファが一杯の場合はブロックせず、
+// その購読者を切断（チャネルを close）します。
+func (h *watchHub[K, V]) publish(typ EventType, key K, val V, ver uint64) {
+	if !h.active.Load() {
+		// 購読者がいなくても ID は進め、再開時に取りこぼしを検出できるようにする
+		h.seq.Add(1)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ev := Event[K, V]{ID: h.seq.Add(1), Type: typ, Key: key, Value: val, Version: ver}
+	if len(h.hist) > 0 {
+		i := (h.histHead + h.histLen) % len(h.hist)
+		h.hist[i] = ev
+		if h.histLen < len(h.hist) {
+			h.histLen++
+		} else {
+			h.histHead = (h.histHead + 1) % len(h.hist)
+		}
+	}
+	if len(h.subs) == 0 {
+		return
+	}
+	ks := keyString(key)
+	for w := range h.subs {
+		if !strings.HasPrefix(ks, w.prefix) {
+			continue
+		}
+		select {
+		case w.ch <- ev:
+		default:
+			h.removeLocked(w)
+		}
+	}
+}
+
+// subscribe は購読者を登録します。resume が true の場合、after より後のイベントを履歴から先に流します。
+func (h *watchHub[K, V]) subscribe(prefix string, after uint64, resume bool) (*watcher[K, V], error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var backlog []Event[K, V]
+	if resume {
+		cur := h.seq.Load()
+		if after > cur {
+			return nil, ErrWatchResumeUnavailable
+		}
+		if after < cur {
+			if h.histLen == 0 || h.hist[h.histHead].ID > after+1 {
+				return nil, ErrWatchResumeUnavailable
+			}
+			for i := 0; i < h.histLen; i++ {
+				ev := h.hist[(h.histHead+i)%len(h.hist)]
+				if ev.ID > after && strings.HasPrefix(keyString(ev.Key), prefix) {
+					backlog = append(backlog, ev)
+				}
+			}
+		}
+	}
+
+	w := &watcher[K, V]{prefix: prefix, ch: make(chan Event[K, V], h.bufSize+len(backlog)), done: make(chan struct{})}
+	for _, ev := range backlog {
+		w.ch <- ev
+	}
+	if h.closed {
+		close(w.ch)
+		close(w.done)
+		return w, nil
+	}
+	h.subs[w] = struct{}{}
+	h.active.Store(true)
+	return w, nil
+}
+
+func (h *watchHub[K, V]) unsubscribe(w *watcher[K, V]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(w)
+}
+
+func (h *watchHub[K, V]) removeLocked(w *watcher[K, V]) {
+	if _, ok := h.subs[w]; !ok {
+		return
+	}
+	delete(h.subs, w)
+	close(w.ch)
+	close(w.done)
+	if len(h.subs) == 0 && len(h.hist) == 0 {
+		h.active.Store(false)
+	}
+}
+
+// close は全購読者のチャネルを閉じます。
+func (h *watchHub[K, V]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for w := range h.subs {
+		h.removeLocked(w)
+	}
+}
+
+// Watch は prefix で始まるキーの変更イベントを受け取るチャネルを返します
+// （キーが文字列以外の場合は fmt.Sprint した表現で比較します）。
+// チャネルは ctx の終了時またはストアの Close 時に閉じられます。
+//
+// 購読者ごとのバッファ（WithWatchBuffer）が一杯になった場合、ストアの書き込みを
+// 止めないよう、その購読者は切断されチャネルが閉じられます。取りこぼしを防ぐには
+// 最後に受け取った Event.ID を使って WatchFrom で再開してください。
+func (s *Store[K, V]) Watch(ctx context.Context, prefix string) <-chan Event[K, V] {
+	w, _ := s.watch.subscribe(prefix, 0, false)
+	s.watchUntilDone(ctx, w)
+	return w.ch
+}
+
+// WatchFrom は afterID より後のイベントから購読を再開します。
+// 間のイベントが履歴に残っていない場合は ErrWatchResumeUnavailable を返します。
+func (s *Store[K, V]) WatchFrom(ctx context.Context, prefix string, afterID uint64) (<-chan Event[K, V], error) {
+	w, err := s.watch.subscribe(prefix, afterID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.watchUntilDone(ctx, w)
+	return w.ch, nil
+}
+
+func (s *Store[K, V]) watchUntilDone(ctx context.Context, w *watcher[K, V]) {
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.watch.unsubscribe(w)
+		case <-w.done:
+		}
+	}()
+}
+
+// setEventType は上書き前に有効なエントリがあったかどうかからイベント種別を決めます。
+func setEventType(live bool) EventType {
+	if live {
+		return EventUpdate
+	}
+	return EventSet
+}
+
+// notify は変更イベントを配信します。シャードのロック下で呼び出します。
+func (s *Store[K, V]) notify(typ EventType, key K, val V, ver uint64) {
+	s.watch.publish(typ, key, val, ver)
+}
+
+// notifyRemoved は削除系イベントを配信します。シャードのロック下で呼び出します。
+func (s *Store[K, V]) notifyRemoved(typ EventType, key K, ver uint64) {
+	var zero V
+	s.watch.publish(typ, key, zero, ver)
+}